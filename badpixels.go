@@ -0,0 +1,53 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// BadPixel is the position of a stuck or hot pixel in visible sensor coordinates.
+type BadPixel struct {
+	Col int
+	Row int
+}
+
+// Returns the pixels of a dark frame (an exposure with covered lens) brighter than the black level
+// by more than threshold (0-1) of the sensor range.
+func (b *BayerImage) HotPixels(threshold float64) []BadPixel {
+	pixels := make([]BadPixel, 0)
+	for row := 0; row < b.VisibleHeight; row++ {
+		for col := 0; col < b.VisibleWidth; col++ {
+			black := float64(b.BlackAt(row, col))
+			if float64(b.At(row, col)) > black+threshold*(float64(b.White)-black) {
+				pixels = append(pixels, BadPixel{Col: col, Row: row})
+			}
+		}
+	}
+	return pixels
+}
+
+// Writes pixels as a dcraw style bad pixel map, applied to images of any date.
+func WriteBadPixelMap(w io.Writer, pixels []BadPixel) error {
+	buf := bufio.NewWriter(w)
+	for _, p := range pixels {
+		if _, err := fmt.Fprintf(buf, "%d %d 0\n", p.Col, p.Row); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// Reads a dark frame RAW file from file system and writes the bad pixel map of its hot pixels to exportPath,
+// to be used with WithBadPixels for images of the same camera. A threshold of 0.05-0.1 is a good start.
+func BuildBadPixelMap(darkFramePath string, exportPath string, threshold float64) error {
+	dark, err := ImportRawBayer(darkFramePath)
+	if err != nil {
+		return err
+	}
+	return encodeFile(exportPath, OutputOptions{}, func(w io.Writer) error {
+		return WriteBadPixelMap(w, dark.HotPixels(threshold))
+	})
+}
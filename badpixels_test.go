@@ -0,0 +1,82 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Returns a Bayer image of uniform sensor values with hot pixels at the given positions
+func flatImage(level uint16, hot ...BadPixel) dngImage {
+	img := testImage("RGGB")
+	for i := range img.Pix {
+		img.Pix[i] = level
+	}
+	for _, p := range hot {
+		img.Pix[p.Row*img.Width+p.Col] = uint16(img.White)
+	}
+	return img
+}
+
+func TestHotPixels(t *testing.T) {
+	bayer := &BayerImage{
+		Pix:           []uint16{100, 110, 900, 120, 130, 100, 100, 1000},
+		Stride:        4,
+		Width:         4,
+		Height:        2,
+		VisibleWidth:  4,
+		VisibleHeight: 2,
+		White:         1000,
+	}
+	want := []BadPixel{{Col: 2, Row: 0}, {Col: 3, Row: 1}}
+	if got := bayer.HotPixels(0.5); !slices.Equal(got, want) {
+		t.Errorf("HotPixels(0.5) = %v, want %v", got, want)
+	}
+	// the black level of each pixel is taken into account
+	bayer.BlackLevel, bayer.BlackLevelWidth, bayer.BlackLevelHeight = []uint{850, 0}, 2, 1
+	if got := bayer.HotPixels(0.5); !slices.Equal(got, want[1:]) {
+		t.Errorf("HotPixels(0.5) above the black levels = %v, want %v", got, want[1:])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBadPixelMap(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "2 0 0\n3 1 0\n" {
+		t.Errorf("bad pixel map = %q", got)
+	}
+}
+
+// Builds the map of a dark frame and interpolates the hot pixel of an image with it
+func TestBadPixelMap(t *testing.T) {
+	hot := BadPixel{Col: 10, Row: 20}
+	dir := t.TempDir()
+	badPixels := filepath.Join(dir, "badpixels.txt")
+	if err := BuildBadPixelMap(writeTestDNG(t, flatImage(0, hot), testMetadata()), badPixels, 0.1); err != nil {
+		t.Fatalf("BuildBadPixelMap failed: %v", err)
+	}
+	if data, _ := os.ReadFile(badPixels); string(data) != "10 20 0\n" {
+		t.Errorf("bad pixel map = %q, want the hot pixel", data)
+	}
+
+	path := writeTestDNG(t, flatImage(0x400, hot), testMetadata())
+	// difference of the hot pixel from a pixel far from it
+	contrast := func(opts ...Option) int {
+		img, err := ImportRawWithOptions(path, append(opts, WithAutoBright(false))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		far := rgba.RGBAAt(hot.Col+20, hot.Row+20)
+		at := rgba.RGBAAt(hot.Col, hot.Row)
+		return max(int(at.R)-int(far.R), int(at.G)-int(far.G), int(at.B)-int(far.B))
+	}
+	if without, with := contrast(), contrast(WithBadPixels(badPixels)); with >= without || with > 8 {
+		t.Errorf("contrast of the hot pixel = %v with the map, %v without it", with, without)
+	}
+}
@@ -0,0 +1,212 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Format is the output file format of a conversion.
+type Format int
+
+const (
+	FormatPPM Format = iota
+	FormatTIFF
+	FormatJPEG
+	FormatPNG
+)
+
+// Returns the file extension of the format, including the leading dot.
+func (f Format) Extension() string {
+	switch f {
+	case FormatTIFF:
+		return ".tiff"
+	case FormatJPEG:
+		return ".jpg"
+	case FormatPNG:
+		return ".png"
+	default:
+		return ".ppm"
+	}
+}
+
+// Batch converts many RAW image files concurrently with a pool of workers.
+type Batch struct {
+	// Number of files converted in parallel, the number of CPUs by default.
+	Workers int
+	// Processing options applied to every file.
+	Options []Option
+	// JPEG quality (1-100), the default JPEG quality if not set.
+	Quality int
+	// When positive, JPEG and PNG outputs are scaled down to fit in MaxDim x MaxDim.
+	MaxDim int
+	// Called after each file with the number of finished and total files, from the worker goroutines.
+	Progress func(done int, total int)
+	// How the output files are created, e.g. Overwrite for re-rendering the outputs of a previous run.
+	Output OutputOptions
+}
+
+// BatchResult is the outcome of converting a single file of a Batch.
+type BatchResult struct {
+	Input  string
+	Output string
+	Err    error
+}
+
+// Converts the RAW image files of inputs to format, writing the outputs to outputDir with the same base name.
+// Inputs from different directories keep their directory relative to the common parent directory of the inputs,
+// so files of the same name from different directories do not collide. Inputs with the same name but a different
+// extension in the same directory (a.NEF and a.DNG) would share their output, only the first one is converted, the
+// others are reported with an error.
+// The results are in the order of inputs, a failing file does not stop the conversion of the others.
+// Files not yet started when ctx is cancelled are reported with the context error.
+func (b Batch) Convert(ctx context.Context, inputs []string, outputDir string, format Format) ([]BatchResult, error) {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory [%v] with [%w]", outputDir, err)
+	}
+
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	outputs := batchOutputs(inputs, outputDir, format)
+	collisions := batchCollisions(inputs, outputs)
+	results := make([]BatchResult, len(inputs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				input, output := inputs[i], outputs[i]
+				err := ctx.Err()
+				if err == nil {
+					err = collisions[i]
+				}
+				if err == nil {
+					err = os.MkdirAll(filepath.Dir(output), 0o755)
+				}
+				if err == nil {
+					err = b.convert(ctx, input, output, format)
+				}
+				results[i] = BatchResult{Input: input, Output: output, Err: err}
+
+				if b.Progress != nil {
+					mu.Lock()
+					done++
+					b.Progress(done, len(inputs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// Returns the output paths of inputs under outputDir, keeping the directories of the inputs relative to their common
+// parent directory
+func batchOutputs(inputs []string, outputDir string, format Format) []string {
+	dirs := make([]string, len(inputs))
+	common := ""
+	for i, input := range inputs {
+		dir, err := filepath.Abs(filepath.Dir(input))
+		if err != nil {
+			dir = filepath.Dir(input)
+		}
+		dirs[i] = dir
+		if i == 0 {
+			common = dir
+		}
+		for common != filepath.Dir(common) && !within(dir, common) {
+			common = filepath.Dir(common)
+		}
+	}
+
+	outputs := make([]string, len(inputs))
+	for i, input := range inputs {
+		base := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		rel, err := filepath.Rel(common, dirs[i])
+		if err != nil {
+			rel = "."
+		}
+		outputs[i] = filepath.Join(outputDir, rel, base+format.Extension())
+	}
+	return outputs
+}
+
+// Returns the errors of the inputs whose output is the output of an earlier input, nil for the others
+func batchCollisions(inputs []string, outputs []string) []error {
+	errs := make([]error, len(inputs))
+	first := make(map[string]int, len(outputs))
+	for i, output := range outputs {
+		if j, ok := first[output]; ok {
+			errs[i] = fmt.Errorf("output file [%v] of [%v] is the output of [%v] too", output, inputs[i], inputs[j])
+			continue
+		}
+		first[output] = i
+	}
+	return errs
+}
+
+// Reports whether path is dir or inside dir
+func within(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func (b Batch) convert(ctx context.Context, input string, output string, format Format) error {
+	switch format {
+	case FormatPPM:
+		return export(ctx, input, output, b.exportOptions()...)
+	case FormatTIFF:
+		return export(ctx, input, output, append([]Option{withTIFF()}, b.exportOptions()...)...)
+	case FormatJPEG:
+		quality := b.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return encodeFile(output, b.Output, func(w io.Writer) error {
+			img, err := ImportRawContext(ctx, input, b.Options...)
+			if err != nil {
+				return err
+			}
+			return jpeg.Encode(w, resample(img, b.MaxDim, newOptions(b.Options).resize.filter), &jpeg.Options{Quality: quality})
+		})
+	case FormatPNG:
+		return encodeFile(output, b.Output, func(w io.Writer) error {
+			img, err := ImportRawContext(ctx, input, b.Options...)
+			if err != nil {
+				return err
+			}
+			return png.Encode(w, resample(img, b.MaxDim, newOptions(b.Options).resize.filter))
+		})
+	default:
+		return fmt.Errorf("unsupported output format [%v]", format)
+	}
+}
+
+// Returns the options of the libraw written exports, copied as the workers share b.Options
+func (b Batch) exportOptions() []Option {
+	return append(append([]Option{}, b.Options...), WithOutputOptions(b.Output))
+}
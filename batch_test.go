@@ -0,0 +1,122 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestBatchConvert(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{
+		testDNG(t, "RGGB"),
+		filepath.Join(dir, "missing.dng"),
+		testDNG(t, "GBRG"),
+	}
+
+	var mu sync.Mutex
+	var reports []int
+	batch := Batch{Workers: 2, Progress: func(done int, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if total != len(inputs) {
+			t.Errorf("progress total = %v, want %v", total, len(inputs))
+		}
+		reports = append(reports, done)
+	}}
+	out := filepath.Join(dir, "out")
+	results, err := batch.Convert(context.Background(), inputs, out, FormatPNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(inputs) {
+		t.Fatalf("%v results, want %v", len(results), len(inputs))
+	}
+	for i, want := range []bool{true, false, true} {
+		if results[i].Input != inputs[i] {
+			t.Errorf("result %v is of %v, want %v", i, results[i].Input, inputs[i])
+		}
+		if (results[i].Err == nil) != want {
+			t.Errorf("result of %v: %v, want success %v", inputs[i], results[i].Err, want)
+		}
+		if _, err := os.Stat(results[i].Output); (err == nil) != want {
+			t.Errorf("output %v exists: %v, want %v", results[i].Output, err == nil, want)
+		}
+	}
+	if len(reports) != len(inputs) || reports[len(reports)-1] != len(inputs) {
+		t.Errorf("progress reports = %v, want 1 to %v", reports, len(inputs))
+	}
+}
+
+func TestBatchConvertCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inputs := []string{testDNG(t, "RGGB")}
+	results, err := Batch{}.Convert(ctx, inputs, t.TempDir(), FormatJPEG)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Convert error = %v, want %v", err, context.Canceled)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("results = %+v, want the context error", results)
+	}
+}
+
+// Inputs of different directories keep their directory relative to the common parent
+func TestBatchOutputs(t *testing.T) {
+	root := t.TempDir()
+	inputs := []string{
+		filepath.Join(root, "day1", "a.NEF"),
+		filepath.Join(root, "day2", "a.NEF"),
+		filepath.Join(root, "day2", "sub", "b.dng"),
+	}
+	out := filepath.Join(root, "out")
+	want := []string{
+		filepath.Join(out, "day1", "a.tiff"),
+		filepath.Join(out, "day2", "a.tiff"),
+		filepath.Join(out, "day2", "sub", "b.tiff"),
+	}
+	for i, got := range batchOutputs(inputs, out, FormatTIFF) {
+		if got != want[i] {
+			t.Errorf("output of %v = %v, want %v", inputs[i], got, want[i])
+		}
+	}
+
+	single := batchOutputs(inputs[:1], out, FormatJPEG)
+	if want := filepath.Join(out, "a.jpg"); single[0] != want {
+		t.Errorf("output of a single input = %v, want %v", single[0], want)
+	}
+}
+
+// Inputs sharing their output are reported instead of overwriting the output of each other
+func TestBatchOutputCollision(t *testing.T) {
+	data, err := os.ReadFile(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	inputs := []string{filepath.Join(dir, "a.dng"), filepath.Join(dir, "a.DNG"), filepath.Join(dir, "b.dng")}
+	for _, input := range inputs {
+		if err = os.WriteFile(input, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(dir, "out")
+	results, err := Batch{Workers: 2}.Convert(context.Background(), inputs, out, FormatJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, true} {
+		if (results[i].Err == nil) != want {
+			t.Errorf("result of %v: %v, want success %v", filepath.Base(inputs[i]), results[i].Err, want)
+		}
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 2 {
+		t.Errorf("outputs = %v, want a.jpg and b.jpg", entries)
+	}
+}
@@ -0,0 +1,210 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// BayerImage is the unprocessed sensor data of a single channel (Bayer, X-Trans or monochrome) sensor,
+// without demosaic, white balance or any other processing.
+type BayerImage struct {
+	// Sensor values of the whole sensor area, including the masked margins
+	Pix    []uint16
+	Stride int
+	Width  int
+	Height int
+	// Visible area of the sensor, starting at LeftMargin x TopMargin
+	VisibleWidth  int
+	VisibleHeight int
+	TopMargin     int
+	LeftMargin    int
+	// Black levels of the sensor values in a BlackLevelWidth x BlackLevelHeight pattern (row-major) repeated over the
+	// visible area, covering the CFA pattern and the per-channel black levels of the camera, see BlackAt
+	BlackLevel       []uint
+	BlackLevelWidth  int
+	BlackLevelHeight int
+	// Saturation (white) level of the sensor values
+	White uint
+	CFA   CFA
+}
+
+// Returns the sensor value at row and col of the visible area.
+func (b *BayerImage) At(row int, col int) uint16 {
+	return b.Pix[(row+b.TopMargin)*b.Stride+col+b.LeftMargin]
+}
+
+// Returns the black level of the sensor value at row and col of the visible area.
+func (b *BayerImage) BlackAt(row int, col int) uint {
+	if len(b.BlackLevel) == 0 {
+		return 0
+	}
+	return b.BlackLevel[row%b.BlackLevelHeight*b.BlackLevelWidth+col%b.BlackLevelWidth]
+}
+
+// Returns the visible area of the sensor as a grayscale image.
+func (b *BayerImage) Gray16() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, b.VisibleWidth, b.VisibleHeight))
+	for y := 0; y < b.VisibleHeight; y++ {
+		for x := 0; x < b.VisibleWidth; x++ {
+			v := b.At(y, x)
+			i := img.PixOffset(x, y)
+			img.Pix[i] = uint8(v >> 8)
+			img.Pix[i+1] = uint8(v)
+		}
+	}
+	return img
+}
+
+// Returns a copy of the unpacked sensor data. Unpack has to be called first.
+func (p *Processor) Bayer() (*BayerImage, error) {
+	if p.handle == nil {
+		return nil, fmt.Errorf("processor is closed")
+	}
+
+	rawdata := &p.handle.rawdata
+	if rawdata.raw_image == nil {
+		return nil, fmt.Errorf("image has no single channel sensor data, it is not unpacked or it is a [%v] image", rawTypeOf(p.handle))
+	}
+
+	sizes := &rawdata.sizes
+	stride := int(sizes.raw_pitch) / 2
+	height := int(sizes.raw_height)
+	raw := unsafe.Slice((*uint16)(unsafe.Pointer(rawdata.raw_image)), stride*height)
+
+	bayer := &BayerImage{
+		Pix:           make([]uint16, len(raw)),
+		Stride:        stride,
+		Width:         int(sizes.raw_width),
+		Height:        height,
+		VisibleWidth:  int(sizes.width),
+		VisibleHeight: int(sizes.height),
+		TopMargin:     int(sizes.top_margin),
+		LeftMargin:    int(sizes.left_margin),
+		White:         uint(p.handle.color.maximum),
+		CFA:           cfaOf(p.handle),
+	}
+	bayer.BlackLevel, bayer.BlackLevelWidth, bayer.BlackLevelHeight = blackLevelOf(p.handle, bayer.CFA)
+	copy(bayer.Pix, raw)
+	return bayer, nil
+}
+
+// Returns the black levels of the visible area as a pattern repeating with both the CFA and the black level pattern
+// of the camera: the common black level, the one of the color channel and the one of the position in the pattern.
+func blackLevelOf(librawProcessor *C.libraw_data_t, cfa CFA) ([]uint, int, int) {
+	color := &librawProcessor.color
+	width, height := max(cfa.Width, 1), max(cfa.Height, 1)
+	// the pattern size is stored in cblack[4] (rows) and cblack[5] (columns), the pattern from cblack[6]
+	patternHeight, patternWidth := int(color.cblack[4]), int(color.cblack[5])
+	if patternWidth > 0 && patternHeight > 0 && 6+patternWidth*patternHeight <= len(color.cblack) {
+		width, height = lcm(width, patternWidth), lcm(height, patternHeight)
+	} else {
+		patternWidth, patternHeight = 0, 0
+	}
+
+	levels := make([]uint, 0, width*height)
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			level := uint(color.black)
+			channel := 0
+			if cfa.Pattern != "" {
+				channel = int(C.libraw_COLOR(librawProcessor, C.int(row), C.int(col)))
+			}
+			if channel >= 0 && channel < 4 {
+				level += uint(color.cblack[channel])
+			}
+			if patternWidth > 0 {
+				level += uint(color.cblack[6+row%patternHeight*patternWidth+col%patternWidth])
+			}
+			levels = append(levels, level)
+		}
+	}
+	return levels, width, height
+}
+
+func lcm(a int, b int) int {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
+
+// Reads a RAW image file from file system and returns the unprocessed sensor data, skipping demosaic entirely.
+func ImportRawBayer(path string) (*BayerImage, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+
+	if err = processor.Unpack(); err != nil {
+		return nil, err
+	}
+
+	return processor.Bayer()
+}
+
+// Returns the half-size four channel representation of the unpacked Bayer data: every 2x2 block of the sensor
+// is turned into a pixel of the four planes, in the order of CFA.ColorDesc (R, G1, B, G2 for "RGBG").
+// Unpack has to be called first, X-Trans sensors are not supported.
+func (p *Processor) FourChannel() ([4]*image.Gray16, error) {
+	planes := [4]*image.Gray16{}
+	if p.handle == nil {
+		return planes, fmt.Errorf("processor is closed")
+	}
+	if p.handle.idata.filters == 9 || p.handle.idata.filters == 0 {
+		return planes, fmt.Errorf("four channel representation requires a Bayer sensor")
+	}
+
+	// raw2image only merges the 2x2 blocks at half size, the setting is restored for later Process calls
+	halfSize := p.handle.params.half_size
+	p.handle.params.half_size = 1
+	defer func() { p.handle.params.half_size = halfSize }()
+	if err := p.check(C.libraw_raw2image(p.handle)); err != nil {
+		return planes, fmt.Errorf("failed to create four channel image with [%w]", err)
+	}
+	defer C.libraw_free_image(p.handle)
+
+	width, height := int(p.handle.sizes.iwidth), int(p.handle.sizes.iheight)
+	pixels := unsafe.Slice(p.handle.image, width*height)
+	for c := range planes {
+		planes[c] = image.NewGray16(image.Rect(0, 0, width, height))
+	}
+	for i, pixel := range pixels {
+		for c, plane := range planes {
+			plane.Pix[2*i] = uint8(pixel[c] >> 8)
+			plane.Pix[2*i+1] = uint8(pixel[c])
+		}
+	}
+	return planes, nil
+}
+
+// Reads a RAW image file from file system and returns the half-size four channel representation of the sensor data,
+// one plane per color of the Bayer pattern (R, G1, B, G2 for most cameras), for custom demosaic or superpixel binning.
+func ImportRaw4Channel(path string) ([4]*image.Gray16, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return [4]*image.Gray16{}, err
+	}
+	defer processor.Close()
+
+	if err = processor.Open(path); err != nil {
+		return [4]*image.Gray16{}, err
+	}
+
+	if err = processor.Unpack(); err != nil {
+		return [4]*image.Gray16{}, err
+	}
+
+	return processor.FourChannel()
+}
@@ -0,0 +1,115 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportRawBayer(t *testing.T) {
+	img := testImage("RGGB")
+	bayer, err := ImportRawBayer(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatalf("ImportRawBayer failed: %v", err)
+	}
+	if bayer.VisibleWidth != testWidth || bayer.VisibleHeight != testHeight || bayer.Width < testWidth || bayer.Height < testHeight {
+		t.Fatalf("size = %vx%v, visible %vx%v, want %vx%v", bayer.Width, bayer.Height, bayer.VisibleWidth, bayer.VisibleHeight, testWidth, testHeight)
+	}
+	if bayer.White != img.White || bayer.CFA.Pattern != "RGGB" {
+		t.Errorf("white level, CFA = %v, %q, want %v, RGGB", bayer.White, bayer.CFA.Pattern, img.White)
+	}
+	for _, p := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {testWidth - 1, testHeight - 1}, {testWidth / 2, testHeight / 3}} {
+		if got, want := bayer.At(p.Y, p.X), img.Pix[p.Y*testWidth+p.X]; got != want {
+			t.Errorf("sensor value at %v = %v, want %v", p, got, want)
+		}
+	}
+
+	gray := bayer.Gray16()
+	if got := gray.Bounds(); got != image.Rect(0, 0, testWidth, testHeight) {
+		t.Errorf("Gray16 bounds = %v, want %vx%v", got, testWidth, testHeight)
+	}
+	if got, want := gray.Gray16At(testWidth/2, testHeight/3).Y, bayer.At(testHeight/3, testWidth/2); got != want {
+		t.Errorf("Gray16 value = %v, want %v", got, want)
+	}
+
+	if _, err := ImportRawBayer(testDNG(t, "")); err == nil {
+		t.Errorf("ImportRawBayer of a linear DNG succeeded")
+	}
+}
+
+// Every 2x2 block of the sensor is split into the planes of its colors
+func TestFourChannel(t *testing.T) {
+	img := testImage("RGGB")
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	if err = processor.Open(testDNG(t, "RGGB")); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Unpack(); err != nil {
+		t.Fatal(err)
+	}
+	planes, err := processor.FourChannel()
+	if err != nil {
+		t.Fatalf("FourChannel failed: %v", err)
+	}
+	for c, plane := range planes {
+		if got := plane.Bounds(); got != image.Rect(0, 0, testWidth/2, testHeight/2) {
+			t.Errorf("bounds of plane %v = %v, want %vx%v", c, got, testWidth/2, testHeight/2)
+		}
+	}
+	for _, block := range []image.Point{{0, 0}, {5, 7}, {testWidth/2 - 1, testHeight/2 - 1}} {
+		for dy := 0; dy < 2; dy++ {
+			for dx := 0; dx < 2; dx++ {
+				row, col := 2*block.Y+dy, 2*block.X+dx
+				plane := planes[processor.ColorAt(row, col)]
+				if got, want := plane.Gray16At(block.X, block.Y).Y, img.Pix[row*testWidth+col]; got != want {
+					t.Errorf("plane %v at %v = %v, want the sensor value %v at %v, %v", processor.ColorAt(row, col), block, got, want, row, col)
+				}
+			}
+		}
+	}
+
+	if _, err := ImportRaw4Channel(testDNG(t, testXTrans)); err == nil {
+		t.Errorf("ImportRaw4Channel of an X-Trans image succeeded")
+	}
+}
+
+// Per-channel black levels are exposed per position of the pattern and carried over to exported DNGs
+func TestBlackLevelPattern(t *testing.T) {
+	img := testImage("RGGB")
+	img.Black, img.BlackWidth, img.BlackHeight = []uint{100, 110, 120, 130}, 2, 2
+	var buf bytes.Buffer
+	if err := writeDNG(&buf, img, testMetadata()); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "black.dng")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exported := filepath.Join(dir, "exported.dng")
+	if err := ExportDNG(path, exported); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{path, exported} {
+		bayer, err := ImportRawBayer(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for row := 0; row < 4; row++ {
+			for col := 0; col < 4; col++ {
+				if got, want := bayer.BlackAt(row, col), img.Black[row%2*2+col%2]; got != want {
+					t.Errorf("black level of %v at %v,%v = %v, want %v", filepath.Base(path), row, col, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package golibraw
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Images are scaled down to this size before computing the BlurHash, the placeholder holds no fine detail anyway
+const blurHashSize = 64
+
+// Reads a RAW image file from file system and computes the BlurHash placeholder of its embedded preview, or of
+// a half size render if there is no usable preview. See ImageBlurHash for the components.
+func BlurHash(path string, xComponents int, yComponents int) (string, error) {
+	img, err := previewOf(path)
+	if err != nil {
+		return "", err
+	}
+	return ImageBlurHash(img, xComponents, yComponents)
+}
+
+// Computes the BlurHash (https://blurha.sh) of an image with xComponents x yComponents (1-9 each) cosine components,
+// 4 x 3 being a common choice for landscape images.
+func ImageBlurHash(img image.Image, xComponents int, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", fmt.Errorf("invalid BlurHash components [%v x %v]", xComponents, yComponents)
+	}
+
+	img = downscale(img, blurHashSize)
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return "", fmt.Errorf("empty image")
+	}
+
+	linear := make([][3]float64, 0, w*h)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			linear = append(linear, [3]float64{srgbToLinear(r), srgbToLinear(g), srgbToLinear(bl)})
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i*x)/float64(w)) * math.Cos(math.Pi*float64(j*y)/float64(h))
+					for c := 0; c < 3; c++ {
+						factor[c] += basis * linear[y*w+x][c]
+					}
+				}
+			}
+			for c := 0; c < 3; c++ {
+				factor[c] *= normalisation / float64(w*h)
+			}
+			factors = append(factors, factor)
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encode83((xComponents-1)+(yComponents-1)*9, 1))
+
+	maximum := 1.0
+	if ac := factors[1:]; len(ac) > 0 {
+		actual := 0.0
+		for _, f := range ac {
+			actual = math.Max(actual, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actual*166-0.5))))
+		maximum = float64(quantised+1) / 166
+		hash.WriteString(encode83(quantised, 1))
+	} else {
+		hash.WriteString(encode83(0, 1))
+	}
+
+	dc := factors[0]
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, f := range factors[1:] {
+		quantise := func(v float64) int {
+			signPow := math.Copysign(math.Pow(math.Abs(v/maximum), 0.5), v)
+			return int(math.Max(0, math.Min(18, math.Floor(signPow*9+9.5))))
+		}
+		hash.WriteString(encode83(quantise(f[0])*19*19+quantise(f[1])*19+quantise(f[2]), 2))
+	}
+	return hash.String(), nil
+}
+
+func encode83(value int, length int) string {
+	digits := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		digits[i] = base83[value%83]
+		value /= 83
+	}
+	return string(digits)
+}
+
+// Converts a 16-bit sRGB encoded value of color.Color to linear [0, 1]
+func srgbToLinear(v uint32) float64 {
+	f := float64(v) / 0xffff
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+// Converts a linear value to 8-bit sRGB
+func linearToSRGB(v float64) int {
+	return int(srgbGamma(v)*255 + 0.5)
+}
+
+// Encodes a linear value with the sRGB transfer function, clamped to [0, 1]
+func srgbGamma(v float64) float64 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 1:
+		return 1
+	case v <= 0.0031308:
+		return 12.92 * v
+	default:
+		return 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+}
@@ -0,0 +1,61 @@
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncode83(t *testing.T) {
+	for _, test := range []struct {
+		value, length int
+		want          string
+	}{
+		{0, 1, "0"},
+		{82, 1, "~"},
+		{83, 2, "10"},
+		{21, 1, "L"},
+	} {
+		if got := encode83(test.value, test.length); got != test.want {
+			t.Errorf("encode83(%v, %v) = %q, want %q", test.value, test.length, got, test.want)
+		}
+	}
+}
+
+func TestImageBlurHash(t *testing.T) {
+	flat := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	for i := 0; i < len(flat.Pix); i += 4 {
+		copy(flat.Pix[i:], []byte{0xff, 0, 0, 0xff})
+	}
+	hash, err := ImageBlurHash(flat, 4, 3)
+	if err != nil {
+		t.Fatalf("ImageBlurHash failed: %v", err)
+	}
+	// size flag of 4 x 3 components, maximum AC, red DC and the 11 AC components
+	if len(hash) != 6+2*11 || hash[0] != 'L' || hash[2:6] != encode83(0xff0000, 4) {
+		t.Errorf("hash of a flat red image = %q, want 4 x 3 components with the red DC %q", hash, encode83(0xff0000, 4))
+	}
+
+	gradient := image.NewGray(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			gradient.SetGray(x, y, color.Gray{Y: uint8(x * 255 / 199)})
+		}
+	}
+	hash, err = ImageBlurHash(gradient, 9, 9)
+	if err != nil {
+		t.Fatalf("ImageBlurHash failed: %v", err)
+	}
+	if len(hash) != 6+2*(9*9-1) || hash[0] != '|' || hash[1] == '0' {
+		t.Errorf("hash of a gradient = %q, want 9 x 9 components with AC", hash)
+	}
+
+	for _, components := range [][2]int{{0, 3}, {4, 10}} {
+		if _, err := ImageBlurHash(flat, components[0], components[1]); err == nil {
+			t.Errorf("ImageBlurHash succeeded with %v x %v components", components[0], components[1])
+		}
+	}
+	if _, err := ImageBlurHash(image.NewRGBA(image.Rectangle{}), 4, 3); err == nil {
+		t.Errorf("ImageBlurHash of an empty image succeeded")
+	}
+}
@@ -0,0 +1,321 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Cache stores rendered previews and extracted thumbnails on disk, so repeated loads of the same files (e.g. by a
+// gallery) do not decode and demosaic them again. Entries are keyed by the SHA-256 hash of the file content and the
+// render settings, a modified or replaced file is rendered again. When the cache grows over its size limit, the least
+// recently used entries are removed. A Cache is safe for concurrent use; entries created by other processes sharing
+// the directory are only picked up by NewCache.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// most recently used entry at the front
+	lru  list.List
+	size int64
+	// content hashes of the files by path, saving the hashing of unchanged files, most recently used at the front.
+	// Evicted together with the entries, there are at most as many hashes as entries (or minCachedHashes).
+	hashes  map[string]*list.Element
+	hashLRU list.List
+}
+
+// Number of file hashes kept even when the cache has fewer entries, e.g. for files failing to render
+const minCachedHashes = 256
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// Opens the cache in dir, creating the directory if needed and loading the entries of previous runs. The least
+// recently used entries are removed when the cache grows over maxBytes, 0 for no limit.
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory [%v] with [%w]", dir, err)
+	}
+	c := &Cache{dir: dir, maxBytes: maxBytes, entries: map[string]*list.Element{}, hashes: map[string]*list.Element{}}
+
+	type stored struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var found []stored
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// other files (e.g. the temporary files of interrupted writes, starting with a dot) are not entries, they are
+		// neither counted nor evicted
+		if !d.Type().IsRegular() || !isCacheKey(d.Name()) || path != c.entryPath(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		found = append(found, stored{key: d.Name(), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cache directory [%v] with [%w]", dir, err)
+	}
+
+	// entries are touched on every hit, the modification time is the time of the last use
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range found {
+		c.entries[s.key] = c.lru.PushFront(&cacheEntry{key: s.key, size: s.size})
+		c.size += s.size
+	}
+	c.evict()
+	return c, nil
+}
+
+// Returns the JPEG preview of a RAW image file scaled down to fit in maxDim x maxDim with the given quality
+// (1-100, 0 for default), rendering it with ImportRawPreview and the provided options on a cache miss.
+func (c *Cache) Preview(path string, maxDim int, quality int, opts ...Option) ([]byte, error) {
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("invalid JPEG quality [%v]", quality)
+	}
+	o := newOptions(opts)
+	if o.err != nil {
+		return nil, o.err
+	}
+	settings := make([]byte, 16)
+	binary.LittleEndian.PutUint64(settings, uint64(maxDim))
+	binary.LittleEndian.PutUint64(settings[8:], uint64(quality))
+
+	return c.load(path, "preview", append(settings, optionsKey(o)...), func(w io.Writer) error {
+		img, err := ImportRawPreview(path, maxDim, opts...)
+		if err != nil {
+			return err
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	})
+}
+
+// Returns the embedded thumbnail of a RAW image file, extracting it on a cache miss.
+// JPEG thumbnails are returned as is, bitmap thumbnails in PPM format.
+func (c *Cache) Thumbnail(path string) ([]byte, error) {
+	return c.load(path, "thumbnail", nil, func(w io.Writer) error {
+		return ExtractThumbnailTo(path, w)
+	})
+}
+
+// Returns the number of entries and their total size in bytes.
+func (c *Cache) Stats() (entries int, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len(), c.size
+}
+
+// Returns the cached entry of the file and settings, creating it with render on a miss
+func (c *Cache) load(path string, kind string, settings []byte, render func(w io.Writer) error) ([]byte, error) {
+	fileHash, err := c.fileHash(path)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	io.WriteString(h, fileHash)
+	io.WriteString(h, kind)
+	h.Write(settings)
+	key := hex.EncodeToString(h.Sum(nil))
+
+	if data, ok := c.get(key); ok {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err = render(&buf); err != nil {
+		return nil, err
+	}
+	c.put(key, buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Reports whether name is a cache key, the hex encoded SHA-256 hash of a file and its settings
+func isCacheKey(name string) bool {
+	if len(name) != 2*sha256.Size {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Entries are spread over subdirectories by the first two characters of their key
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *Cache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.entryPath(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// removed from the directory behind the cache
+		c.mu.Lock()
+		c.remove(key)
+		c.mu.Unlock()
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Stores the entry, failing to write the cache only costs rendering it again
+func (c *Cache) put(key string, data []byte) {
+	output := OutputOptions{Overwrite: true, CreateDirs: true, Atomic: true}
+	err := encodeFile(c.entryPath(key), output, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		logFallback("failed to write cache entry", "key", key, "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+}
+
+// Removes the least recently used entries until the cache fits in its size limit, keeping at least the newest entry,
+// and the least recently used file hashes beyond the number of entries
+func (c *Cache) evict() {
+	for c.maxBytes > 0 && c.size > c.maxBytes && c.lru.Len() > 1 {
+		entry := c.lru.Back().Value.(*cacheEntry)
+		c.remove(entry.key)
+		os.Remove(c.entryPath(entry.key))
+	}
+	for c.hashLRU.Len() > max(c.lru.Len(), minCachedHashes) {
+		file := c.hashLRU.Remove(c.hashLRU.Back()).(*cachedFile)
+		delete(c.hashes, file.path)
+	}
+}
+
+func (c *Cache) remove(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// Returns the SHA-256 hash of the file content, hashing the file only if it changed since the last call
+func (c *Cache) fileHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("input file [%v] does not exist", path)
+	}
+	c.mu.Lock()
+	if elem, ok := c.hashes[path]; ok {
+		if file := elem.Value.(*cachedFile); file.size == info.Size() && file.modTime.Equal(info.ModTime()) {
+			c.hashLRU.MoveToFront(elem)
+			c.mu.Unlock()
+			return file.sum, nil
+		}
+	}
+	c.mu.Unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("input file [%v] does not exist", path)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file [%v] with [%w]", path, err)
+	}
+	file := &cachedFile{path: path, size: info.Size(), modTime: info.ModTime(), sum: hex.EncodeToString(h.Sum(nil))}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.hashes[path]; ok {
+		c.hashLRU.Remove(elem)
+	}
+	c.hashes[path] = c.hashLRU.PushFront(file)
+	c.evict()
+	return file.sum, nil
+}
+
+// Serializes the processing settings of the options: the parameters are applied to zeroed libraw parameters, which
+// are compared as bytes together with the file path parameters, the selected frame and the settings applied to the
+// processed image
+func optionsKey(o *options) []byte {
+	var params C.libraw_output_params_t
+	for _, set := range o.params {
+		set(&params)
+	}
+	key := bytes.Clone(unsafe.Slice((*byte)(unsafe.Pointer(&params)), unsafe.Sizeof(params)))
+	for _, param := range o.paths {
+		key = append(key, param.path...)
+		key = append(key, 0)
+	}
+	key = binary.LittleEndian.AppendUint64(key, uint64(o.shot))
+	key = binary.LittleEndian.AppendUint64(key, uint64(o.resize.maxDim))
+	key = binary.LittleEndian.AppendUint64(key, uint64(o.resize.filter))
+	key = append(key, boolByte(o.monochrome))
+	key = binary.LittleEndian.AppendUint64(key, uint64(o.xtransPasses))
+	return append(key, boolByte(o.dngDefaultCrop), boolByte(o.baselineExposure))
+}
+
+func boolByte(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
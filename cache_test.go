@@ -0,0 +1,213 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/jpeg"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns the paths of the entries stored in the cache directory
+func cacheEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() && isCacheKey(d.Name()) {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+// Previews are rendered once, and again when the file or the settings change
+func TestCachePreview(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := testDNG(t, "RGGB")
+
+	data, err := cache.Preview(path, testWidth/2, 0)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("preview is not a JPEG image: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, testWidth/2, testHeight/2) {
+		t.Errorf("preview bounds = %v, want %vx%v", img.Bounds(), testWidth/2, testHeight/2)
+	}
+
+	// a hit returns the stored entry without rendering
+	entries := cacheEntries(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("cache entries = %v, want one", entries)
+	}
+	if err := os.WriteFile(entries[0], []byte("cached"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := cache.Preview(path, testWidth/2, 0); err != nil || string(data) != "cached" {
+		t.Errorf("second Preview = %q, %v, want the cached entry", data, err)
+	}
+
+	// other settings are other entries
+	for _, preview := range []func() ([]byte, error){
+		func() ([]byte, error) { return cache.Preview(path, testWidth/4, 0) },
+		func() ([]byte, error) { return cache.Preview(path, testWidth/2, 50) },
+		func() ([]byte, error) { return cache.Preview(path, testWidth/2, 0, WithHalfSize()) },
+	} {
+		if data, err := preview(); err != nil || string(data) == "cached" {
+			t.Errorf("Preview with other settings = %q, %v, want a new render", data, err)
+		}
+	}
+	if n, _ := cache.Stats(); n != 4 {
+		t.Errorf("cache has %v entries, want 4", n)
+	}
+
+	// a modified file is rendered again
+	metadata := testMetadata()
+	metadata.Artist = "Golibraw"
+	modified, err := os.ReadFile(writeTestDNG(t, testImage("RGGB"), metadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, modified, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := cache.Preview(path, testWidth/2, 0); err != nil || string(data) == "cached" {
+		t.Errorf("Preview of a modified file = %q, %v, want a new render", data, err)
+	}
+
+	// entries of previous runs are loaded
+	reopened, err := NewCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, size := reopened.Stats(); n != 5 || size <= 0 {
+		t.Errorf("reopened cache has %v entries of %v bytes, want 5", n, size)
+	}
+
+	if _, err := cache.Preview(path, testWidth/2, 101); err == nil {
+		t.Errorf("Preview succeeded with quality 101")
+	}
+	if _, err := cache.Preview(filepath.Join(t.TempDir(), "missing.dng"), testWidth/2, 0); err == nil {
+		t.Errorf("Preview succeeded on a missing file")
+	}
+}
+
+func TestCacheThumbnail(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, preview := previewDNG(t)
+	for i := 0; i < 2; i++ {
+		data, err := cache.Thumbnail(path)
+		if err != nil {
+			t.Fatalf("Thumbnail failed: %v", err)
+		}
+		if !bytes.Equal(data, preview) {
+			t.Errorf("thumbnail of %v bytes differs from the embedded one of %v bytes", len(data), len(preview))
+		}
+	}
+	if n, size := cache.Stats(); n != 1 || size != int64(len(preview)) {
+		t.Errorf("cache has %v entries of %v bytes, want the thumbnail", n, size)
+	}
+}
+
+// The least recently used entries are removed over the size limit, the newest entry is kept
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := testDNG(t, "RGGB")
+	for _, size := range []int{testWidth / 2, testWidth / 4} {
+		if _, err := cache.Preview(path, size, 0); err != nil {
+			t.Fatalf("Preview failed: %v", err)
+		}
+	}
+	if n, _ := cache.Stats(); n != 1 {
+		t.Errorf("cache has %v entries, want the newest one", n)
+	}
+	if entries := cacheEntries(t, dir); len(entries) != 1 {
+		t.Errorf("cache directory has the entries %v, want the newest one", entries)
+	}
+}
+
+// Every option changing the output has to change the cache key
+func TestOptionsKey(t *testing.T) {
+	keys := map[string]string{}
+	for name, opts := range map[string][]Option{
+		"default":    nil,
+		"half size":  {WithHalfSize()},
+		"shot":       {WithShot(1)},
+		"resize":     {WithResize(512, ResizeBox)},
+		"lanczos":    {WithResize(512, ResizeLanczos)},
+		"monochrome": {WithMonochrome()},
+		"x-trans":    {WithXTransPasses(1)},
+		"crop":       {WithDNGDefaultCrop()},
+		"baseline":   {WithBaselineExposure()},
+	} {
+		key := string(optionsKey(newOptions(opts)))
+		for other, otherKey := range keys {
+			if key == otherKey {
+				t.Errorf("options %v and %v have the same cache key", name, other)
+			}
+		}
+		keys[name] = key
+	}
+}
+
+// Only the files stored as cache entries are loaded, other files in the directory are neither counted nor evicted
+func TestCacheForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	key := strings.Repeat("ab", sha256.Size)
+	files := map[string]string{
+		key:                            "misplaced",
+		"a":                            "short name",
+		"notes.txt":                    "foreign",
+		filepath.Join("ab", "x"):       "foreign",
+		filepath.Join("ab", "."+key):   "interrupted write",
+		filepath.Join("ab", key):       "entry",
+		filepath.Join("ab", "ab", key): "nested",
+		filepath.Join("cd", key):       "wrong directory",
+		filepath.Join("ab", strings.ToUpper(key)): "upper case",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache, err := NewCache(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, size := cache.Stats(); entries != 1 || size != int64(len("entry")) {
+		t.Errorf("stats = %v entries of %v bytes, want the single entry", entries, size)
+	}
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("file %v was removed: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// Called by libraw at every processing stage, a non-zero return value cancels the processing.
+//
+//export goProgress
+func goProgress(data unsafe.Pointer, stage C.enum_LibRaw_progress, iteration C.int, expected C.int) (cancel C.int) {
+	p, ok := cgo.Handle(uintptr(data)).Value().(*Processor)
+	if !ok {
+		return 0
+	}
+	// a panic must not unwind through the libraw frames, it is reported by the cancelled call instead
+	defer func() {
+		if r := recover(); r != nil {
+			p.fault = fmt.Errorf("progress callback panicked with [%v]", r)
+			cancel = 1
+		}
+	}()
+	if p.progress != nil {
+		p.progress(C.GoString(C.libraw_strprogress(stage)), int(iteration), int(expected))
+	}
+	if p.ctx != nil && p.ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// Called by libraw when the image data cannot be read, e.g. the file is truncated. libraw continues with
+// the data it has, the error is reported by Processor.DataError.
+//
+//export goDataError
+func goDataError(data unsafe.Pointer, file *C.char, offset C.INT64) {
+	p, ok := cgo.Handle(uintptr(data)).Value().(*Processor)
+	if !ok || p.dataErr != nil {
+		return
+	}
+	p.dataErr = &DataError{File: C.GoString(file), Offset: int64(offset)}
+}
@@ -0,0 +1,79 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import "log/slog"
+
+// Returns the index of the color in CFA.ColorDesc of the visible pixel at row and col of the opened image.
+func (p *Processor) ColorAt(row int, col int) int {
+	if p.handle == nil {
+		return 0
+	}
+	return int(C.libraw_COLOR(p.handle, C.int(row), C.int(col)))
+}
+
+func cfaOf(librawProcessor *C.libraw_data_t) CFA {
+	idata := &librawProcessor.idata
+	cfa := CFA{
+		ColorDesc: C.GoString(&idata.cdesc[0]),
+		Filters:   uint(idata.filters),
+	}
+	if cfa.Filters == 0 || cfa.ColorDesc == "" {
+		return cfa
+	}
+
+	// dcraw filters values below 1000 select special patterns: 1 for the 16x16 pattern of Leaf backs, 9 for X-Trans
+	switch {
+	case cfa.Filters == 1:
+		cfa.Width, cfa.Height = 16, 16
+	case cfa.Filters == 9:
+		cfa.Width, cfa.Height, cfa.XTrans = 6, 6, true
+	case cfa.Filters < 1000:
+		return cfa
+	default:
+		cfa.Width, cfa.Height = 2, 2
+	}
+	pattern := make([]byte, 0, cfa.Width*cfa.Height)
+	for row := 0; row < cfa.Height; row++ {
+		for col := 0; col < cfa.Width; col++ {
+			color := int(C.libraw_COLOR(librawProcessor, C.int(row), C.int(col)))
+			if color < 0 || color >= len(cfa.ColorDesc) {
+				return CFA{ColorDesc: cfa.ColorDesc, Filters: cfa.Filters}
+			}
+			pattern = append(pattern, cfa.ColorDesc[color])
+		}
+	}
+	cfa.Pattern = string(pattern)
+
+	// the pattern is repeated from the visible area, shifted by the margins
+	top, left := int(librawProcessor.sizes.top_margin), int(librawProcessor.sizes.left_margin)
+	raw := make([]byte, 0, len(pattern))
+	for row := 0; row < cfa.Height; row++ {
+		for col := 0; col < cfa.Width; col++ {
+			raw = append(raw, cfa.ColorAt(row+cfa.Height-top%cfa.Height, col+cfa.Width-left%cfa.Width))
+		}
+	}
+	cfa.RawPattern = string(raw)
+	return cfa
+}
+
+// Selects the demosaic of X-Trans sensors. libraw only has the Markesteijn algorithm for them, run with one pass for
+// DemosaicPPG and with three passes for AHD and the other Bayer algorithms; linear and VNG are kept.
+func (p *Processor) selectXTransDemosaic(passes int) {
+	if p.handle.idata.filters != 9 || p.handle.params.half_size != 0 {
+		return
+	}
+	algorithm := Demosaic(p.handle.params.user_qual)
+	switch {
+	case passes == 1:
+		p.handle.params.user_qual = C.int(DemosaicPPG)
+	case passes >= 3:
+		p.handle.params.user_qual = C.int(DemosaicAHD)
+	case algorithm == DemosaicPPG || algorithm > DemosaicAHD:
+		p.log(slog.LevelInfo, "demosaic algorithm not available for X-Trans sensors, using Markesteijn",
+			"path", p.path, "demosaic", algorithm.String())
+	}
+}
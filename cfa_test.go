@@ -0,0 +1,49 @@
+//go:build cgo
+
+package golibraw
+
+import "testing"
+
+func TestCFA(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		size    int
+		xtrans  bool
+	}{
+		{"RGGB", 2, false},
+		{"GBRG", 2, false},
+		{testXTrans, 6, true},
+	} {
+		processor, err := NewProcessor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer processor.Close()
+		if err = processor.Open(testDNG(t, test.pattern)); err != nil {
+			t.Fatal(err)
+		}
+		md, err := processor.Metadata()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfa := md.CFA
+		if cfa.Pattern != test.pattern || cfa.Width != test.size || cfa.Height != test.size || cfa.XTrans != test.xtrans {
+			t.Errorf("CFA = %+v, want %v of %vx%v", cfa, test.pattern, test.size, test.size)
+		}
+		for row := 0; row < 2*test.size; row++ {
+			for col := 0; col < 2*test.size; col++ {
+				if got, want := cfa.ColorDesc[processor.ColorAt(row, col)], cfa.ColorAt(row, col); got != want {
+					t.Errorf("color of %v at %v, %v = %c, want %c", test.pattern, row, col, got, want)
+				}
+			}
+		}
+	}
+
+	md, err := ExtractMetadata(testDNG(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.CFA.Pattern != "" {
+		t.Errorf("CFA of a linear DNG = %q, want none", md.CFA.Pattern)
+	}
+}
@@ -0,0 +1,37 @@
+package golibraw
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// Reads a RAW image file from file system once, returning its metadata together with the digest of the file
+// computed with h (e.g. an xxhash digest), SHA-256 if h is nil. The file is buffered in memory for libraw.
+func ExtractMetadataWithChecksum(path string, h hash.Hash) (Metadata, []byte, error) {
+	if h == nil {
+		h = sha256.New()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, nil, fmt.Errorf("input file [%v] does not exist", path)
+	}
+	defer f.Close()
+
+	var data bytes.Buffer
+	if stat, err := f.Stat(); err == nil {
+		data.Grow(int(stat.Size()))
+	}
+	if _, err = data.ReadFrom(io.TeeReader(f, h)); err != nil {
+		return Metadata{}, nil, fmt.Errorf("failed to read file [%v] with [%w]", path, err)
+	}
+	metadata, err := ExtractMetadataBytes(data.Bytes())
+	if err != nil {
+		return Metadata{}, nil, err
+	}
+	return metadata, h.Sum(nil), nil
+}
@@ -0,0 +1,47 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractMetadataWithChecksum(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md, digest, err := ExtractMetadataWithChecksum(path, nil)
+	if err != nil {
+		t.Fatalf("ExtractMetadataWithChecksum failed: %v", err)
+	}
+	if want := sha256.Sum256(data); !bytes.Equal(digest, want[:]) {
+		t.Errorf("digest = %x, want the SHA-256 %x", digest, want)
+	}
+	if md.Camera != testMetadata().Camera || md.Width != testWidth {
+		t.Errorf("metadata = %+v, width %v, want the test camera, width %v", md.Camera, md.Width, testWidth)
+	}
+
+	_, digest, err = ExtractMetadataWithChecksum(path, crc32.NewIEEE())
+	if err != nil {
+		t.Fatalf("ExtractMetadataWithChecksum failed: %v", err)
+	}
+	if want := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(data)); !bytes.Equal(digest, want) {
+		t.Errorf("digest = %x, want the CRC-32 %x", digest, want)
+	}
+
+	if _, _, err := ExtractMetadataWithChecksum(filepath.Join("testdata", "corrupt", "garbage.raw"), nil); err == nil {
+		t.Errorf("ExtractMetadataWithChecksum of garbage succeeded")
+	}
+	if _, _, err := ExtractMetadataWithChecksum(filepath.Join(t.TempDir(), "missing.dng"), nil); err == nil {
+		t.Errorf("ExtractMetadataWithChecksum of a missing file succeeded")
+	}
+}
@@ -0,0 +1,45 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+)
+
+// Returns the clipping masks of img for rendering "zebra" overlays. In highlights the red, green and blue channels are
+// set to 0xff where the channel is at or above high, in shadows where it is at or below low (8-bit values).
+// Alpha is 0xff where any channel is clipped and 0 elsewhere, so the masks can be drawn over the image as they are.
+func ClippingMasks(img image.Image, high uint8, low uint8) (highlights *image.NRGBA, shadows *image.NRGBA) {
+	b := img.Bounds()
+	highlights = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	shadows = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			i := highlights.PixOffset(x-b.Min.X, y-b.Min.Y)
+			for c, v := range [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8)} {
+				if v >= high {
+					highlights.Pix[i+c] = 0xff
+					highlights.Pix[i+3] = 0xff
+				}
+				if v <= low {
+					shadows.Pix[i+c] = 0xff
+					shadows.Pix[i+3] = 0xff
+				}
+			}
+		}
+	}
+	return highlights, shadows
+}
+
+// Reads a RAW image file from file system and returns the clipping masks of its preview scaled down to fit
+// in maxDim x maxDim, see ClippingMasks and ImportRawPreview.
+func ImportClippingMasks(path string, maxDim int, high uint8, low uint8) (highlights *image.NRGBA, shadows *image.NRGBA, err error) {
+	img, err := ImportRawPreview(path, maxDim)
+	if err != nil {
+		return nil, nil, err
+	}
+	highlights, shadows = ClippingMasks(img, high, low)
+	return highlights, shadows, nil
+}
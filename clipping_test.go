@@ -0,0 +1,49 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestClippingMasks(t *testing.T) {
+	// an offset image, the masks start at the origin
+	img := image.NewRGBA(image.Rect(10, 10, 13, 11))
+	img.Set(10, 10, color.RGBA{R: 255, G: 128, B: 2, A: 255})
+	img.Set(11, 10, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+	img.Set(12, 10, color.RGBA{R: 0, G: 250, B: 255, A: 255})
+
+	highlights, shadows := ClippingMasks(img, 250, 2)
+	if highlights.Bounds() != image.Rect(0, 0, 3, 1) || shadows.Bounds() != image.Rect(0, 0, 3, 1) {
+		t.Fatalf("mask bounds = %v, %v, want 3x1", highlights.Bounds(), shadows.Bounds())
+	}
+	for _, test := range []struct {
+		name string
+		mask *image.NRGBA
+		x    int
+		want color.NRGBA
+	}{
+		{"highlights", highlights, 0, color.NRGBA{R: 0xff, A: 0xff}},
+		{"highlights", highlights, 1, color.NRGBA{}},
+		{"highlights", highlights, 2, color.NRGBA{G: 0xff, B: 0xff, A: 0xff}},
+		{"shadows", shadows, 0, color.NRGBA{B: 0xff, A: 0xff}},
+		{"shadows", shadows, 1, color.NRGBA{}},
+		{"shadows", shadows, 2, color.NRGBA{R: 0xff, A: 0xff}},
+	} {
+		if got := test.mask.NRGBAAt(test.x, 0); got != test.want {
+			t.Errorf("%v at %v = %v, want %v", test.name, test.x, got, test.want)
+		}
+	}
+}
+
+func TestImportClippingMasks(t *testing.T) {
+	highlights, shadows, err := ImportClippingMasks(testDNG(t, "RGGB"), 48, 255, 0)
+	if err != nil {
+		t.Fatalf("ImportClippingMasks failed: %v", err)
+	}
+	if want := image.Rect(0, 0, 48, 32); highlights.Bounds() != want || shadows.Bounds() != want {
+		t.Errorf("mask bounds = %v, %v, want %v", highlights.Bounds(), shadows.Bounds(), want)
+	}
+}
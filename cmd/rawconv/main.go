@@ -0,0 +1,248 @@
+//go:build cgo
+
+// Command rawconv converts RAW image files, extracts their embedded thumbnails and prints their metadata.
+//
+//	rawconv convert [-format jpeg] [-quality 90] [-max 0] [-half] [-workers N] [-out dir] [-overwrite] files...
+//	rawconv thumbnail [-workers N] [-out dir] [-overwrite] files...
+//	rawconv metadata [-workers N] [-csv] files...
+//
+// Files may be glob patterns (e.g. "DCIM/*.CR3"), quoted to keep the shell from expanding them.
+// The metadata is printed as one JSON object per line, with the path of the file and its metadata, or as CSV with -csv.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	raw "github.com/inokone/golibraw"
+)
+
+const usage = `usage: rawconv <command> [flags] files...
+
+commands:
+  convert     convert RAW files to JPEG, PNG, TIFF or PPM
+  thumbnail   extract the embedded thumbnails
+  metadata    print the metadata as JSON lines or CSV
+
+Run rawconv <command> -h for the flags of a command.
+`
+
+var formats = map[string]raw.Format{
+	"jpeg": raw.FormatJPEG,
+	"jpg":  raw.FormatJPEG,
+	"png":  raw.FormatPNG,
+	"tiff": raw.FormatTIFF,
+	"tif":  raw.FormatTIFF,
+	"ppm":  raw.FormatPPM,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "convert":
+		err = convert(ctx, os.Args[2:])
+	case "thumbnail":
+		err = thumbnail(ctx, os.Args[2:])
+	case "metadata":
+		err = metadata(ctx, os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command [%v]\n\n%v", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func convert(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	formatName := flags.String("format", "jpeg", "output format: jpeg, png, tiff or ppm")
+	quality := flags.Int("quality", 90, "JPEG quality (1-100)")
+	maxDim := flags.Int("max", 0, "scale JPEG and PNG outputs down to fit in max x max pixels, 0 for full size")
+	half := flags.Bool("half", false, "render at half size, much faster")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of files converted in parallel")
+	out := flags.String("out", ".", "output directory")
+	overwrite := flags.Bool("overwrite", false, "replace existing output files")
+	flags.Parse(args)
+
+	format, ok := formats[strings.ToLower(*formatName)]
+	if !ok {
+		return fmt.Errorf("unknown output format [%v]", *formatName)
+	}
+	inputs, err := expand(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	batch := raw.Batch{
+		Workers: *workers,
+		Quality: *quality,
+		MaxDim:  *maxDim,
+		Output:  raw.OutputOptions{Overwrite: *overwrite, Atomic: true},
+	}
+	if *half {
+		batch.Options = append(batch.Options, raw.WithHalfSize())
+	}
+	results, err := batch.Convert(ctx, inputs, *out, format)
+	if err != nil && results == nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%v: %v\n", result.Input, result.Err)
+			continue
+		}
+		fmt.Println(result.Output)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to convert [%v] of [%v] files", failed, len(results))
+	}
+	return err
+}
+
+func thumbnail(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("thumbnail", flag.ExitOnError)
+	workers := flags.Int("workers", runtime.NumCPU(), "number of files processed in parallel")
+	out := flags.String("out", ".", "output directory")
+	overwrite := flags.Bool("overwrite", false, "replace existing output files")
+	flags.Parse(args)
+
+	inputs, err := expand(flags.Args())
+	if err != nil {
+		return err
+	}
+	output := raw.OutputOptions{Overwrite: *overwrite, CreateDirs: true, Atomic: true}
+
+	return forEach(ctx, inputs, *workers, func(input string) (string, error) {
+		data, info, err := raw.ExtractThumbnailBytes(input)
+		if err != nil {
+			return "", err
+		}
+		// JPEG thumbnails are written as is, bitmap thumbnails as PPM
+		extension := ".jpg"
+		if info.Format != raw.ThumbnailJPEG {
+			extension = ".ppm"
+		}
+		base := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		path := filepath.Join(*out, base+extension)
+		return path, raw.WriteThumbnail(path, data, info, output)
+	})
+}
+
+func metadata(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("metadata", flag.ExitOnError)
+	workers := flags.Int("workers", runtime.NumCPU(), "number of files processed in parallel")
+	asCSV := flags.Bool("csv", false, "print the main fields as CSV instead of JSON lines")
+	flags.Parse(args)
+
+	inputs, err := expand(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *asCSV {
+		return raw.WriteMetadataCSV(os.Stdout, raw.ExtractMetadataAll(inputs, *workers))
+	}
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(os.Stdout)
+	return forEach(ctx, inputs, *workers, func(input string) (string, error) {
+		md, err := raw.ExtractMetadata(input)
+		if err != nil {
+			return "", err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return "", encoder.Encode(struct {
+			Path     string       `json:"path"`
+			Metadata raw.Metadata `json:"metadata"`
+		}{input, md})
+	})
+}
+
+// Expands the glob patterns of args, arguments without matches are kept as is to be reported as missing files
+func expand(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+	inputs := make([]string, 0, len(args))
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern [%v] with [%w]", arg, err)
+		}
+		if len(matches) == 0 {
+			matches = []string{arg}
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// Runs fn for the inputs with the given number of workers, printing the outputs and reporting the failures
+func forEach(ctx context.Context, inputs []string, workers int, fn func(input string) (string, error)) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for input := range jobs {
+				output, err := fn(input)
+				mu.Lock()
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "%v: %v\n", input, err)
+				} else if output != "" {
+					fmt.Println(output)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, input := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- input
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to process [%v] of [%v] files", failed, len(inputs))
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+// Command rawinfo prints the metadata of RAW image files, the camera, exposure, lens, GPS position and the highlights
+// of the maker notes as a table, or the full metadata as JSON.
+//
+//	rawinfo [-json] [-all] files...
+//
+// With -json, one JSON object is printed per line, with the path of the file and its metadata.
+// Without cgo, only the metadata recorded in the TIFF/EXIF container is available, see the package documentation.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	raw "github.com/inokone/golibraw"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the full metadata as JSON lines")
+	all := flag.Bool("all", false, "print the empty fields of the table too")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: rawinfo [-json] [-all] files...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	failed := 0
+	for i, path := range expand(flag.Args()) {
+		md, err := raw.ExtractMetadata(path)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%v: %v\n", path, err)
+			continue
+		}
+		if *asJSON {
+			err = encoder.Encode(struct {
+				Path     string       `json:"path"`
+				Metadata raw.Metadata `json:"metadata"`
+			}{path, md})
+		} else {
+			if i > 0 {
+				fmt.Println()
+			}
+			err = printTable(os.Stdout, path, md, *all)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// Expands the glob patterns of args, arguments without matches are kept as is to be reported as missing files
+func expand(args []string) []string {
+	inputs := make([]string, 0, len(args))
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil || len(matches) == 0 {
+			matches = []string{arg}
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs
+}
+
+type row struct {
+	name  string
+	value string
+}
+
+func printTable(w io.Writer, path string, md raw.Metadata, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "File\t%v\n", path)
+	for _, r := range rows(md) {
+		if r.value == "" && !all {
+			continue
+		}
+		fmt.Fprintf(tw, "%v\t%v\n", r.name, r.value)
+	}
+	return tw.Flush()
+}
+
+func rows(md raw.Metadata) []row {
+	rows := []row{
+		{"Camera", strings.TrimSpace(md.Camera.Make + " " + md.Camera.Model)},
+		{"Serial", md.Camera.Serial},
+		{"Software", md.Camera.Software},
+		{"Firmware", md.MakerNotes.Common.Firmware},
+		{"Captured", capturedAt(md)},
+		{"Size", dimensions(md.Width, md.Height)},
+		{"Raw size", dimensions(md.Sizes.RawWidth, md.Sizes.RawHeight)},
+		{"Sensor", sensor(md)},
+		{"Raw type", rawType(md.RawType)},
+		{"CFA", strings.Join(md.CFA.Rows(), " ")},
+		{"DNG", dng(md.DNG)},
+		{"File size", fileSize(md.DataSize)},
+		{"Orientation", number(float64(md.Orientation), "")},
+		{"Frames", count(md.RawCount)},
+		{"ISO", count(md.ISO)},
+		{"Shutter", shutter(md.Shutter)},
+		{"Aperture", aperture(md.Aperture)},
+		{"Exposure compensation", signed(md.ExposureCompensation, " EV")},
+		{"Focal length", focalLength(md)},
+		{"Flash", flash(md)},
+		{"Focus distance", number(md.FocusDistance, " m")},
+		{"Lens", strings.TrimSpace(md.Lens.Make + " " + md.Lens.Model)},
+		{"Lens serial", md.Lens.Serial},
+		{"Lens range", lensRange(md.Lens)},
+		{"White balance", whiteBalance(md)},
+		{"Shutter count", count(md.ShutterCount)},
+		{"Temperature", temperatures(md.Temperature)},
+		{"GPS", position(md.GPS)},
+		{"Artist", md.Artist},
+		{"Copyright", md.Copyright},
+		{"Description", md.Description},
+		{"Warnings", md.Warnings.String()},
+	}
+	return append(rows, makerNotes(md.MakerNotes)...)
+}
+
+// Highlights of the vendor specific maker notes, the full set is available with -json
+func makerNotes(notes raw.MakerNotes) []row {
+	switch {
+	case notes.Canon != nil:
+		return []row{
+			{"Canon quality", count(notes.Canon.Quality)},
+			{"Canon sRAW quality", count(notes.Canon.SRAWQuality)},
+			{"Canon highlight tone priority", count(notes.Canon.HighlightTonePriority)},
+			{"Canon auto lighting optimizer", count(notes.Canon.AutoLightingOptimizer)},
+			{"Canon AF micro adjustment", number(notes.Canon.AFMicroAdjValue, "")},
+		}
+	case notes.Nikon != nil:
+		return []row{
+			{"Nikon NEF compression", count(notes.Nikon.NEFCompression)},
+			{"Nikon Active D-Lighting", count(notes.Nikon.ActiveDLighting)},
+			{"Nikon vibration reduction", count(notes.Nikon.VibrationReduction)},
+			{"Nikon flash", strings.TrimSpace(notes.Nikon.FlashType + " " + notes.Nikon.FlashSetting)},
+			{"Nikon AF fine tune", count(notes.Nikon.AFFineTuneAdj)},
+		}
+	case notes.Sony != nil:
+		return []row{
+			{"Sony raw file type", count(notes.Sony.RawFileType)},
+			{"Sony quality", count(notes.Sony.Quality)},
+			{"Sony AF area mode", count(notes.Sony.AFAreaMode)},
+			{"Sony shots since power up", count(notes.Sony.ShotNumberSincePowerUp)},
+			{"Sony front curtain shutter", count(notes.Sony.ElectronicFrontCurtainShutter)},
+		}
+	case notes.Fuji != nil:
+		return []row{
+			{"Fuji film mode", count(notes.Fuji.FilmMode)},
+			{"Fuji dynamic range", count(notes.Fuji.DevelopmentDynamicRange)},
+			{"Fuji shutter type", count(notes.Fuji.ShutterType)},
+			{"Fuji rating", count(notes.Fuji.Rating)},
+			{"Fuji RAF version", notes.Fuji.RAFVersion},
+		}
+	case notes.Olympus != nil:
+		return []row{
+			{"Olympus camera type", notes.Olympus.CameraType},
+			{"Olympus Live ND", yesNo(notes.Olympus.LiveND)},
+		}
+	case notes.Pentax != nil:
+		return []row{
+			{"Pentax quality", count(notes.Pentax.Quality)},
+			{"Pentax multi exposure", count(notes.Pentax.MultiExposure)},
+			{"Pentax AF points in focus", count(notes.Pentax.AFPointsInFocus)},
+		}
+	}
+	return nil
+}
+
+func capturedAt(md raw.Metadata) string {
+	captured := md.Time()
+	if captured.IsZero() {
+		return ""
+	}
+	if md.TimeOffset != "" {
+		return captured.Format(time.RFC3339)
+	}
+	return captured.Format("2006-01-02 15:04:05")
+}
+
+func dimensions(width int, height int) string {
+	if width == 0 || height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v x %v (%.1f MP)", width, height, float64(width*height)/1e6)
+}
+
+func sensor(md raw.Metadata) string {
+	if md.SensorFormat == "" {
+		return ""
+	}
+	if md.CropFactor > 0 {
+		return fmt.Sprintf("%v (crop factor %.2g)", md.SensorFormat, md.CropFactor)
+	}
+	return md.SensorFormat
+}
+
+func dng(info *raw.DNGInfo) string {
+	if info == nil {
+		return ""
+	}
+	parts := []string{info.Version}
+	if info.BaselineExposure != 0 {
+		parts = append(parts, fmt.Sprintf("baseline exposure %+.2g EV", info.BaselineExposure))
+	}
+	if len(info.Opcodes) > 0 {
+		names := make([]string, len(info.Opcodes))
+		for i, opcode := range info.Opcodes {
+			names[i] = opcode.Name
+		}
+		parts = append(parts, "opcodes "+strings.Join(names, ", "))
+	}
+	if info.ProfileGainTableMap {
+		parts = append(parts, "profile gain table map")
+	}
+	return strings.Join(parts, "; ")
+}
+
+func fileSize(size int64) string {
+	switch {
+	case size == 0:
+		return ""
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	default:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+}
+
+func shutter(seconds float64) string {
+	switch {
+	case seconds <= 0:
+		return ""
+	case seconds < 0.5:
+		return fmt.Sprintf("1/%.0f s", 1/seconds)
+	default:
+		return fmt.Sprintf("%g s", seconds)
+	}
+}
+
+func aperture(f float64) string {
+	if f <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("f/%.1f", f)
+}
+
+func focalLength(md raw.Metadata) string {
+	if md.FocalLength <= 0 {
+		return ""
+	}
+	if equivalent := md.EquivalentFocalLength(); equivalent > 0 && equivalent != md.FocalLength {
+		return fmt.Sprintf("%g mm (%.0f mm equiv.)", md.FocalLength, equivalent)
+	}
+	return fmt.Sprintf("%g mm", md.FocalLength)
+}
+
+func lensRange(lens raw.Lens) string {
+	if lens.MinFocal <= 0 {
+		return ""
+	}
+	focal := fmt.Sprintf("%g", lens.MinFocal)
+	if lens.MaxFocal > lens.MinFocal {
+		focal += fmt.Sprintf("-%g", lens.MaxFocal)
+	}
+	ap := ""
+	if lens.MaxAp4MinFocal > 0 {
+		ap = fmt.Sprintf(" f/%.1f", lens.MaxAp4MinFocal)
+		if lens.MaxAp4MaxFocal > 0 && lens.MaxAp4MaxFocal != lens.MaxAp4MinFocal {
+			ap += fmt.Sprintf("-%.1f", lens.MaxAp4MaxFocal)
+		}
+	}
+	return focal + " mm" + ap
+}
+
+func whiteBalance(md raw.Metadata) string {
+	if md.ColorTemperature <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f K, tint %+.1f", md.ColorTemperature, md.Tint)
+}
+
+func temperatures(t raw.Temperatures) string {
+	var parts []string
+	for _, v := range []struct {
+		name  string
+		value *float64
+	}{{"camera", t.Camera}, {"sensor", t.Sensor}, {"lens", t.Lens}, {"battery", t.Battery}, {"ambient", t.Ambient}} {
+		if v.value != nil {
+			parts = append(parts, fmt.Sprintf("%v %.1f °C", v.name, *v.value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func position(gps *raw.GPS) string {
+	if gps == nil {
+		return ""
+	}
+	s := fmt.Sprintf("%.6f, %.6f", gps.Latitude, gps.Longitude)
+	if gps.Altitude != 0 {
+		s += fmt.Sprintf(", %.0f m", gps.Altitude)
+	}
+	if gps.Status == 'V' {
+		s += " (void fix)"
+	}
+	return s
+}
+
+func count(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func number(v float64, unit string) string {
+	if v == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%g%v", v, unit)
+}
+
+func signed(v float64, unit string) string {
+	if v == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.2g%v", v, unit)
+}
+
+func rawType(t raw.RawType) string {
+	if t == raw.RawTypeUnknown {
+		return ""
+	}
+	return t.String()
+}
+
+// Reports whether the flash fired, unknown for files without the record
+func flash(md raw.Metadata) string {
+	if !md.FlashRecorded {
+		return "unknown"
+	}
+	return yesNo(md.Flash)
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	raw "github.com/inokone/golibraw"
+)
+
+func TestFormat(t *testing.T) {
+	celsius := 21.5
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"fast shutter", shutter(1.0 / 250), "1/250 s"},
+		{"slow shutter", shutter(2), "2 s"},
+		{"unknown shutter", shutter(0), ""},
+		{"aperture", aperture(2.8), "f/2.8"},
+		{"focal length", focalLength(raw.Metadata{FocalLength: 24, Lens: raw.Lens{CurFocal: 24}, CropFactor: 1.5}), "24 mm (36 mm equiv.)"},
+		{"full frame focal length", focalLength(raw.Metadata{FocalLength: 50, Lens: raw.Lens{CurFocal: 50}, CropFactor: 1}), "50 mm"},
+		{"zoom", lensRange(raw.Lens{MinFocal: 24, MaxFocal: 70, MaxAp4MinFocal: 2.8, MaxAp4MaxFocal: 2.8}), "24-70 mm f/2.8"},
+		{"variable aperture zoom", lensRange(raw.Lens{MinFocal: 18, MaxFocal: 55, MaxAp4MinFocal: 3.5, MaxAp4MaxFocal: 5.6}), "18-55 mm f/3.5-5.6"},
+		{"prime", lensRange(raw.Lens{MinFocal: 35, MaxFocal: 35}), "35 mm"},
+		{"dimensions", dimensions(6000, 4000), "6000 x 4000 (24.0 MP)"},
+		{"file size", fileSize(25 << 20), "25.0 MiB"},
+		{"small file size", fileSize(1536), "1.5 KiB"},
+		{"GPS", position(&raw.GPS{Latitude: 47.5, Longitude: -19.05, Altitude: 120, Status: 'V'}), "47.500000, -19.050000, 120 m (void fix)"},
+		{"temperatures", temperatures(raw.Temperatures{Sensor: &celsius}), "sensor 21.5 °C"},
+		{"exposure compensation", signed(-0.7, " EV"), "-0.7 EV"},
+		{"unknown flash", flash(raw.Metadata{Flash: true}), "unknown"},
+		{"flash", flash(raw.Metadata{Flash: true, FlashRecorded: true}), "yes"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%v = %q, want %q", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	md := raw.Metadata{
+		Camera:      raw.Camera{Make: "Canon", Model: "EOS R5"},
+		ISO:         100,
+		MakerNotes:  raw.MakerNotes{Canon: &raw.CanonNotes{Quality: 4}},
+		RawType:     raw.RawTypeBayer,
+		Orientation: 1,
+	}
+	var buf bytes.Buffer
+	if err := printTable(&buf, "IMG_0001.CR3", md, false); err != nil {
+		t.Fatal(err)
+	}
+	table := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		name, value, _ := strings.Cut(line, "  ")
+		table[name] = strings.TrimSpace(value)
+	}
+	for name, want := range map[string]string{
+		"File":          "IMG_0001.CR3",
+		"Camera":        "Canon EOS R5",
+		"ISO":           "100",
+		"Raw type":      "bayer",
+		"Canon quality": "4",
+	} {
+		if table[name] != want {
+			t.Errorf("%v = %q, want %q:\n%v", name, table[name], want, buf.String())
+		}
+	}
+	if _, ok := table["Lens"]; ok {
+		t.Errorf("table has the empty lens row:\n%v", buf.String())
+	}
+
+	buf.Reset()
+	if err := printTable(&buf, "IMG_0001.CR3", md, true); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 1+len(rows(md)) {
+		t.Errorf("table with all fields has %v lines, want %v", lines, 1+len(rows(md)))
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.dng", "b.dng", "c.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "missing.dng")
+	got := expand([]string{filepath.Join(dir, "*.dng"), missing})
+	want := []string{filepath.Join(dir, "a.dng"), filepath.Join(dir, "b.dng"), missing}
+	if !slices.Equal(got, want) {
+		t.Errorf("expand = %v, want %v", got, want)
+	}
+}
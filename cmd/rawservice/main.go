@@ -0,0 +1,267 @@
+//go:build cgo
+
+// Command rawservice exposes the conversion, metadata and thumbnail functions of the package as a JSON over HTTP
+// service on a local unix socket, so applications in other languages can use libraw without shelling out to
+// dcraw_emu for every file.
+//
+//	rawservice [-socket /tmp/rawservice.sock] [-addr 127.0.0.1:8765 -token-file token] [-workers N]
+//
+// Every call is a POST request with a JSON body and the Content-Type application/json, answered with a JSON object.
+// Failures are answered with a non-2xx status and {"error": "..."}. Paths are file system paths of the host running
+// the service. On TCP, which any local process and web page can reach, every request has to carry the token read from
+// -token-file (or the RAWSERVICE_TOKEN environment variable) in an "Authorization: Bearer <token>" header.
+//
+//	POST /convert    {"input": "a.NEF", "output": "a.jpg", "format": "jpeg", "quality": 90, "max_dim": 2048, "half": false, "overwrite": false}
+//	                 -> {"output": "a.jpg"}
+//	POST /thumbnail  {"input": "a.NEF", "output": "a.thumb.jpg", "overwrite": false}
+//	                 -> {"output": "a.thumb.jpg"}
+//	POST /metadata   {"input": "a.NEF"}
+//	                 -> {"metadata": {...}}
+//
+// For example with curl:
+//
+//	curl --unix-socket /tmp/rawservice.sock -H 'Content-Type: application/json' -d '{"input": "a.NEF"}' http://rawservice/metadata
+//
+// max_dim scales JPEG and PNG outputs down, it is rejected for TIFF and PPM outputs which are written at full size.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+
+	raw "github.com/inokone/golibraw"
+)
+
+// Largest accepted request body, the requests only carry paths and settings
+const maxRequestSize = 1 << 20
+
+type request struct {
+	Input     string `json:"input"`
+	Output    string `json:"output"`
+	Format    string `json:"format"`
+	Quality   int    `json:"quality"`
+	MaxDim    int    `json:"max_dim"`
+	Half      bool   `json:"half"`
+	Overwrite bool   `json:"overwrite"`
+}
+
+type response struct {
+	Output   string        `json:"output,omitempty"`
+	Metadata *raw.Metadata `json:"metadata,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Limits the number of files processed at the same time, the rest of the requests wait
+type service struct {
+	slots chan struct{}
+	// bearer token required on TCP, empty on the unix socket
+	token string
+}
+
+func main() {
+	socket := flag.String("socket", "/tmp/rawservice.sock", "unix socket to listen on")
+	addr := flag.String("addr", "", "TCP address to listen on instead of the socket, e.g. 127.0.0.1:8765")
+	tokenFile := flag.String("token-file", "", "file of the token required on TCP, RAWSERVICE_TOKEN if not set")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of files processed in parallel")
+	flag.Parse()
+
+	s := &service{slots: make(chan struct{}, max(*workers, 1))}
+	if *addr != "" {
+		token, err := readToken(*tokenFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		s.token = token
+	}
+
+	listener, err := listen(*socket, *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/convert", s.handle(s.convert))
+	mux.HandleFunc("/thumbnail", s.handle(s.thumbnail))
+	mux.HandleFunc("/metadata", s.handle(s.metadata))
+	server := &http.Server{Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "listening on %v\n", listener.Addr())
+	if err = server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Reads the token required on TCP from path, or from the RAWSERVICE_TOKEN environment variable without path
+func readToken(path string) (string, error) {
+	token := os.Getenv("RAWSERVICE_TOKEN")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file [%v] with [%w]", path, err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return "", fmt.Errorf("listening on TCP requires a token, set -token-file or RAWSERVICE_TOKEN")
+	}
+	return token, nil
+}
+
+func listen(socket string, addr string) (net.Listener, error) {
+	if addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on [%v] with [%w]", addr, err)
+		}
+		return listener, nil
+	}
+	// a socket left behind by a previous run would fail the listen
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socket)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on [%v] with [%w]", socket, err)
+	}
+	return listener, nil
+}
+
+// Decodes the request, runs fn in a free slot and encodes its response
+func (s *service) handle(fn func(ctx context.Context, req request) (response, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			reply(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+			return
+		}
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			reply(w, http.StatusUnauthorized, response{Error: "unauthorized"})
+			return
+		}
+		// browsers send cross-origin JSON requests only after a preflight the service does not answer
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			reply(w, http.StatusUnsupportedMediaType, response{Error: "the request body has to be application/json"})
+			return
+		}
+		var req request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+			reply(w, http.StatusBadRequest, response{Error: fmt.Sprintf("invalid request with [%v]", err)})
+			return
+		}
+		if req.Input == "" {
+			reply(w, http.StatusBadRequest, response{Error: "missing input"})
+			return
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		case <-r.Context().Done():
+			return
+		}
+		res, err := fn(r.Context(), req)
+		if err != nil {
+			reply(w, http.StatusUnprocessableEntity, response{Error: err.Error()})
+			return
+		}
+		reply(w, http.StatusOK, res)
+	}
+}
+
+// Checks the bearer token of the request, every request is authorized on the unix socket
+func (s *service) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func reply(w http.ResponseWriter, status int, res response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *service) convert(ctx context.Context, req request) (response, error) {
+	if req.Output == "" {
+		return response{}, fmt.Errorf("missing output")
+	}
+	// the conversion is aborted when the client disconnects or the service shuts down, releasing the worker slot
+	opts := []raw.Option{
+		raw.WithContext(ctx),
+		raw.WithOutputOptions(raw.OutputOptions{Overwrite: req.Overwrite, Atomic: true}),
+	}
+	if req.Half {
+		opts = append(opts, raw.WithHalfSize())
+	}
+
+	format := strings.ToLower(req.Format)
+	if req.MaxDim > 0 {
+		switch format {
+		case "", "jpeg", "jpg", "png":
+			opts = append(opts, raw.WithResize(req.MaxDim, raw.ResizeBox))
+		default:
+			return response{}, fmt.Errorf("max_dim is not supported for [%v] output", format)
+		}
+	}
+
+	var err error
+	switch format {
+	case "", "jpeg", "jpg":
+		err = raw.ExportJPEG(req.Input, req.Output, req.Quality, 0, opts...)
+	case "png":
+		err = raw.ExportPNG(req.Input, req.Output, opts...)
+	case "tiff", "tif":
+		err = raw.ExportTIFF(req.Input, req.Output, opts...)
+	case "ppm":
+		err = raw.ExportPPM(req.Input, req.Output, opts...)
+	default:
+		return response{}, fmt.Errorf("unknown output format [%v]", req.Format)
+	}
+	if err != nil {
+		return response{}, err
+	}
+	return response{Output: req.Output}, nil
+}
+
+func (s *service) thumbnail(ctx context.Context, req request) (response, error) {
+	if req.Output == "" {
+		return response{}, fmt.Errorf("missing output")
+	}
+	output := raw.OutputOptions{Overwrite: req.Overwrite, Atomic: true}
+	if err := raw.ExtractThumbnail(req.Input, req.Output, raw.WithContext(ctx), raw.WithOutputOptions(output)); err != nil {
+		return response{}, err
+	}
+	return response{Output: req.Output}, nil
+}
+
+func (s *service) metadata(_ context.Context, req request) (response, error) {
+	metadata, err := raw.ExtractMetadata(req.Input)
+	if err != nil {
+		return response{}, err
+	}
+	return response{Metadata: &metadata}, nil
+}
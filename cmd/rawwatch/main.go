@@ -0,0 +1,107 @@
+//go:build cgo
+
+// Command rawwatch watches a hot folder, e.g. the target directory of tethered shooting, and processes the RAW files
+// arriving in it: it renders them, extracts their thumbnails and writes their metadata as JSON.
+//
+//	rawwatch [-render jpeg] [-thumbnail] [-metadata] [-quality 90] [-max 0] [-half] [-existing] [-poll] [-interval 1s] [-workers 1] [-out dir] [-overwrite] dir
+//
+// The written files of every processed input are printed as one JSON object per line, with the metadata of the input.
+// Stop watching with Ctrl+C.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	raw "github.com/inokone/golibraw"
+)
+
+var formats = map[string]raw.Format{
+	"jpeg": raw.FormatJPEG,
+	"jpg":  raw.FormatJPEG,
+	"png":  raw.FormatPNG,
+	"tiff": raw.FormatTIFF,
+	"tif":  raw.FormatTIFF,
+	"ppm":  raw.FormatPPM,
+}
+
+func main() {
+	render := flag.String("render", "", "render new files to jpeg, png, tiff or ppm, nothing by default")
+	thumbnail := flag.Bool("thumbnail", false, "extract the embedded thumbnails of new files")
+	metadata := flag.Bool("metadata", false, "write the metadata of new files as JSON")
+	quality := flag.Int("quality", 90, "JPEG quality (1-100)")
+	maxDim := flag.Int("max", 0, "scale JPEG and PNG outputs down to fit in max x max pixels, 0 for full size")
+	half := flag.Bool("half", false, "render at half size, much faster")
+	existing := flag.Bool("existing", false, "process the files already in the directory too")
+	poll := flag.Bool("poll", false, "scan the directory periodically instead of relying on notifications, for network shares")
+	interval := flag.Duration("interval", time.Second, "time between two scans of the directory when polling")
+	workers := flag.Int("workers", 1, "number of files processed in parallel")
+	out := flag.String("out", "", "output directory, the processed subdirectory of the watched directory by default")
+	overwrite := flag.Bool("overwrite", false, "replace existing output files")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: rawwatch [flags] dir")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	watcher := raw.Watcher{
+		Interval:  *interval,
+		Poll:      *poll,
+		OutputDir: *out,
+		Thumbnail: *thumbnail,
+		Metadata:  *metadata,
+		Existing:  *existing,
+		Batch: raw.Batch{
+			Workers: *workers,
+			Quality: *quality,
+			MaxDim:  *maxDim,
+			Output:  raw.OutputOptions{Overwrite: *overwrite, Atomic: true},
+		},
+	}
+	if *render != "" {
+		format, ok := formats[strings.ToLower(*render)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown output format [%v]\n", *render)
+			os.Exit(2)
+		}
+		watcher.Render, watcher.Format = true, format
+	}
+	if *half {
+		watcher.Batch.Options = append(watcher.Batch.Options, raw.WithHalfSize())
+	}
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(os.Stdout)
+	watcher.OnFile = func(event raw.WatchEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", event.Input, event.Err)
+			return
+		}
+		encoder.Encode(struct {
+			Path     string       `json:"path"`
+			Outputs  []string     `json:"outputs"`
+			Metadata raw.Metadata `json:"metadata"`
+		}{event.Input, event.Outputs, event.Metadata})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := watcher.Watch(ctx, flag.Arg(0)); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
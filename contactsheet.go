@@ -0,0 +1,243 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Defaults of a ContactSheet
+const (
+	defaultSheetColumns  = 4
+	defaultSheetCellSize = 320
+	defaultSheetMargin   = 12
+)
+
+// ContactSheet renders a grid of the embedded previews of RAW files with their file names and exposure as captions,
+// e.g. for a quick review of a shoot or for client proofing. Files without a usable preview are rendered from the RAW data.
+type ContactSheet struct {
+	// Number of previews in a row, 4 by default
+	Columns int
+	// Longest side of the previews in pixels, 320 by default
+	CellSize int
+	// Space between the previews and around the grid in pixels, 12 by default
+	Margin int
+	// Omits the captions below the previews
+	NoCaptions bool
+	// Color of the background and of the captions, dark grey and light grey by default
+	Background color.Color
+	Foreground color.Color
+	// Number of files loaded in parallel, the number of CPUs by default
+	Workers int
+	// JPEG quality (1-100) of Export, the default JPEG quality if not set
+	Quality int
+	// How Export creates the output file
+	Output OutputOptions
+}
+
+// Renders the contact sheet of the RAW image files of paths, in their order. Files failing to load leave an empty cell
+// with their caption, the sheet is returned together with the errors of all failing files.
+func (s ContactSheet) Render(paths []string) (*image.RGBA, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+	columns := positive(s.Columns, defaultSheetColumns)
+	cellSize := positive(s.CellSize, defaultSheetCellSize)
+	margin := positive(s.Margin, defaultSheetMargin)
+	background := color.RGBAModel.Convert(orDefault(s.Background, color.RGBA{0x20, 0x20, 0x20, 0xff})).(color.RGBA)
+	foreground := color.RGBAModel.Convert(orDefault(s.Foreground, color.RGBA{0xd0, 0xd0, 0xd0, 0xff})).(color.RGBA)
+
+	// captions are scaled with the cell size, two lines: the file name and the exposure
+	scale := max(1, cellSize/320)
+	lineHeight := (glyphHeight + 3) * scale
+	captionHeight := 0
+	if !s.NoCaptions {
+		captionHeight = 2*lineHeight + margin/2
+	}
+
+	rows := (len(paths) + columns - 1) / columns
+	width := columns*cellSize + (columns+1)*margin
+	height := rows*(cellSize+captionHeight) + (rows+1)*margin
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(sheet, sheet.Rect, image.NewUniform(background), image.Point{}, draw.Src)
+
+	errs := make([]error, len(paths))
+	s.forEach(paths, func(i int, path string) {
+		col, row := i%columns, i/columns
+		left := margin + col*(cellSize+margin)
+		top := margin + row*(cellSize+captionHeight+margin)
+
+		metadata, err := ExtractMetadata(path)
+		if err != nil {
+			errs[i] = err
+		}
+		if !s.NoCaptions {
+			caption := fitText(filepath.Base(path), cellSize, scale)
+			drawText(sheet, left, top+cellSize+margin/2, caption, scale, foreground)
+			drawText(sheet, left, top+cellSize+margin/2+lineHeight, fitText(exposureCaption(metadata), cellSize, scale), scale, foreground)
+		}
+		if err != nil {
+			return
+		}
+
+		// embedded previews are stored unrotated, renders are rotated by libraw
+		preview, err := ImportThumbnail(path)
+		if err == nil {
+			preview = orient(preview, metadata.Orientation)
+		} else {
+			logFallback("no usable thumbnail, rendering RAW data", "path", path, "error", err)
+			preview, err = ImportRawPreview(path, cellSize)
+		}
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		preview = resample(preview, cellSize, ResizeCatmullRom)
+		bounds := preview.Bounds()
+		// centered horizontally, aligned to the bottom of the cell above the caption
+		at := image.Pt(left+(cellSize-bounds.Dx())/2, top+cellSize-bounds.Dy())
+		draw.Draw(sheet, image.Rectangle{Min: at, Max: at.Add(bounds.Size())}, preview, bounds.Min, draw.Src)
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("file [%v] failed with [%w]", paths[i], err)
+		}
+	}
+	return sheet, errors.Join(errs...)
+}
+
+// Renders the contact sheet of paths and exports it to exportPath, in PNG format for a .png extension and in JPEG
+// format otherwise. Nothing is written if any of the files fails to load.
+func (s ContactSheet) Export(paths []string, exportPath string) error {
+	quality := s.Quality
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("invalid JPEG quality [%v]", quality)
+	}
+	if err := s.Output.prepare(exportPath); err != nil {
+		return err
+	}
+
+	sheet, err := s.Render(paths)
+	if err != nil {
+		return err
+	}
+	return encodeFile(exportPath, s.Output, func(w io.Writer) error {
+		if strings.EqualFold(filepath.Ext(exportPath), ".png") {
+			return png.Encode(w, sheet)
+		}
+		return jpeg.Encode(w, sheet, &jpeg.Options{Quality: quality})
+	})
+}
+
+// Calls fn for every path from the workers, the cells of the sheet do not overlap
+func (s ContactSheet) forEach(paths []string, fn func(i int, path string)) {
+	workers := positive(s.Workers, runtime.NumCPU())
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Returns the exposure of the image as "1/250 s  f/2.8  ISO 100  50 mm", leaving out the unknown values
+func exposureCaption(m Metadata) string {
+	var parts []string
+	switch {
+	case m.Shutter <= 0:
+	case m.Shutter < 0.5:
+		parts = append(parts, fmt.Sprintf("1/%.0f s", 1/m.Shutter))
+	default:
+		parts = append(parts, fmt.Sprintf("%g s", m.Shutter))
+	}
+	if m.Aperture > 0 {
+		parts = append(parts, fmt.Sprintf("f/%.1f", m.Aperture))
+	}
+	if m.ISO > 0 {
+		parts = append(parts, fmt.Sprintf("ISO %v", m.ISO))
+	}
+	if m.FocalLength > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f mm", m.FocalLength))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// Shortens text to fit in width pixels, marking the cut with a trailing ".."
+func fitText(text string, width int, scale int) string {
+	runes := []rune(text)
+	if textWidth(text, scale) <= width {
+		return text
+	}
+	for len(runes) > 0 && textWidth(string(runes)+"..", scale) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + ".."
+}
+
+// Rotates img upright for the EXIF orientations turning the image (3, 6 and 8), the mirrored ones are rare for RAW files
+func orient(img image.Image, orientation int) image.Image {
+	if orientation != 3 && orientation != 6 && orientation != 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var out *image.RGBA
+	if orientation == 3 {
+		out = image.NewRGBA(image.Rect(0, 0, w, h))
+	} else {
+		out = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			switch orientation {
+			case 3:
+				out.Set(w-1-x, h-1-y, c)
+			case 6:
+				out.Set(h-1-y, x, c)
+			case 8:
+				out.Set(y, w-1-x, c)
+			}
+		}
+	}
+	return out
+}
+
+func positive(v int, fallback int) int {
+	if v > 0 {
+		return v
+	}
+	return fallback
+}
+
+func orDefault(c color.Color, fallback color.Color) color.Color {
+	if c != nil {
+		return c
+	}
+	return fallback
+}
@@ -0,0 +1,138 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContactSheet(t *testing.T) {
+	preview, _ := previewDNG(t)
+	plain := testDNG(t, "RGGB")
+	paths := []string{preview, plain, preview}
+
+	sheet := ContactSheet{Columns: 2, CellSize: 40, Margin: 4, Workers: 2}
+	img, err := sheet.Render(paths)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	// two rows of two cells, each cell followed by a two line caption
+	captionHeight := 2*(glyphHeight+3) + 4/2
+	want := image.Rect(0, 0, 2*40+3*4, 2*(40+captionHeight)+3*4)
+	if img.Bounds() != want {
+		t.Errorf("sheet bounds = %v, want %v", img.Bounds(), want)
+	}
+	background := color.RGBA{0x20, 0x20, 0x20, 0xff}
+	if c := img.RGBAAt(0, 0); c != background {
+		t.Errorf("margin = %v, want the background %v", c, background)
+	}
+	// the embedded preview is scaled to the cell width, aligned to the bottom of the cell
+	if c := img.RGBAAt(4+20, 4+40-1); c == background {
+		t.Errorf("preview cell is empty")
+	}
+	// the empty fourth cell only has the background
+	if c := img.RGBAAt(4+40+4+20, 4+40+captionHeight+4+20); c != background {
+		t.Errorf("empty cell = %v, want the background", c)
+	}
+
+	noCaptions, err := ContactSheet{Columns: 3, CellSize: 40, Margin: 4, NoCaptions: true}.Render(paths)
+	if err != nil {
+		t.Fatalf("Render without captions failed: %v", err)
+	}
+	if want := image.Rect(0, 0, 3*40+4*4, 40+2*4); noCaptions.Bounds() != want {
+		t.Errorf("sheet bounds without captions = %v, want %v", noCaptions.Bounds(), want)
+	}
+
+	// failing files leave an empty cell, the others are rendered
+	missing := filepath.Join(t.TempDir(), "missing.dng")
+	img, err = sheet.Render([]string{missing, preview})
+	if err == nil {
+		t.Errorf("Render succeeded with a missing file")
+	}
+	if img == nil || img.RGBAAt(4+40+4+20, 4+40-1) == background {
+		t.Errorf("preview after the missing file is not rendered")
+	}
+	if _, err := sheet.Render(nil); err == nil {
+		t.Errorf("Render succeeded without files")
+	}
+}
+
+func TestContactSheetExport(t *testing.T) {
+	preview, _ := previewDNG(t)
+	dir := t.TempDir()
+	for _, name := range []string{"sheet.jpg", "sheet.png"} {
+		output := filepath.Join(dir, name)
+		if err := (ContactSheet{CellSize: 40}).Export([]string{preview}, output); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		want := map[string]string{"sheet.jpg": "jpeg", "sheet.png": "png"}[name]
+		if _, format := decodeConfigFile(t, output); format != want {
+			t.Errorf("format of %v = %v, want %v", name, format, want)
+		}
+	}
+
+	failed := filepath.Join(dir, "failed.jpg")
+	if err := (ContactSheet{}).Export([]string{filepath.Join(dir, "missing.dng")}, failed); err == nil {
+		t.Errorf("Export succeeded with a missing file")
+	}
+	if err := (ContactSheet{Quality: 101}).Export([]string{preview}, failed); err == nil {
+		t.Errorf("Export succeeded with quality 101")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("failed export left its output: %v", err)
+	}
+}
+
+func TestExposureCaption(t *testing.T) {
+	for _, tt := range []struct {
+		m    Metadata
+		want string
+	}{
+		{Metadata{Shutter: 1.0 / 250, Aperture: 2.8, ISO: 100, FocalLength: 50}, "1/250 s  f/2.8  ISO 100  50 mm"},
+		{Metadata{Shutter: 2, ISO: 3200}, "2 s  ISO 3200"},
+		{Metadata{}, ""},
+	} {
+		if got := exposureCaption(tt.m); got != tt.want {
+			t.Errorf("exposureCaption(%+v) = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestFitText(t *testing.T) {
+	if got := fitText("IMG_0001.CR3", 100, 1); got != "IMG_0001.CR3" {
+		t.Errorf("fitText of a fitting text = %q, want it unchanged", got)
+	}
+	got := fitText("IMG_0001.CR3", 40, 1)
+	if got != "IMG_.." || textWidth(got, 1) > 40 {
+		t.Errorf("fitText of a long text = %q, want \"IMG_..\"", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	marker := color.RGBA{0xff, 0, 0, 0xff}
+	img.SetRGBA(0, 0, marker)
+	for _, tt := range []struct {
+		orientation int
+		size        image.Point
+		at          image.Point
+	}{
+		{1, image.Pt(3, 2), image.Pt(0, 0)},
+		{3, image.Pt(3, 2), image.Pt(2, 1)},
+		{6, image.Pt(2, 3), image.Pt(1, 0)},
+		{8, image.Pt(2, 3), image.Pt(0, 2)},
+	} {
+		out := orient(img, tt.orientation)
+		if out.Bounds().Size() != tt.size {
+			t.Errorf("orientation %v size = %v, want %v", tt.orientation, out.Bounds().Size(), tt.size)
+			continue
+		}
+		if c := color.RGBAModel.Convert(out.At(tt.at.X, tt.at.Y)); c != marker {
+			t.Errorf("orientation %v has %v at %v, want the top left corner", tt.orientation, c, tt.at)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package golibraw
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Reads the known-corrupt files of testdata/corrupt: random bytes, a DNG truncated after its IFD, a TIFF with looping
+// IFDs and oversized counts, and a RAF with a broken preview
+func corruptFiles(t testing.TB) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "corrupt", "*"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("corrupt test files are missing")
+	}
+	files := map[string][]byte{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(path)] = data
+	}
+	return files
+}
+
+func TestDetectFormatCorrupt(t *testing.T) {
+	want := map[string]string{
+		"garbage.raw":     "",
+		"truncated.dng":   "DNG",
+		"ifd-loop.tif":    "",
+		"bad-preview.raf": "RAF",
+	}
+	for name, data := range corruptFiles(t) {
+		if got := DetectFormat(data); got != want[name] {
+			t.Errorf("DetectFormat(%v) = %q, want %q", name, got, want[name])
+		}
+		for n := 0; n < len(data); n += 7 {
+			DetectFormat(data[:n])
+		}
+	}
+}
+
+func TestReadExifCorrupt(t *testing.T) {
+	for name, data := range corruptFiles(t) {
+		for n := 0; n <= len(data); n += 7 {
+			if exif := readExif(bytes.NewReader(data[:n])); exif.shutterCount != 0 || exif.hasFlash {
+				t.Errorf("readExif(%v[:%v]) = %+v, want no fields", name, n, exif)
+			}
+		}
+	}
+}
+
+func TestExtractMetadataBytesGarbage(t *testing.T) {
+	if _, err := ExtractMetadataBytes(corruptFiles(t)["garbage.raw"]); err == nil {
+		t.Errorf("ExtractMetadataBytes of random bytes succeeded")
+	}
+}
+
+func FuzzReadExif(f *testing.F) {
+	for _, data := range corruptFiles(f) {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DetectFormat(data)
+		readExif(bytes.NewReader(data))
+	})
+}
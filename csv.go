@@ -0,0 +1,111 @@
+package golibraw
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Columns written by WriteMetadataCSV
+var csvHeader = []string{
+	"path", "format", "error", "time", "make", "model", "serial", "lens", "focal_length", "focal_length_35mm",
+	"aperture", "shutter", "iso", "exposure_compensation", "flash", "width", "height", "orientation", "shutter_count",
+	"latitude", "longitude", "altitude", "data_size",
+}
+
+// Extracts the metadata of many RAW image files with the given number of workers (the number of CPUs if not positive),
+// e.g. for computing statistics over an archive. The results are in the order of paths, a failing file does not stop
+// the others and is reported with its error.
+func ExtractMetadataAll(paths []string, workers int) []RawFileInfo {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	files := make([]RawFileInfo, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				format, _ := detectFile(paths[i])
+				metadata, err := ExtractMetadata(paths[i])
+				files[i] = RawFileInfo{Path: paths[i], Format: format, Metadata: metadata, Err: err}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return files
+}
+
+// Writes the most used metadata fields of files as CSV with a header row, one row per file, e.g. for analyzing focal
+// length usage or the ISO distribution of an archive in a spreadsheet or with pandas. Times are in RFC 3339 format,
+// shutter speeds in seconds and unknown values are empty.
+func WriteMetadataCSV(w io.Writer, files []RawFileInfo) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV with [%w]", err)
+	}
+	for _, file := range files {
+		if err := out.Write(csvRecord(file)); err != nil {
+			return fmt.Errorf("failed to write CSV with [%w]", err)
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV with [%w]", err)
+	}
+	return nil
+}
+
+func csvRecord(file RawFileInfo) []string {
+	m := file.Metadata
+	record := make([]string, 0, len(csvHeader))
+	errText := ""
+	if file.Err != nil {
+		errText = file.Err.Error()
+	}
+	captured := ""
+	if t := m.Time(); !t.IsZero() {
+		captured = t.Format(time.RFC3339)
+	}
+	flash := ""
+	if m.FlashRecorded {
+		flash = strconv.FormatBool(m.Flash)
+	}
+	record = append(record, file.Path, file.Format, errText, captured, m.Camera.Make, m.Camera.Model, m.Camera.Serial,
+		m.Lens.Model, csvFloat(m.FocalLength), csvFloat(m.EquivalentFocalLength()), csvFloat(m.Aperture),
+		csvFloat(m.Shutter), csvInt(int64(m.ISO)), strconv.FormatFloat(m.ExposureCompensation, 'g', -1, 64),
+		flash, csvInt(int64(m.Width)), csvInt(int64(m.Height)), csvInt(int64(m.Orientation)),
+		csvInt(int64(m.ShutterCount)))
+	if m.GPS != nil {
+		record = append(record, csvFloat(m.GPS.Latitude), csvFloat(m.GPS.Longitude), strconv.FormatFloat(m.GPS.Altitude, 'g', -1, 64))
+	} else {
+		record = append(record, "", "", "")
+	}
+	return append(record, csvInt(m.DataSize))
+}
+
+// Formats v, empty for zero which is unknown for the metadata fields
+func csvFloat(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func csvInt(v int64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(v, 10)
+}
@@ -0,0 +1,103 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteMetadataCSV(t *testing.T) {
+	wall := time.Date(2024, 5, 1, 10, 20, 30, 0, time.Local)
+	files := []RawFileInfo{
+		{Path: "a.nef", Format: "NEF", Metadata: Metadata{
+			Timestamp:     wall.Unix(),
+			TimeOffset:    "+02:00",
+			Camera:        Camera{Make: "Nikon", Model: "Z 6", Serial: "6001234"},
+			Lens:          Lens{Model: "NIKKOR Z 50mm f/1.8 S", FocalLength35mm: 50},
+			FocalLength:   50,
+			Aperture:      1.8,
+			Shutter:       0.004,
+			ISO:           100,
+			FlashRecorded: true,
+			Width:         6048,
+			Height:        4024,
+			Orientation:   1,
+			ShutterCount:  1234,
+			GPS:           &GPS{Latitude: 47.5, Longitude: 19.04, Altitude: 0},
+			DataSize:      2048,
+		}},
+		{Path: "b.cr3", Err: errors.New("broken, \"really\"")},
+	}
+	var buf bytes.Buffer
+	if err := WriteMetadataCSV(&buf, files); err != nil {
+		t.Fatalf("WriteMetadataCSV failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %v, want the header and one row per file", len(records))
+	}
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	for column, want := range map[string]string{
+		"path": "a.nef", "format": "NEF", "error": "", "time": "2024-05-01T10:20:30+02:00", "model": "Z 6",
+		"serial": "6001234", "focal_length": "50", "focal_length_35mm": "50", "aperture": "1.8", "shutter": "0.004",
+		"iso": "100", "exposure_compensation": "0", "flash": "false", "width": "6048", "orientation": "1",
+		"shutter_count": "1234", "latitude": "47.5", "altitude": "0", "data_size": "2048",
+	} {
+		if row[column] != want {
+			t.Errorf("%v = %q, want %q", column, row[column], want)
+		}
+	}
+
+	// unknown values of a failing file are empty, the error is quoted
+	for i, column := range records[0] {
+		value := records[2][i]
+		switch column {
+		case "path":
+			if value != "b.cr3" {
+				t.Errorf("path = %q, want b.cr3", value)
+			}
+		case "error":
+			if value != "broken, \"really\"" {
+				t.Errorf("error = %q, want the error text", value)
+			}
+		case "exposure_compensation":
+		default:
+			if value != "" {
+				t.Errorf("%v of a failing file = %q, want it empty", column, value)
+			}
+		}
+	}
+}
+
+func TestExtractMetadataAll(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "text.dng")
+	if err := os.WriteFile(text, []byte("not a RAW file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{filepath.Join(dir, "missing.dng"), text, filepath.Join(dir, "other.dng")}
+	for _, workers := range []int{0, 1, 8} {
+		files := ExtractMetadataAll(paths, workers)
+		if len(files) != len(paths) {
+			t.Fatalf("results = %v, want one per file", len(files))
+		}
+		for i, file := range files {
+			if file.Path != paths[i] || file.Err == nil {
+				t.Errorf("result [%v] = %v with error %v, want %v failing", i, file.Path, file.Err, paths[i])
+			}
+		}
+	}
+	if files := ExtractMetadataAll(nil, 4); len(files) != 0 {
+		t.Errorf("results without files = %v, want none", files)
+	}
+}
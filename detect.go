@@ -0,0 +1,145 @@
+package golibraw
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Number of leading bytes read to recognize a RAW container, TIFF based formats need the first IFD
+const sniffLen = 64 * 1024
+
+// Number of IFDs searched for the RAW image data, protecting against loops in malformed files
+const maxSniffIFDs = 16
+
+// TIFF based RAW formats recognized by the camera maker of the first IFD, once the file is known to hold sensor data
+var tiffMakers = []struct {
+	maker  string
+	format string
+}{
+	{"NIKON", "NEF"},
+	{"SONY", "ARW"},
+	{"PENTAX", "PEF"},
+	{"SAMSUNG", "SRW"},
+	{"HASSELBLAD", "3FR"},
+	{"EPSON", "ERF"},
+	{"KODAK", "DCR"},
+	{"MAMIYA", "MEF"},
+	{"CANON", "TIF"},
+}
+
+// Compressions used only for the sensor data of TIFF based RAW formats
+var rawCompressions = map[uint32]bool{
+	262:   true, // Kodak DCR
+	32767: true, // Sony ARW
+	32769: true, // Samsung SRW
+	32770: true, // Samsung SRW
+	32772: true, // Samsung SRW
+	34713: true, // Nikon NEF
+	65000: true, // Kodak DCR
+	65535: true, // Pentax PEF
+}
+
+// Reads the leading bytes of r and reports whether it is a RAW file, with its container format
+// (CR2, CR3, CRW, NEF, ARW, RAF, ORF, RW2, PEF, SRW, DNG, X3F, MRW, ...).
+// Detection is based on the file signature, the file extension is irrelevant.
+func IsRaw(r io.Reader) (bool, string, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, "", fmt.Errorf("failed to read input with [%w]", err)
+	}
+	format := DetectFormat(header[:n])
+	return format != "", format, nil
+}
+
+// Returns the container format of a RAW file recognized from its leading bytes, or empty string if it is not a RAW file.
+// TIFF based formats are recognized from the first IFD, so at least the first few kilobytes are needed.
+func DetectFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("II\x1a\x00\x00\x00HEAPCCDR")):
+		return "CRW"
+	case len(header) >= 12 && bytes.Equal(header[4:12], []byte("ftypcrx ")):
+		return "CR3"
+	case bytes.HasPrefix(header, []byte("FUJIFILMCCD-RAW")):
+		return "RAF"
+	case bytes.HasPrefix(header, []byte("IIRO")), bytes.HasPrefix(header, []byte("IIRS")), bytes.HasPrefix(header, []byte("MMOR")):
+		return "ORF"
+	case bytes.HasPrefix(header, []byte("IIU\x00")):
+		return "RW2"
+	case bytes.HasPrefix(header, []byte("FOVb")):
+		return "X3F"
+	case bytes.HasPrefix(header, []byte("\x00MRM")):
+		return "MRW"
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")):
+		return tiffFormat(header)
+	default:
+		return ""
+	}
+}
+
+// Recognizes TIFF based RAW formats by the CR2 marker, the DNGVersion tag or an IFD of sensor data, named after the
+// Make tag of the first IFD. Plain TIFF images, including the ones written by cameras and RAW converters, are reported
+// as empty string.
+func tiffFormat(header []byte) string {
+	if len(header) >= 11 && bytes.Equal(header[8:11], []byte("CR\x02")) {
+		return "CR2"
+	}
+
+	t, ok := newTiffReader(bytes.NewReader(header))
+	if !ok {
+		return ""
+	}
+
+	ifd0, next := t.ifd(t.first)
+	if _, ok := findEntry(ifd0, tagDNGVersion); ok {
+		return "DNG"
+	}
+	if !hasSensorData(t, ifd0, next) {
+		return ""
+	}
+
+	maker := ""
+	if e, ok := findEntry(ifd0, tagMake); ok {
+		maker = strings.ToUpper(t.ascii(e))
+	}
+	for _, m := range tiffMakers {
+		if strings.Contains(maker, m.maker) {
+			return m.format
+		}
+	}
+	return ""
+}
+
+// Reports whether the chain of IFDs starting with ifd0 (followed by the IFD at next) or their SubIFDs hold sensor data:
+// a color filter array or linear raw image, or a compression only used by RAW formats
+func hasSensorData(t *tiffReader, ifd0 []tiffEntry, next int64) bool {
+	ifds := [][]tiffEntry{ifd0}
+	for len(ifds) < maxSniffIFDs && next > 0 {
+		var ifd []tiffEntry
+		if ifd, next = t.ifd(next); ifd == nil {
+			break
+		}
+		ifds = append(ifds, ifd)
+	}
+	for i := 0; i < len(ifds) && i < maxSniffIFDs; i++ {
+		ifd := ifds[i]
+		if e, ok := findEntry(ifd, tagPhotometric); ok {
+			if photometric := t.uint(e, 0); photometric == photometricCFA || photometric == photometricLinearRaw {
+				return true
+			}
+		}
+		if e, ok := findEntry(ifd, tagCompression); ok && rawCompressions[t.uint(e, 0)] {
+			return true
+		}
+		if e, ok := findEntry(ifd, tagSubIFDs); ok {
+			for j := 0; j < min(e.count, maxSniffIFDs); j++ {
+				if sub, _ := t.ifd(int64(t.uint(e, j))); sub != nil {
+					ifds = append(ifds, sub)
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+type testEntry struct {
+	tag  uint16
+	typ  uint16
+	data []byte
+}
+
+// Returns a little endian TIFF file with a single IFD of entries, sorted by the caller
+func testTIFF(entries ...testEntry) []byte {
+	return appendIFD([]byte("II*\x00\x08\x00\x00\x00"), entries...)
+}
+
+// Appends an IFD of entries to a little endian TIFF file, followed by the values not fitting in the entries
+func appendIFD(out []byte, entries ...testEntry) []byte {
+	valuesOffset := len(out) + 2 + 12*len(entries) + 4
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(entries)))
+	var values []byte
+	for _, e := range entries {
+		out = binary.LittleEndian.AppendUint16(out, e.tag)
+		out = binary.LittleEndian.AppendUint16(out, e.typ)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(e.data)/typeSizes[e.typ]))
+		if len(e.data) <= 4 {
+			out = append(out, append(e.data, make([]byte, 4-len(e.data))...)...)
+			continue
+		}
+		out = binary.LittleEndian.AppendUint32(out, uint32(valuesOffset+len(values)))
+		values = append(values, e.data...)
+	}
+	out = binary.LittleEndian.AppendUint32(out, 0)
+	return append(out, values...)
+}
+
+func shortEntry(tag uint16, value uint16) testEntry {
+	return testEntry{tag, typeShort, binary.LittleEndian.AppendUint16(nil, value)}
+}
+
+func TestDetectFormat(t *testing.T) {
+	nikon := testEntry{tagMake, typeASCII, []byte("NIKON CORPORATION\x00")}
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"CRW", []byte("II\x1a\x00\x00\x00HEAPCCDRAW"), "CRW"},
+		{"CR3", []byte("\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01"), "CR3"},
+		{"CR2", []byte("II*\x00\x10\x00\x00\x00CR\x02\x00"), "CR2"},
+		{"RAF", []byte("FUJIFILMCCD-RAW 0201"), "RAF"},
+		{"ORF", []byte("IIRO\x08\x00\x00\x00"), "ORF"},
+		{"RW2", []byte("IIU\x00\x18\x00\x00\x00"), "RW2"},
+		{"X3F", []byte("FOVb\x00\x00\x04\x00"), "X3F"},
+		{"MRW", []byte("\x00MRM\x00\x00\x00\x00"), "MRW"},
+		{"DNG", testTIFF(testEntry{tagDNGVersion, typeByte, []byte{1, 4, 0, 0}}), "DNG"},
+		{"NEF", testTIFF(shortEntry(tagCompression, 34713), nikon), "NEF"},
+		{"NEF CFA", testTIFF(shortEntry(tagPhotometric, photometricCFA), nikon), "NEF"},
+		{"TIFF of a camera", testTIFF(shortEntry(tagCompression, 1), nikon), ""},
+		{"sensor data of an unknown maker", testTIFF(shortEntry(tagCompression, 34713)), ""},
+		{"JPEG", []byte{0xff, 0xd8, 0xff, 0xe0}, ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.header); got != tt.want {
+			t.Errorf("DetectFormat(%v) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsRaw(t *testing.T) {
+	ok, format, err := IsRaw(bytes.NewReader(testTIFF(testEntry{tagDNGVersion, typeByte, []byte{1, 4, 0, 0}})))
+	if err != nil || !ok || format != "DNG" {
+		t.Errorf("IsRaw of a DNG = %v, %q, %v, want true, DNG", ok, format, err)
+	}
+	ok, format, err = IsRaw(bytes.NewReader([]byte("plain text")))
+	if err != nil || ok || format != "" {
+		t.Errorf("IsRaw of text = %v, %q, %v, want false", ok, format, err)
+	}
+}
@@ -0,0 +1,372 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+)
+
+// DNG and TIFF/EP tags written by ExportDNG
+const (
+	tagImageWidth             = 0x0100
+	tagImageLength            = 0x0101
+	tagBitsPerSample          = 0x0102
+	tagModel                  = 0x0110
+	tagStripOffsets           = 0x0111
+	tagOrientation            = 0x0112
+	tagSamplesPerPixel        = 0x0115
+	tagRowsPerStrip           = 0x0116
+	tagStripByteCounts        = 0x0117
+	tagPlanarConfiguration    = 0x011c
+	tagSoftware               = 0x0131
+	tagDateTime               = 0x0132
+	tagArtist                 = 0x013b
+	tagCFARepeatPatternDim    = 0x828d
+	tagCFAPattern             = 0x828e
+	tagExposureTime           = 0x829a
+	tagFNumber                = 0x829d
+	tagISOSpeedRatings        = 0x8827
+	tagFocalLength            = 0x920a
+	tagDNGBackwardVersion     = 0xc613
+	tagUniqueCameraModel      = 0xc614
+	tagBlackLevelRepeatDim    = 0xc619
+	tagBlackLevel             = 0xc61a
+	tagWhiteLevel             = 0xc61d
+	tagColorMatrix1           = 0xc621
+	tagAsShotNeutral          = 0xc628
+	tagCalibrationIlluminant1 = 0xc65a
+)
+
+// Raw image data of a DNG file, either a CFA mosaic (Samples = 1) or linear RGB (Samples = 3)
+type dngImage struct {
+	Width   int
+	Height  int
+	Samples int
+	// 16-bit samples, interleaved for linear RGB
+	Pix []uint16
+	CFA CFA
+	// black levels in a BlackWidth x BlackHeight pattern (row-major), a single 0 if empty
+	Black       []uint
+	BlackWidth  int
+	BlackHeight int
+	White       uint
+}
+
+// Reads a RAW image file from file system and exports it to DNG format. Bayer and X-Trans sensor data is kept as
+// an unprocessed mosaic, other sensors (Foveon, sRAW, ...) are demosaiced by libraw to linear RGB in the camera color space.
+// Color matrix, white balance and basic capture metadata are carried over. Only the options taking effect when
+// the file is opened (WithShot, WithMemoryLimit, ...) and WithOutputOptions apply.
+func ExportDNG(inputPath string, exportPath string, opts ...Option) error {
+	o := newOptions(opts)
+	return encodeFile(exportPath, o.output, func(w io.Writer) error {
+		processor, err := NewProcessor()
+		if err != nil {
+			return err
+		}
+		defer processor.Close()
+		processor.configure(o)
+
+		if err = processor.Open(inputPath); err != nil {
+			return err
+		}
+		metadata, err := processor.Metadata()
+		if err != nil {
+			return err
+		}
+		if err = processor.Unpack(); err != nil {
+			return err
+		}
+
+		if bayer, err := processor.Bayer(); err == nil && dngCFAPattern(bayer.CFA) != nil {
+			return writeDNG(w, mosaicOf(bayer), metadata)
+		}
+		processor.log(slog.LevelInfo, "no Bayer mosaic, writing linear DNG", "path", inputPath)
+
+		if err = processor.Process(WithLinearOutput(), WithColorSpace(ColorSpaceRaw), WithWhiteBalanceMultipliers(1, 1, 1, 1)); err != nil {
+			return err
+		}
+		img, err := processor.Image()
+		if err != nil {
+			return err
+		}
+		linear, ok := img.(*image.NRGBA64)
+		if !ok {
+			return fmt.Errorf("unexpected linear image type [%T]", img)
+		}
+		return writeDNG(w, linearOf(linear), metadata)
+	})
+}
+
+func mosaicOf(bayer *BayerImage) dngImage {
+	d := dngImage{
+		Width:       bayer.VisibleWidth,
+		Height:      bayer.VisibleHeight,
+		Samples:     1,
+		Pix:         make([]uint16, 0, bayer.VisibleWidth*bayer.VisibleHeight),
+		CFA:         bayer.CFA,
+		Black:       bayer.BlackLevel,
+		BlackWidth:  bayer.BlackLevelWidth,
+		BlackHeight: bayer.BlackLevelHeight,
+		White:       bayer.White,
+	}
+	for row := 0; row < bayer.VisibleHeight; row++ {
+		for col := 0; col < bayer.VisibleWidth; col++ {
+			d.Pix = append(d.Pix, bayer.At(row, col))
+		}
+	}
+	return d
+}
+
+func linearOf(img *image.NRGBA64) dngImage {
+	b := img.Bounds()
+	d := dngImage{
+		Width:   b.Dx(),
+		Height:  b.Dy(),
+		Samples: 3,
+		Pix:     make([]uint16, 0, 3*b.Dx()*b.Dy()),
+		White:   0xffff,
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				d.Pix = append(d.Pix, binary.BigEndian.Uint16(img.Pix[i+2*c:]))
+			}
+		}
+	}
+	return d
+}
+
+// Returns the DNG CFAPattern values (0 red, 1 green, 2 blue) of the CFA, nil if it is not an RGB mosaic
+func dngCFAPattern(cfa CFA) []byte {
+	if cfa.Pattern == "" || len(cfa.Pattern) != cfa.Width*cfa.Height {
+		return nil
+	}
+	pattern := make([]byte, len(cfa.Pattern))
+	for i := range cfa.Pattern {
+		switch cfa.Pattern[i] {
+		case 'R':
+			pattern[i] = 0
+		case 'G':
+			pattern[i] = 1
+		case 'B':
+			pattern[i] = 2
+		default:
+			return nil
+		}
+	}
+	return pattern
+}
+
+func writeDNG(w io.Writer, img dngImage, metadata Metadata) error {
+	t, data := dngFields(img, metadata)
+	return t.write(w, tagStripOffsets, data)
+}
+
+// Returns the IFD entries and the strip of a DNG file of img
+func dngFields(img dngImage, metadata Metadata) (*tiffWriter, []byte) {
+	t := &tiffWriter{}
+	t.long(tagNewSubFileType, 0)
+	t.long(tagImageWidth, uint32(img.Width))
+	t.long(tagImageLength, uint32(img.Height))
+	bits := make([]uint16, img.Samples)
+	for i := range bits {
+		bits[i] = 16
+	}
+	t.short(tagBitsPerSample, bits...)
+	t.short(tagCompression, 1)
+	t.short(tagSamplesPerPixel, uint16(img.Samples))
+	t.long(tagRowsPerStrip, uint32(img.Height))
+	t.long(tagStripByteCounts, uint32(2*len(img.Pix)))
+	t.short(tagPlanarConfiguration, 1)
+	if img.Samples == 1 {
+		t.short(tagPhotometric, photometricCFA)
+		t.short(tagCFARepeatPatternDim, uint16(img.CFA.Height), uint16(img.CFA.Width))
+		t.add(tagCFAPattern, typeByte, len(img.CFA.Pattern), dngCFAPattern(img.CFA))
+	} else {
+		t.short(tagPhotometric, photometricLinearRaw)
+	}
+	if len(img.Black) > 0 {
+		black := make([]uint32, len(img.Black))
+		for i, level := range img.Black {
+			black[i] = uint32(level)
+		}
+		t.short(tagBlackLevelRepeatDim, uint16(img.BlackHeight), uint16(img.BlackWidth))
+		t.long(tagBlackLevel, black...)
+	} else {
+		t.long(tagBlackLevel, 0)
+	}
+	t.long(tagWhiteLevel, uint32(img.White))
+
+	t.add(tagDNGVersion, typeByte, 4, []byte{1, 4, 0, 0})
+	t.add(tagDNGBackwardVersion, typeByte, 4, []byte{1, 1, 0, 0})
+	t.ascii(tagMake, metadata.Camera.Make)
+	t.ascii(tagModel, metadata.Camera.Model)
+	t.ascii(tagUniqueCameraModel, metadata.Camera.Make+" "+metadata.Camera.Model)
+	t.ascii(tagSoftware, "golibraw")
+	t.ascii(tagArtist, metadata.Artist)
+	t.ascii(tagCopyright, metadata.Copyright)
+	if captured := metadata.Time(); !captured.IsZero() {
+		t.ascii(tagDateTime, captured.Format("2006:01:02 15:04:05"))
+	}
+	if metadata.Orientation > 0 {
+		t.short(tagOrientation, uint16(metadata.Orientation))
+	}
+	if metadata.Shutter > 0 {
+		t.rational(tagExposureTime, metadata.Shutter)
+	}
+	if metadata.Aperture > 0 {
+		t.rational(tagFNumber, metadata.Aperture)
+	}
+	if metadata.FocalLength > 0 {
+		t.rational(tagFocalLength, metadata.FocalLength)
+	}
+	if metadata.ISO > 0 {
+		t.short(tagISOSpeedRatings, uint16(min(metadata.ISO, math.MaxUint16)))
+	}
+
+	calibration := metadata.Calibration
+	if len(calibration.CamXYZ) >= 3 && calibration.CamXYZ[0][0] != 0 {
+		matrix := make([]float64, 0, 9)
+		for _, row := range calibration.CamXYZ[:3] {
+			matrix = append(matrix, row[:3]...)
+		}
+		t.srational(tagColorMatrix1, matrix...)
+		// libraw's camera matrices are the D65 ones of the DNG specification
+		t.short(tagCalibrationIlluminant1, 21)
+	}
+	mul := calibration.CamMul
+	if mul[0] <= 0 || mul[1] <= 0 || mul[2] <= 0 {
+		mul = calibration.PreMul
+	}
+	if mul[0] > 0 && mul[1] > 0 && mul[2] > 0 {
+		t.rational(tagAsShotNeutral, mul[1]/mul[0], 1, mul[1]/mul[2])
+	}
+
+	data := make([]byte, 2*len(img.Pix))
+	for i, v := range img.Pix {
+		binary.LittleEndian.PutUint16(data[2*i:], v)
+	}
+	return t, data
+}
+
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count int
+	data  []byte
+}
+
+// Minimal little endian TIFF writer producing a single IFD with a single strip
+type tiffWriter struct {
+	fields []tiffField
+}
+
+func (t *tiffWriter) add(tag uint16, typ uint16, count int, data []byte) {
+	t.fields = append(t.fields, tiffField{tag: tag, typ: typ, count: count, data: data})
+}
+
+func (t *tiffWriter) short(tag uint16, values ...uint16) {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(data[2*i:], v)
+	}
+	t.add(tag, typeShort, len(values), data)
+}
+
+func (t *tiffWriter) long(tag uint16, values ...uint32) {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], v)
+	}
+	t.add(tag, typeLong, len(values), data)
+}
+
+func (t *tiffWriter) ascii(tag uint16, value string) {
+	if value == "" {
+		return
+	}
+	data := append([]byte(value), 0)
+	t.add(tag, typeASCII, len(data), data)
+}
+
+func (t *tiffWriter) rational(tag uint16, values ...float64) {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		den := uint32(1000000)
+		if v >= 1000 {
+			den = 1000
+		}
+		binary.LittleEndian.PutUint32(data[8*i:], uint32(math.Round(math.Max(v, 0)*float64(den))))
+		binary.LittleEndian.PutUint32(data[8*i+4:], den)
+	}
+	t.add(tag, typeRational, len(values), data)
+}
+
+func (t *tiffWriter) srational(tag uint16, values ...float64) {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[8*i:], uint32(int32(math.Round(v*10000))))
+		binary.LittleEndian.PutUint32(data[8*i+4:], 10000)
+	}
+	t.add(tag, typeSRational, len(values), data)
+}
+
+// Writes the header, the IFD, the values not fitting in the entries and finally the strip, whose offset is
+// stored in the stripTag entry.
+func (t *tiffWriter) write(w io.Writer, stripTag uint16, strip []byte) error {
+	t.long(stripTag, 0)
+	sort.SliceStable(t.fields, func(i, j int) bool { return t.fields[i].tag < t.fields[j].tag })
+
+	ifdSize := 2 + 12*len(t.fields) + 4
+	extra := 0
+	for _, f := range t.fields {
+		if len(f.data) > 4 {
+			extra += len(f.data) + len(f.data)%2
+		}
+	}
+	stripOffset := 8 + ifdSize + extra
+	for i := range t.fields {
+		if t.fields[i].tag == stripTag {
+			binary.LittleEndian.PutUint32(t.fields[i].data, uint32(stripOffset))
+		}
+	}
+
+	out := make([]byte, 8, stripOffset)
+	copy(out, "II*\x00")
+	binary.LittleEndian.PutUint32(out[4:], 8)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(t.fields)))
+
+	values := make([]byte, 0, extra)
+	valuesOffset := 8 + ifdSize
+	for _, f := range t.fields {
+		out = binary.LittleEndian.AppendUint16(out, f.tag)
+		out = binary.LittleEndian.AppendUint16(out, f.typ)
+		out = binary.LittleEndian.AppendUint32(out, uint32(f.count))
+		if len(f.data) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, f.data)
+			out = append(out, inline...)
+			continue
+		}
+		out = binary.LittleEndian.AppendUint32(out, uint32(valuesOffset+len(values)))
+		values = append(values, f.data...)
+		if len(f.data)%2 == 1 {
+			values = append(values, 0)
+		}
+	}
+	out = binary.LittleEndian.AppendUint32(out, 0)
+	out = append(out, values...)
+
+	if _, err := w.Write(out); err != nil {
+		return err
+	}
+	_, err := w.Write(strip)
+	return err
+}
@@ -0,0 +1,124 @@
+package golibraw
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DNGInfo describes the processing data of a DNG file which libraw does not apply, notably for the DNGs of smartphones
+// (Apple ProRAW, Google Pixel) relying on it more than the DNGs of cameras. Renders of such files may look darker or
+// show vignetting compared to the camera rendering, see WithBaselineExposure and WithDNGDefaultCrop.
+type DNGInfo struct {
+	// DNG specification version of the file, e.g. "1.6.0.0"
+	Version string `json:"version"`
+	// Exposure in EV the image is meant to be brightened by, typically 1-2 EV for smartphone DNGs
+	BaselineExposure float64 `json:"baseline_exposure"`
+	// Processing steps stored in the opcode lists of the raw image, in the order of application
+	Opcodes []DNGOpcode `json:"opcodes,omitempty"`
+	// Number of GainMap opcodes, the lens shading correction of smartphone DNGs
+	GainMaps int `json:"gain_maps"`
+	// Set for the local tone mapping of ProRAW files (DNG 1.6 ProfileGainTableMap)
+	ProfileGainTableMap bool `json:"profile_gain_table_map"`
+}
+
+// DNGOpcode is a processing step stored in a DNG file.
+type DNGOpcode struct {
+	// Opcode list of the step: 1 applies to the raw data as stored, 2 after linearization, 3 after demosaic
+	List int    `json:"list"`
+	Name string `json:"name"`
+	// Optional steps may be skipped, e.g. for previews
+	Optional bool `json:"optional"`
+}
+
+// Names of the opcodes of the DNG specification by their ID
+var dngOpcodeNames = map[uint32]string{
+	1:  "WarpRectilinear",
+	2:  "WarpFisheye",
+	3:  "FixVignetteRadial",
+	4:  "FixBadPixelsConstant",
+	5:  "FixBadPixelsList",
+	6:  "TrimBounds",
+	7:  "MapTable",
+	8:  "MapPolynomial",
+	9:  "GainMap",
+	10: "DeltaPerRow",
+	11: "DeltaPerColumn",
+	12: "ScalePerRow",
+	13: "ScalePerColumn",
+	14: "WarpRectilinear2",
+}
+
+// Reads the DNG processing data from IFD0 and the main raw image, which is either IFD0 or one of its SubIFDs.
+// Returns nil for other files.
+func readDNGInfo(t *tiffReader, ifd0 []tiffEntry) *DNGInfo {
+	e, ok := findEntry(ifd0, tagDNGVersion)
+	if !ok {
+		return nil
+	}
+	version := t.bytes(e)
+	if len(version) < 4 {
+		return nil
+	}
+	info := &DNGInfo{Version: fmt.Sprintf("%d.%d.%d.%d", version[0], version[1], version[2], version[3])}
+	if e, ok := findEntry(ifd0, tagBaselineExposure); ok {
+		info.BaselineExposure = t.rational(e, 0)
+	}
+
+	ifds := [][]tiffEntry{ifd0}
+	if e, ok := findEntry(ifd0, tagSubIFDs); ok {
+		for i := 0; i < min(e.count, 16); i++ {
+			if ifd, _ := t.ifd(int64(t.uint(e, i))); ifd != nil {
+				ifds = append(ifds, ifd)
+			}
+		}
+	}
+	for _, ifd := range ifds {
+		// the main image has NewSubFileType 0, previews and masks have other types
+		if e, ok := findEntry(ifd, tagNewSubFileType); ok && t.uint(e, 0) != 0 {
+			continue
+		}
+		if _, ok := findEntry(ifd, tagProfileGainTableMap); ok {
+			info.ProfileGainTableMap = true
+		}
+		for list, tag := range []uint16{tagOpcodeList1, tagOpcodeList2, tagOpcodeList3} {
+			if e, ok := findEntry(ifd, tag); ok {
+				info.Opcodes = append(info.Opcodes, parseOpcodeList(t.bytes(e), list+1)...)
+			}
+		}
+	}
+	if _, ok := findEntry(ifd0, tagProfileGainTableMap); ok {
+		info.ProfileGainTableMap = true
+	}
+	for _, opcode := range info.Opcodes {
+		if opcode.Name == "GainMap" {
+			info.GainMaps++
+		}
+	}
+	return info
+}
+
+// Parses an opcode list, stored in big endian byte order regardless of the byte order of the file: the number of
+// opcodes, then the ID, the DNG version, the flags, the parameter size and the parameters of every opcode
+func parseOpcodeList(data []byte, list int) []DNGOpcode {
+	if len(data) < 4 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(data))
+	data = data[4:]
+	var opcodes []DNGOpcode
+	for i := 0; i < count && len(data) >= 16; i++ {
+		id := binary.BigEndian.Uint32(data)
+		flags := binary.BigEndian.Uint32(data[8:])
+		size := binary.BigEndian.Uint32(data[12:])
+		name, ok := dngOpcodeNames[id]
+		if !ok {
+			name = fmt.Sprintf("Opcode%d", id)
+		}
+		opcodes = append(opcodes, DNGOpcode{List: list, Name: name, Optional: flags&1 != 0})
+		if uint64(size) > uint64(len(data)-16) {
+			break
+		}
+		data = data[16+size:]
+	}
+	return opcodes
+}
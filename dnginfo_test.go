@@ -0,0 +1,84 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// Builds a little endian DNG structure like the ones of smartphones: IFD0 with the DNG version, a BaselineExposure
+// and a ProfileGainTableMap, the raw image in a SubIFD with a GainMap and a WarpRectilinear opcode, and a preview
+// SubIFD whose opcodes are ignored
+func testDNGInfoFile() []byte {
+	opcodes := func(ids ...uint32) []byte {
+		var b bytes.Buffer
+		binary.Write(&b, binary.BigEndian, uint32(len(ids)))
+		for _, id := range ids {
+			// ID, DNG version, flags (optional), parameter size and 4 bytes of parameters
+			binary.Write(&b, binary.BigEndian, []uint32{id, 0x01030000, 1, 4, 0})
+		}
+		return b.Bytes()
+	}
+	raw, preview := opcodes(9, 1), opcodes(9)
+
+	// header, IFD0 of 4 entries at 8, 2 SubIFDs of 2 entries, then the values
+	const ifd0, rawIFD, previewIFD = 8, 8 + 2 + 4*12 + 4, 8 + 2 + 4*12 + 4 + 2 + 2*12 + 4
+	const subIFDs = previewIFD + 2 + 2*12 + 4
+	const baseline, rawOpcodes = subIFDs + 8, subIFDs + 16
+	previewOpcodes := rawOpcodes + len(raw)
+
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	entry := func(tag, typ uint16, count, value uint32) {
+		binary.Write(&b, le, tag)
+		binary.Write(&b, le, typ)
+		binary.Write(&b, le, count)
+		binary.Write(&b, le, value)
+	}
+	b.WriteString("II*\x00")
+	binary.Write(&b, le, uint32(ifd0))
+	binary.Write(&b, le, uint16(4))
+	entry(tagSubIFDs, typeLong, 2, subIFDs)
+	entry(tagDNGVersion, typeByte, 4, 0x00000601)
+	entry(tagBaselineExposure, typeSRational, 1, baseline)
+	entry(tagProfileGainTableMap, typeUndefined, 4, 0)
+	binary.Write(&b, le, uint32(0))
+	binary.Write(&b, le, uint16(2))
+	entry(tagNewSubFileType, typeLong, 1, 0)
+	entry(tagOpcodeList2, typeUndefined, uint32(len(raw)), rawOpcodes)
+	binary.Write(&b, le, uint32(0))
+	binary.Write(&b, le, uint16(2))
+	entry(tagNewSubFileType, typeLong, 1, 1)
+	entry(tagOpcodeList3, typeUndefined, uint32(len(preview)), uint32(previewOpcodes))
+	binary.Write(&b, le, uint32(0))
+	binary.Write(&b, le, []uint32{rawIFD, previewIFD})
+	binary.Write(&b, le, []int32{3, 2})
+	b.Write(raw)
+	b.Write(preview)
+	return b.Bytes()
+}
+
+func TestReadDNGInfo(t *testing.T) {
+	info := readExif(bytes.NewReader(testDNGInfoFile())).dng
+	want := &DNGInfo{
+		Version:          "1.6.0.0",
+		BaselineExposure: 1.5,
+		Opcodes: []DNGOpcode{
+			{List: 2, Name: "GainMap", Optional: true},
+			{List: 2, Name: "WarpRectilinear", Optional: true},
+		},
+		GainMaps:            1,
+		ProfileGainTableMap: true,
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("readDNGInfo = %+v, want %+v", info, want)
+	}
+}
+
+func TestReadDNGInfoTruncated(t *testing.T) {
+	data := testDNGInfoFile()
+	for n := 0; n < len(data); n++ {
+		readExif(bytes.NewReader(data[:n]))
+	}
+}
@@ -0,0 +1,28 @@
+// Package golibraw is a Go wrapper for libraw, reading metadata, embedded thumbnails and sensor data of RAW image
+// files and converting them to standard Go images.
+//
+// # Concurrency
+//
+// The package level functions (ImportRaw, ExtractMetadata, ExportDNG, ...) create their own libraw handle for every
+// call, so they are safe to call from multiple goroutines concurrently. The package links the reentrant build of
+// libraw (libraw_r), which keeps no global state between handles.
+//
+// A Processor wraps a single libraw handle and is not safe for concurrent use: its calls depend on each other
+// (Open, Unpack, Process, ...), so a Processor has to be used by one goroutine at a time. Use one Processor per
+// goroutine, or a pool of them, for processing images in parallel. Images returned by a Processor are copies, except
+// MemImage, and stay valid after the Processor is recycled or closed.
+//
+// # Corrupt and untrusted files
+//
+// libraw reports the problems of corrupt files as errors: unreadable image data as DataError, failures as Error.
+// Panics of the callbacks are recovered and returned as errors of the interrupted call. Memory corruption inside
+// libraw cannot be recovered in process though, decode untrusted files with Sandbox and SetDefaultMemoryLimit.
+//
+// # Builds without cgo
+//
+// When cgo is disabled, libraw is not linked and the package falls back to reading the RAW containers in Go:
+// ExtractMetadata, ExtractMetadataBytes and DecodeMetadata read the basic EXIF metadata, ExtractThumbnailBytes,
+// ExtractThumbnailTo and ImportThumbnail the largest embedded JPEG preview of TIFF based formats and RAF files.
+// The functions working on embedded previews and decoded images (PerceptualHash, ImageBlurHash, ScanDir, ...) are
+// available too, decoding the sensor data is not.
+package golibraw
@@ -0,0 +1,162 @@
+package golibraw
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Largest perceptual hash distance of near-duplicate images
+const nearDuplicateDistance = 10
+
+// DuplicateGroup is a set of RAW files found to be duplicates of each other by FindDuplicates.
+type DuplicateGroup struct {
+	// Exact is set for byte-identical files, otherwise the files are near-duplicates: images of the same camera captured
+	// at the same second with similar previews, e.g. a re-encoded copy, a DNG conversion or a burst frame
+	Exact bool
+	Paths []string
+	// Largest perceptual hash distance between the files of a near-duplicate group
+	Distance int
+}
+
+// Walks the directory tree at root and reports the groups of exact and near-duplicate RAW files, e.g. for cleaning up
+// an archive. Exact duplicates are found by file size and content hash. Near-duplicates are only searched among files
+// of the same camera with the same capture time, compared by the perceptual hash of their previews, which keeps the
+// search fast for large archives. Of every exact group, only the first file takes part in the near-duplicate search.
+func FindDuplicates(root string) ([]DuplicateGroup, error) {
+	files, err := ScanDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// exact duplicates: same size first, then same content
+	bySize := map[int64][]int{}
+	for i, file := range files {
+		if info, err := os.Stat(file.Path); err == nil {
+			bySize[info.Size()] = append(bySize[info.Size()], i)
+		}
+	}
+	var groups []DuplicateGroup
+	duplicate := make([]bool, len(files))
+	for _, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
+		}
+		byHash := map[[sha256.Size]byte][]string{}
+		var order [][sha256.Size]byte
+		for _, i := range candidates {
+			sum, err := fileSHA256(files[i].Path)
+			if err != nil {
+				continue
+			}
+			if len(byHash[sum]) == 0 {
+				order = append(order, sum)
+			} else {
+				duplicate[i] = true
+			}
+			byHash[sum] = append(byHash[sum], files[i].Path)
+		}
+		for _, sum := range order {
+			if len(byHash[sum]) > 1 {
+				groups = append(groups, DuplicateGroup{Exact: true, Paths: byHash[sum]})
+			}
+		}
+	}
+
+	// near duplicates: same camera and capture time, similar previews
+	type moment struct {
+		camera    string
+		timestamp int64
+	}
+	byMoment := map[moment][]int{}
+	for i, file := range files {
+		if duplicate[i] || file.Err != nil || file.Metadata.Timestamp == 0 {
+			continue
+		}
+		key := moment{camera: CameraKey(file.Metadata), timestamp: file.Metadata.Timestamp}
+		byMoment[key] = append(byMoment[key], i)
+	}
+	for _, candidates := range byMoment {
+		if len(candidates) < 2 {
+			continue
+		}
+		groups = append(groups, nearDuplicates(files, candidates)...)
+	}
+
+	for i := range groups {
+		sort.Strings(groups[i].Paths)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Paths[0] < groups[j].Paths[0] })
+	return groups, nil
+}
+
+// Groups the candidates with similar perceptual hashes, files similar to any file of a group join the group
+func nearDuplicates(files []RawFileInfo, candidates []int) []DuplicateGroup {
+	hashes := map[int]uint64{}
+	for _, i := range candidates {
+		if hash, err := PerceptualHash(files[i].Path); err == nil {
+			hashes[i] = hash
+		}
+	}
+
+	parent := map[int]int{}
+	var find func(i int) int
+	find = func(i int) int {
+		if p, ok := parent[i]; ok && p != i {
+			parent[i] = find(p)
+			return parent[i]
+		}
+		return i
+	}
+	for a, i := range candidates {
+		for _, j := range candidates[a+1:] {
+			hi, okI := hashes[i]
+			hj, okJ := hashes[j]
+			if okI && okJ && HashDistance(hi, hj) <= nearDuplicateDistance {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	var roots []int
+	for _, i := range candidates {
+		root := find(i)
+		if len(members[root]) == 0 {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+	var groups []DuplicateGroup
+	for _, root := range roots {
+		if len(members[root]) < 2 {
+			continue
+		}
+		group := DuplicateGroup{}
+		for a, i := range members[root] {
+			group.Paths = append(group.Paths, files[i].Path)
+			for _, j := range members[root][a+1:] {
+				group.Distance = max(group.Distance, HashDistance(hashes[i], hashes[j]))
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func fileSHA256(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, fmt.Errorf("input file [%v] does not exist", path)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return sum, fmt.Errorf("failed to read file [%v] with [%w]", path, err)
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
@@ -0,0 +1,72 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	captured := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	metadata := testMetadata()
+	metadata.Timestamp = captured.Unix()
+	original := writeTestDNG(t, testImage("RGGB"), metadata)
+	// the same image and capture time with different file content, e.g. a re-encoded copy
+	metadata.Artist = "Golibraw"
+	copied := writeTestDNG(t, testImage("RGGB"), metadata)
+	// a different capture time is never a near-duplicate
+	metadata.Timestamp = captured.Add(time.Second).Unix()
+	later := writeTestDNG(t, testImage("RGGB"), metadata)
+
+	root := t.TempDir()
+	files := map[string]string{
+		"a.dng":                          original,
+		filepath.Join("backup", "b.dng"): original,
+		"c.dng":                          copied,
+		"d.dng":                          later,
+	}
+	for name, source := range files {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups, err := FindDuplicates(root)
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v, want an exact and a near-duplicate group", groups)
+	}
+	for _, group := range groups {
+		var want []string
+		if group.Exact {
+			want = []string{filepath.Join(root, "a.dng"), filepath.Join(root, "backup", "b.dng")}
+		} else {
+			// only the first file of the exact group is compared
+			want = []string{filepath.Join(root, "a.dng"), filepath.Join(root, "c.dng")}
+		}
+		if !slices.Equal(group.Paths, want) {
+			t.Errorf("group (exact %v) = %v, want %v", group.Exact, group.Paths, want)
+		}
+		if group.Distance > nearDuplicateDistance {
+			t.Errorf("group distance = %v, want at most %v", group.Distance, nearDuplicateDistance)
+		}
+	}
+
+	if _, err := FindDuplicates(filepath.Join(root, "missing")); err == nil {
+		t.Errorf("FindDuplicates of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,73 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is an error reported by libraw, carrying the libraw error code.
+// Use errors.Is with the Err* values to check for a specific kind of failure.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("libraw error: %v", e.Message)
+}
+
+// Reports whether target is a libraw error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// DataError is reported when the image data of a file cannot be read, e.g. the file is truncated or corrupt.
+type DataError struct {
+	File   string
+	Offset int64
+}
+
+func (e *DataError) Error() string {
+	return fmt.Sprintf("corrupt or truncated data in [%v] at offset [%v]", e.File, e.Offset)
+}
+
+var (
+	ErrUnspecified          = newError(C.LIBRAW_UNSPECIFIED_ERROR)
+	ErrUnsupportedFile      = newError(C.LIBRAW_FILE_UNSUPPORTED)
+	ErrNonexistentImage     = newError(C.LIBRAW_REQUEST_FOR_NONEXISTENT_IMAGE)
+	ErrOutOfOrderCall       = newError(C.LIBRAW_OUT_OF_ORDER_CALL)
+	ErrNoThumbnail          = newError(C.LIBRAW_NO_THUMBNAIL)
+	ErrUnsupportedThumbnail = newError(C.LIBRAW_UNSUPPORTED_THUMBNAIL)
+	ErrInputClosed          = newError(C.LIBRAW_INPUT_CLOSED)
+	ErrNotImplemented       = newError(C.LIBRAW_NOT_IMPLEMENTED)
+	ErrNonexistentThumbnail = newError(C.LIBRAW_REQUEST_FOR_NONEXISTENT_THUMBNAIL)
+	ErrOutOfMemory          = newError(C.LIBRAW_UNSUFFICIENT_MEMORY)
+	ErrCorruptData          = newError(C.LIBRAW_DATA_ERROR)
+	ErrIO                   = newError(C.LIBRAW_IO_ERROR)
+	ErrCancelled            = newError(C.LIBRAW_CANCELLED_BY_CALLBACK)
+	ErrBadCrop              = newError(C.LIBRAW_BAD_CROP)
+	ErrTooBig               = newError(C.LIBRAW_TOO_BIG)
+	ErrMemoryPoolOverflow   = newError(C.LIBRAW_MEMPOOL_OVERFLOW)
+
+	// The image needs more memory than allowed by SetMemoryLimit, libraw reports it as too big
+	ErrMemoryLimit = ErrTooBig
+
+	// An option is out of its supported range, see WithExposure
+	ErrInvalidOption = errors.New("invalid option")
+
+	// The feature or file format needs a newer libraw than the one the package is built with or linked to
+	ErrUnsupportedByLibraw = errors.New("not supported by the libraw version")
+)
+
+func newError(code C.int) *Error {
+	return &Error{
+		Code:    int(code),
+		Message: C.GoString(C.libraw_strerror(code)),
+	}
+}
@@ -0,0 +1,36 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("failed with [%w]", &Error{Code: ErrTooBig.Code})
+	if !errors.Is(err, ErrTooBig) {
+		t.Errorf("errors.Is(%v, ErrTooBig) = false, want true", err)
+	}
+	if errors.Is(err, ErrCorruptData) {
+		t.Errorf("errors.Is(%v, ErrCorruptData) = true, want false", err)
+	}
+	var librawErr *Error
+	if !errors.As(err, &librawErr) || librawErr.Code != ErrTooBig.Code {
+		t.Errorf("errors.As(%v) = %v, want the libraw error", err, librawErr)
+	}
+	if ErrTooBig.Message == "" {
+		t.Errorf("libraw error %v has no message", ErrTooBig.Code)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	if _, err := ImportRaw(filepath.Join("testdata", "corrupt", "garbage.raw")); !errors.Is(err, ErrUnsupportedFile) {
+		t.Errorf("ImportRaw of random bytes: %v, want %v", err, ErrUnsupportedFile)
+	}
+	if _, _, err := ExtractThumbnailBytes(testDNG(t, "RGGB")); !errors.Is(err, ErrNoThumbnail) {
+		t.Errorf("ExtractThumbnailBytes without thumbnail: %v, want %v", err, ErrNoThumbnail)
+	}
+}
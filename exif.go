@@ -0,0 +1,92 @@
+package golibraw
+
+import (
+	"bytes"
+	"io"
+	"math"
+)
+
+// EXIF fields not exposed by libraw, read directly from TIFF based RAW containers
+type exifData struct {
+	copyright       string
+	exposureBias    float64
+	flash           bool
+	hasFlash        bool
+	timeOffset      string
+	shutterCount    int
+	subjectDistance float64
+	dng             *DNGInfo
+}
+
+// Reads the EXIF fields of a TIFF based container, the fields of malformed or truncated structures are left empty.
+func readExif(r io.ReaderAt) exifData {
+	data := exifData{}
+	t, ok := newTiffReader(r)
+	if !ok {
+		return data
+	}
+
+	ifd0, _ := t.ifd(t.first)
+	if e, ok := findEntry(ifd0, tagCopyright); ok {
+		data.copyright = t.ascii(e)
+	}
+	data.dng = readDNGInfo(t, ifd0)
+
+	e, ok := findEntry(ifd0, tagExifIFD)
+	if !ok {
+		return data
+	}
+	exif, _ := t.ifd(int64(t.uint(e, 0)))
+	if e, ok := findEntry(exif, tagExposureBias); ok {
+		data.exposureBias = t.rational(e, 0)
+	}
+	if e, ok := findEntry(exif, tagFlash); ok {
+		// bit 0 of the Flash tag reports whether the flash fired
+		data.flash = t.uint(e, 0)&1 != 0
+		data.hasFlash = true
+	}
+	if e, ok := findEntry(exif, tagSubjectDistance); ok {
+		data.subjectDistance = t.rational(e, 0)
+	}
+	if e, ok := findEntry(exif, tagOffsetTime); ok {
+		data.timeOffset = t.ascii(e)
+	}
+	if e, ok := findEntry(exif, tagMakerNote); ok {
+		data.shutterCount = nikonShutterCount(t, e)
+	}
+	return data
+}
+
+// Completes the metadata with the EXIF fields not exposed by libraw
+func (m *Metadata) addExif(exif exifData) {
+	m.Copyright = exif.copyright
+	m.TimeOffset = exif.timeOffset
+	m.ExposureCompensation = exif.exposureBias
+	m.FocusDistance = exif.subjectDistance
+	m.DNG = exif.dng
+	if exif.shutterCount > 0 {
+		m.ShutterCount = exif.shutterCount
+	}
+	if exif.hasFlash {
+		m.Flash = exif.flash
+		m.FlashRecorded = true
+	}
+}
+
+// Reads the shutter count from Nikon maker notes, which embed a complete TIFF structure after a "Nikon\0" header.
+// Returns zero for maker notes of other vendors.
+func nikonShutterCount(t *tiffReader, makerNote tiffEntry) int {
+	header := t.read(makerNote.offset, 10)
+	if !bytes.HasPrefix(header, []byte("Nikon\x00")) {
+		return 0
+	}
+	notes, ok := newTiffReader(io.NewSectionReader(t.r, makerNote.offset+10, math.MaxInt32))
+	if !ok {
+		return 0
+	}
+	ifd, _ := notes.ifd(notes.first)
+	if e, ok := findEntry(ifd, tagNikonShutterCount); ok {
+		return int(notes.uint(e, 0))
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func rationalEntry(tag uint16, typ uint16, num int32, den uint32) testEntry {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(num))
+	return testEntry{tag, typ, binary.LittleEndian.AppendUint32(data, den)}
+}
+
+// Returns a TIFF file with the entries of IFD0 and the entries of the EXIF IFD linked by IFD0
+func exifTIFF(ifd0 []testEntry, exif ...testEntry) []byte {
+	ifd0 = append(ifd0, testEntry{tagExifIFD, typeLong, make([]byte, 4)})
+	data := testTIFF(ifd0...)
+	// the EXIF IFD entry is the last one of IFD0
+	binary.LittleEndian.PutUint32(data[8+2+12*len(ifd0)-4:], uint32(len(data)))
+	return appendIFD(data, exif...)
+}
+
+func TestReadExif(t *testing.T) {
+	data := exifTIFF(
+		[]testEntry{{tagCopyright, typeASCII, []byte("(c) Golibraw\x00")}},
+		rationalEntry(tagExposureBias, typeSRational, -2, 3),
+		shortEntry(tagFlash, 0x19),
+	)
+	exif := readExif(bytes.NewReader(data))
+	if exif.copyright != "(c) Golibraw" {
+		t.Errorf("copyright = %q, want %q", exif.copyright, "(c) Golibraw")
+	}
+	if exif.exposureBias > -0.66 || exif.exposureBias < -0.67 {
+		t.Errorf("exposure bias = %v, want -2/3", exif.exposureBias)
+	}
+	if !exif.flash || !exif.hasFlash {
+		t.Errorf("flash = %v, recorded %v, want fired", exif.flash, exif.hasFlash)
+	}
+
+	var metadata Metadata
+	metadata.addExif(exif)
+	if metadata.Copyright != exif.copyright || metadata.ExposureCompensation != exif.exposureBias || !metadata.Flash || !metadata.FlashRecorded {
+		t.Errorf("metadata = %+v, want the EXIF fields", metadata)
+	}
+
+	// flash did not fire
+	exif = readExif(bytes.NewReader(exifTIFF(nil, shortEntry(tagFlash, 0x10))))
+	if exif.flash || !exif.hasFlash {
+		t.Errorf("flash = %v, recorded %v, want recorded not fired", exif.flash, exif.hasFlash)
+	}
+	// without EXIF IFD
+	if exif := readExif(bytes.NewReader(testTIFF())); exif.hasFlash || exif.copyright != "" {
+		t.Errorf("readExif of an empty TIFF = %+v", exif)
+	}
+}
+
+func TestNikonShutterCount(t *testing.T) {
+	notes := testTIFF(testEntry{tagNikonShutterCount, typeLong, binary.LittleEndian.AppendUint32(nil, 12345)})
+	nikon := append([]byte("Nikon\x00\x02\x10\x00\x00"), notes...)
+	exif := readExif(bytes.NewReader(exifTIFF(nil, testEntry{tagMakerNote, typeUndefined, nikon})))
+	if exif.shutterCount != 12345 {
+		t.Errorf("shutter count = %v, want 12345", exif.shutterCount)
+	}
+	var metadata Metadata
+	metadata.addExif(exif)
+	if metadata.ShutterCount != 12345 {
+		t.Errorf("metadata shutter count = %v, want 12345", metadata.ShutterCount)
+	}
+
+	// maker notes of another vendor
+	other := append([]byte("OLYMPUS\x00II\x03\x00"), notes...)
+	if exif := readExif(bytes.NewReader(exifTIFF(nil, testEntry{tagMakerNote, typeUndefined, other}))); exif.shutterCount != 0 {
+		t.Errorf("shutter count of Olympus maker notes = %v, want 0", exif.shutterCount)
+	}
+	// the shutter count of the camera is kept without Nikon maker notes
+	metadata = Metadata{ShutterCount: 678}
+	metadata.addExif(exifData{})
+	if metadata.ShutterCount != 678 {
+		t.Errorf("metadata shutter count = %v, want 678 kept", metadata.ShutterCount)
+	}
+}
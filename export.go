@@ -0,0 +1,56 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Reads a RAW image file from file system and exports it to JPEG format with the given quality (1-100, 0 for default).
+// When maxDim is positive, the image is scaled down to fit in maxDim x maxDim, keeping its aspect ratio.
+// The image is processed with the provided options.
+func ExportJPEG(inputPath string, exportPath string, quality int, maxDim int, opts ...Option) error {
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("invalid JPEG quality [%v]", quality)
+	}
+
+	return encodeFile(exportPath, newOptions(opts).output, func(w io.Writer) error {
+		img, err := ImportRawWithOptions(inputPath, opts...)
+		if err != nil {
+			return err
+		}
+		return jpeg.Encode(w, resample(img, maxDim, newOptions(opts).resize.filter), &jpeg.Options{Quality: quality})
+	})
+}
+
+// Reads a RAW image file from file system and exports it to lossless PNG format, processing it with the provided options.
+// Use WithBitDepth(16) for 16-bit output.
+func ExportPNG(inputPath string, exportPath string, opts ...Option) error {
+	return encodeFile(exportPath, newOptions(opts).output, func(w io.Writer) error {
+		img, err := ImportRawWithOptions(inputPath, opts...)
+		if err != nil {
+			return err
+		}
+		return png.Encode(w, img)
+	})
+}
+
+// Creates the file at exportPath and writes it with encode, the file is removed if encoding fails
+func encodeFile(exportPath string, output OutputOptions, encode func(w io.Writer) error) error {
+	out, err := output.create(exportPath)
+	if err != nil {
+		return err
+	}
+
+	if err = encode(out); err != nil {
+		out.Close()
+		return output.finish(out.Name(), exportPath, fmt.Errorf("failed to export file to [%v] with [%w]", exportPath, err))
+	}
+	return output.finish(out.Name(), exportPath, out.Close())
+}
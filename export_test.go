@@ -0,0 +1,89 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Returns the decoded configuration of the image file at path
+func decodeConfigFile(t *testing.T, path string) (image.Config, string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	config, format, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("failed to decode [%v]: %v", path, err)
+	}
+	return config, format
+}
+
+func TestExportJPEG(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	dir := t.TempDir()
+
+	full := filepath.Join(dir, "full.jpg")
+	if err := ExportJPEG(path, full, 0, 0); err != nil {
+		t.Fatalf("ExportJPEG failed: %v", err)
+	}
+	if config, format := decodeConfigFile(t, full); format != "jpeg" || config.Width != testWidth || config.Height != testHeight {
+		t.Errorf("export = %v %vx%v, want jpeg %vx%v", format, config.Width, config.Height, testWidth, testHeight)
+	}
+
+	small := filepath.Join(dir, "small.jpg")
+	if err := ExportJPEG(path, small, jpeg.DefaultQuality, testWidth/2); err != nil {
+		t.Fatalf("ExportJPEG failed: %v", err)
+	}
+	if config, _ := decodeConfigFile(t, small); config.Width != testWidth/2 || config.Height != testHeight/2 {
+		t.Errorf("resized export = %vx%v, want %vx%v", config.Width, config.Height, testWidth/2, testHeight/2)
+	}
+
+	for _, quality := range []int{-1, 101} {
+		output := filepath.Join(dir, "invalid.jpg")
+		if err := ExportJPEG(path, output, quality, 0); err == nil {
+			t.Errorf("ExportJPEG succeeded with quality %v", quality)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("ExportJPEG with quality %v created its output", quality)
+		}
+	}
+
+	failed := filepath.Join(dir, "failed.jpg")
+	if err := ExportJPEG(filepath.Join(dir, "missing.dng"), failed, 0, 0); err == nil {
+		t.Errorf("ExportJPEG succeeded on a missing file")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("failed export left its output: %v", err)
+	}
+}
+
+func TestExportPNG(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	for _, bits := range []int{8, 16} {
+		output := filepath.Join(t.TempDir(), "out.png")
+		if err := ExportPNG(path, output, WithBitDepth(bits)); err != nil {
+			t.Fatalf("ExportPNG failed: %v", err)
+		}
+		if config, format := decodeConfigFile(t, output); format != "png" || config.Width != testWidth || config.Height != testHeight {
+			t.Errorf("export = %v %vx%v, want png %vx%v", format, config.Width, config.Height, testWidth, testHeight)
+		}
+		// the bit depth follows the signature, the IHDR chunk header, the width and the height
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) < 25 {
+			t.Fatalf("export of %v bytes is too short for a PNG file", len(data))
+		}
+		if int(data[24]) != bits {
+			t.Errorf("export bit depth = %v, want %v", data[24], bits)
+		}
+	}
+}
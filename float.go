@@ -0,0 +1,174 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+)
+
+// TIFF tag of the sample format, 3 for IEEE floating point
+const tagSampleFormat = 0x0153
+
+// FloatImage is a scene-linear RGB image with 32-bit floating point samples, interleaved as R, G, B.
+// Sample values are relative to the white level, 1.0 being the saturation of the output.
+type FloatImage struct {
+	Pix    []float32
+	Width  int
+	Height int
+}
+
+// Returns the red, green and blue samples of the pixel at x and y.
+func (f *FloatImage) RGB(x int, y int) (float32, float32, float32) {
+	i := 3 * (y*f.Width + x)
+	return f.Pix[i], f.Pix[i+1], f.Pix[i+2]
+}
+
+// Reads a RAW image file from file system and converts it to a scene-linear floating point image, processing it with
+// the provided options on top of WithLinearOutput. Recent libraw versions removed the floating point processing,
+// so the image is rendered with 16-bit precision and converted.
+func ImportRawFloat(path string, opts ...Option) (*FloatImage, error) {
+	img, err := ImportRawWithOptions(path, append([]Option{WithLinearOutput()}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return floatImageOf(img), nil
+}
+
+func floatImageOf(img image.Image) *FloatImage {
+	b := img.Bounds()
+	f := &FloatImage{
+		Pix:    make([]float32, 3*b.Dx()*b.Dy()),
+		Width:  b.Dx(),
+		Height: b.Dy(),
+	}
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			f.Pix[i] = float32(r) / 0xffff
+			f.Pix[i+1] = float32(g) / 0xffff
+			f.Pix[i+2] = float32(bl) / 0xffff
+			i += 3
+		}
+	}
+	return f
+}
+
+// Reads a RAW image file from file system and exports it to an uncompressed OpenEXR file with 32-bit float
+// R, G and B channels, processing it with the provided options on top of WithLinearOutput.
+func ExportEXR(inputPath string, exportPath string, opts ...Option) error {
+	return encodeFile(exportPath, newOptions(opts).output, func(w io.Writer) error {
+		img, err := ImportRawFloat(inputPath, opts...)
+		if err != nil {
+			return err
+		}
+		return writeEXR(w, img)
+	})
+}
+
+// Reads a RAW image file from file system and exports it to an uncompressed 32-bit floating point RGB TIFF file,
+// processing it with the provided options on top of WithLinearOutput.
+func ExportFloatTIFF(inputPath string, exportPath string, opts ...Option) error {
+	return encodeFile(exportPath, newOptions(opts).output, func(w io.Writer) error {
+		img, err := ImportRawFloat(inputPath, opts...)
+		if err != nil {
+			return err
+		}
+		return writeFloatTIFF(w, img)
+	})
+}
+
+func writeFloatTIFF(w io.Writer, img *FloatImage) error {
+	t := &tiffWriter{}
+	t.long(tagImageWidth, uint32(img.Width))
+	t.long(tagImageLength, uint32(img.Height))
+	t.short(tagBitsPerSample, 32, 32, 32)
+	t.short(tagCompression, 1)
+	t.short(tagPhotometric, 2)
+	t.short(tagSamplesPerPixel, 3)
+	t.long(tagRowsPerStrip, uint32(img.Height))
+	t.long(tagStripByteCounts, uint32(4*len(img.Pix)))
+	t.short(tagPlanarConfiguration, 1)
+	t.ascii(tagSoftware, "golibraw")
+	t.short(tagSampleFormat, 3, 3, 3)
+
+	data := make([]byte, 4*len(img.Pix))
+	for i, v := range img.Pix {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return t.write(w, tagStripOffsets, data)
+}
+
+// Writes img as a single part scanline OpenEXR file without compression, one scanline per block.
+func writeEXR(w io.Writer, img *FloatImage) error {
+	if img.Width <= 0 || img.Height <= 0 {
+		return fmt.Errorf("invalid image size [%v x %v]", img.Width, img.Height)
+	}
+
+	header := []byte{0x76, 0x2f, 0x31, 0x01, 2, 0, 0, 0}
+	attribute := func(name string, typ string, value []byte) {
+		header = append(header, name...)
+		header = append(header, 0)
+		header = append(header, typ...)
+		header = append(header, 0)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(value)))
+		header = append(header, value...)
+	}
+
+	// channels are stored in alphabetical order
+	channels := []byte{}
+	for _, name := range []string{"B", "G", "R"} {
+		channels = append(channels, name...)
+		channels = append(channels, 0)
+		channels = binary.LittleEndian.AppendUint32(channels, 2) // FLOAT
+		channels = append(channels, 0, 0, 0, 0)                  // pLinear and reserved
+		channels = binary.LittleEndian.AppendUint32(channels, 1)
+		channels = binary.LittleEndian.AppendUint32(channels, 1)
+	}
+	channels = append(channels, 0)
+
+	window := make([]byte, 16)
+	binary.LittleEndian.PutUint32(window[8:], uint32(img.Width-1))
+	binary.LittleEndian.PutUint32(window[12:], uint32(img.Height-1))
+	one := binary.LittleEndian.AppendUint32(nil, math.Float32bits(1))
+
+	attribute("channels", "chlist", channels)
+	attribute("compression", "compression", []byte{0})
+	attribute("dataWindow", "box2i", window)
+	attribute("displayWindow", "box2i", window)
+	attribute("lineOrder", "lineOrder", []byte{0})
+	attribute("pixelAspectRatio", "float", one)
+	attribute("screenWindowCenter", "v2f", make([]byte, 8))
+	attribute("screenWindowWidth", "float", one)
+	header = append(header, 0)
+
+	lineSize := 3 * 4 * img.Width
+	blockSize := 8 + lineSize
+	offset := uint64(len(header) + 8*img.Height)
+	for y := 0; y < img.Height; y++ {
+		header = binary.LittleEndian.AppendUint64(header, offset+uint64(y*blockSize))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	block := make([]byte, blockSize)
+	for y := 0; y < img.Height; y++ {
+		binary.LittleEndian.PutUint32(block, uint32(y))
+		binary.LittleEndian.PutUint32(block[4:], uint32(lineSize))
+		for c, channel := range []int{2, 1, 0} {
+			for x := 0; x < img.Width; x++ {
+				v := img.Pix[3*(y*img.Width+x)+channel]
+				binary.LittleEndian.PutUint32(block[8+4*(c*img.Width+x):], math.Float32bits(v))
+			}
+		}
+		if _, err := w.Write(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
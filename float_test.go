@@ -0,0 +1,114 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFloatImageOf(t *testing.T) {
+	img := image.NewRGBA64(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA64{R: 0xffff, G: 0x8000, B: 0, A: 0xffff})
+	img.Set(1, 0, color.RGBA64{R: 0, G: 0, B: 0xffff, A: 0xffff})
+
+	f := floatImageOf(img)
+	if f.Width != 2 || f.Height != 1 || len(f.Pix) != 6 {
+		t.Fatalf("float image = %vx%v with %v samples, want 2x1 with 6", f.Width, f.Height, len(f.Pix))
+	}
+	if r, g, b := f.RGB(0, 0); r != 1 || math.Abs(float64(g)-0.5) > 1e-4 || b != 0 {
+		t.Errorf("RGB(0, 0) = %v, %v, %v, want 1, 0.5, 0", r, g, b)
+	}
+	if r, g, b := f.RGB(1, 0); r != 0 || g != 0 || b != 1 {
+		t.Errorf("RGB(1, 0) = %v, %v, %v, want 0, 0, 1", r, g, b)
+	}
+}
+
+func TestImportRawFloat(t *testing.T) {
+	f, err := ImportRawFloat(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatalf("ImportRawFloat failed: %v", err)
+	}
+	if f.Width != testWidth || f.Height != testHeight || len(f.Pix) != 3*testWidth*testHeight {
+		t.Fatalf("float image = %vx%v with %v samples, want %vx%v", f.Width, f.Height, len(f.Pix), testWidth, testHeight)
+	}
+	for i, v := range f.Pix {
+		if v < 0 || v > 1 {
+			t.Fatalf("sample [%v] = %v, want a value in [0, 1]", i, v)
+		}
+	}
+}
+
+func TestExportFloatTIFF(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.tiff")
+	if err := ExportFloatTIFF(testDNG(t, "RGGB"), output); err != nil {
+		t.Fatalf("ExportFloatTIFF failed: %v", err)
+	}
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, ok := newTiffReader(f)
+	if !ok {
+		t.Fatalf("float export is not a TIFF file")
+	}
+	entries, _ := r.ifd(r.first)
+	for tag, want := range map[uint16]int{
+		tagImageWidth: testWidth, tagImageLength: testHeight, tagBitsPerSample: 32, tagSampleFormat: 3,
+		tagStripByteCounts: 4 * 3 * testWidth * testHeight,
+	} {
+		e, ok := findEntry(entries, tag)
+		if !ok {
+			t.Errorf("float TIFF has no tag %#x", tag)
+			continue
+		}
+		if got := int(r.uint(e, 0)); got != want {
+			t.Errorf("float TIFF tag %#x = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestWriteEXR(t *testing.T) {
+	img := &FloatImage{Pix: []float32{0.25, 0.5, 0.75, 1, 2, 3}, Width: 2, Height: 1}
+	var buf bytes.Buffer
+	if err := writeEXR(&buf, img); err != nil {
+		t.Fatalf("writeEXR failed: %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte{0x76, 0x2f, 0x31, 0x01, 2, 0, 0, 0}) {
+		t.Fatalf("EXR starts with %x, want the magic number and version 2", data[:8])
+	}
+	for _, attribute := range []string{"channels\x00chlist", "compression\x00compression", "dataWindow\x00box2i"} {
+		if !bytes.Contains(data, []byte(attribute)) {
+			t.Errorf("EXR header has no %q attribute", attribute)
+		}
+	}
+
+	// the single scanline block is at the end: y, size and the B, G and R channels of both pixels
+	lineSize := 3 * 4 * img.Width
+	block := data[len(data)-8-lineSize:]
+	if y, size := binary.LittleEndian.Uint32(block), binary.LittleEndian.Uint32(block[4:]); y != 0 || int(size) != lineSize {
+		t.Errorf("scanline block y = %v and size = %v, want 0 and %v", y, size, lineSize)
+	}
+	offset := binary.LittleEndian.Uint64(data[len(data)-8-lineSize-8:])
+	if int(offset) != len(data)-8-lineSize {
+		t.Errorf("scanline offset = %v, want %v", offset, len(data)-8-lineSize)
+	}
+	want := []float32{0.75, 3, 0.5, 2, 0.25, 1}
+	for i, w := range want {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(block[8+4*i:])); got != w {
+			t.Errorf("sample [%v] = %v, want %v", i, got, w)
+		}
+	}
+
+	if err := writeEXR(&buf, &FloatImage{}); err == nil {
+		t.Errorf("writeEXR succeeded with an empty image")
+	}
+}
@@ -0,0 +1,75 @@
+package golibraw
+
+import (
+	"image"
+)
+
+// Reads a RAW image file from file system and computes the focus score of its embedded preview, or of a half size
+// render if there is no usable preview. See ImageFocusScore, scores are only comparable between images of the same camera.
+func FocusScore(path string) (float64, error) {
+	img, err := previewOf(path)
+	if err != nil {
+		return 0, err
+	}
+	return ImageFocusScore(img), nil
+}
+
+// Computes the focus score of an image as the variance of the Laplacian of its green channel.
+// Sharper images score higher, e.g. for ranking the shots of a burst.
+func ImageFocusScore(img image.Image) float64 {
+	green := greenOf(img)
+	return laplacianVariance(green, img.Bounds().Dx(), image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+}
+
+// Computes a coarse focus map of an image, with the focus score of each cell of a cols x rows grid, row by row.
+func FocusMap(img image.Image, cols int, rows int) [][]float64 {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	green := greenOf(img)
+	scores := make([][]float64, rows)
+	for r := range scores {
+		scores[r] = make([]float64, cols)
+		for c := range scores[r] {
+			cell := image.Rect(c*w/cols, r*h/rows, (c+1)*w/cols, (r+1)*h/rows)
+			scores[r][c] = laplacianVariance(green, w, cell)
+		}
+	}
+	return scores
+}
+
+// Returns the green channel of img with 8-bit precision, row by row
+func greenOf(img image.Image) []float64 {
+	b := img.Bounds()
+	green := make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, g, _, _ := img.At(x, y).RGBA()
+			green = append(green, float64(g>>8))
+		}
+	}
+	return green
+}
+
+// Variance of the 4-neighbour Laplacian of the plane in the rectangle r, skipping the border pixels of the plane
+func laplacianVariance(plane []float64, stride int, r image.Rectangle) float64 {
+	height := len(plane) / max(stride, 1)
+	r = r.Intersect(image.Rect(1, 1, stride-1, height-1))
+	if r.Empty() {
+		return 0
+	}
+
+	sum, squares := 0.0, 0.0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := y*stride + x
+			l := plane[i-1] + plane[i+1] + plane[i-stride] + plane[i+stride] - 4*plane[i]
+			sum += l
+			squares += l * l
+		}
+	}
+	n := float64(r.Dx() * r.Dy())
+	mean := sum / n
+	return squares/n - mean*mean
+}
@@ -0,0 +1,55 @@
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+// Returns a gray image with a checkerboard of cell x cell squares in the rectangle r, flat gray elsewhere
+func checkerboard(width int, height int, cell int, r image.Rectangle) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(128)
+			if (image.Point{X: x, Y: y}).In(r) {
+				v = uint8(64 + 128*((x/cell+y/cell)%2))
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestImageFocusScore(t *testing.T) {
+	all := image.Rect(0, 0, 64, 64)
+	sharp := ImageFocusScore(checkerboard(64, 64, 1, all))
+	soft := ImageFocusScore(checkerboard(64, 64, 8, all))
+	if sharp <= soft || soft <= 0 {
+		t.Errorf("focus score of fine detail = %v, of coarse detail = %v, want fine > coarse > 0", sharp, soft)
+	}
+	if flat := ImageFocusScore(checkerboard(64, 64, 1, image.Rectangle{})); flat != 0 {
+		t.Errorf("focus score of a flat image = %v, want 0", flat)
+	}
+	if tiny := ImageFocusScore(checkerboard(2, 2, 1, all)); tiny != 0 {
+		t.Errorf("focus score of a 2x2 image = %v, want 0", tiny)
+	}
+}
+
+func TestFocusMap(t *testing.T) {
+	// detail in the left half only, away from the border of the cells
+	scores := FocusMap(checkerboard(64, 32, 1, image.Rect(0, 0, 31, 32)), 2, 1)
+	if len(scores) != 1 || len(scores[0]) != 2 {
+		t.Fatalf("focus map = %v, want 1 row of 2 cells", scores)
+	}
+	if scores[0][0] <= 0 || scores[0][1] != 0 {
+		t.Errorf("focus map = %v, want the left cell in focus and the right one flat", scores)
+	}
+	if scores := FocusMap(checkerboard(8, 8, 1, image.Rect(0, 0, 8, 8)), 0, 1); scores != nil {
+		t.Errorf("focus map of 0 columns = %v, want nil", scores)
+	}
+	if _, err := FocusScore(filepath.Join(t.TempDir(), "missing.dng")); err == nil {
+		t.Errorf("FocusScore of a missing file succeeded")
+	}
+}
@@ -0,0 +1,78 @@
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTextWidth(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		scale int
+		want  int
+	}{
+		{"", 1, 0},
+		{"A", 1, glyphWidth},
+		{"AB", 1, glyphAdvance + glyphWidth},
+		{"AB", 2, 2 * (glyphAdvance + glyphWidth)},
+		{"ÄÖ", 1, glyphAdvance + glyphWidth},
+	} {
+		if got := textWidth(tt.text, tt.scale); got != tt.want {
+			t.Errorf("textWidth(%q, %v) = %v, want %v", tt.text, tt.scale, got, tt.want)
+		}
+	}
+}
+
+// Returns the font pixels of text drawn at scale 1 as rows of '#' and ' '
+func drawnRows(text string) []string {
+	img := image.NewRGBA(image.Rect(0, 0, textWidth(text, 1), glyphHeight))
+	drawText(img, 0, 0, text, 1, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	rows := make([]string, glyphHeight)
+	for y := range rows {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if img.RGBAAt(x, y).R != 0 {
+				rows[y] += "#"
+			} else {
+				rows[y] += " "
+			}
+		}
+	}
+	return rows
+}
+
+func TestDrawText(t *testing.T) {
+	for y, row := range drawnRows("1") {
+		if row != glyphs['1'][y] {
+			t.Errorf("row %v of \"1\" = %q, want %q", y, row, glyphs['1'][y])
+		}
+	}
+	// lower case letters are drawn in upper case, unknown characters as '?'
+	for _, tt := range []struct{ text, like string }{{"raw", "RAW"}, {"é", "?"}} {
+		got, want := drawnRows(tt.text), drawnRows(tt.like)
+		for y := range want {
+			if got[y] != want[y] {
+				t.Errorf("row %v of %q = %q, want it drawn like %q", y, tt.text, got[y], tt.like)
+			}
+		}
+	}
+
+	// scaled font pixels are blocks, text is clipped at the edges of the image
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	drawText(img, -2, -2, "_", 2, color.RGBA{0xff, 0, 0, 0xff})
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			// the underline is the last glyph row, covering y 10 to 11 at scale 2
+			if img.RGBAAt(x, y).R != 0 {
+				t.Errorf("pixel (%v, %v) is drawn, want the underline outside of the image", x, y)
+			}
+		}
+	}
+	img = image.NewRGBA(image.Rect(0, 0, 4, 4))
+	drawText(img, 0, -12, "_", 2, color.RGBA{0xff, 0, 0, 0xff})
+	for x := 0; x < 4; x++ {
+		if img.RGBAAt(x, 0).R == 0 || img.RGBAAt(x, 1).R == 0 || img.RGBAAt(x, 2).R != 0 {
+			t.Errorf("column %v is not drawn in rows 0 and 1 only", x)
+		}
+	}
+}
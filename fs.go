@@ -0,0 +1,80 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+)
+
+// Reads a RAW image file from fsys (e.g. embed.FS, a zip archive or a virtual file system) and converts it to
+// standard image.Image, processing it with the provided options. The file is buffered in memory for libraw.
+func ImportRawFS(fsys fs.FS, name string, opts ...Option) (image.Image, error) {
+	processor, err := openFS(fsys, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+
+	if err = processor.Unpack(); err != nil {
+		return nil, err
+	}
+
+	if err = processor.Process(opts...); err != nil {
+		return nil, err
+	}
+
+	return processor.Image()
+}
+
+// Reads a RAW image file from fsys and exports collected metadata, see ImportRawFS.
+func ExtractMetadataFS(fsys fs.FS, name string) (Metadata, error) {
+	processor, err := openFS(fsys, name, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer processor.Close()
+
+	return processor.Metadata()
+}
+
+// Reads a RAW image file from fsys and decodes the embedded thumbnail image - if it exists - to standard image.Image,
+// see ImportRawFS.
+func ImportThumbnailFS(fsys fs.FS, name string) (image.Image, error) {
+	processor, err := openFS(fsys, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+
+	if err = processor.UnpackThumbnail(); err != nil {
+		return nil, err
+	}
+
+	data, info, err := processor.Thumbnail()
+	if err != nil {
+		return nil, err
+	}
+	return decodeThumbnail(data, info)
+}
+
+// Returns a new processor with the file opened, which has to be closed by the caller
+func openFS(fsys fs.FS, name string, opts []Option) (*Processor, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file [%v] with [%w]", name, err)
+	}
+
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
+	}
+	processor.configure(newOptions(opts))
+
+	if err = processor.OpenBytes(data); err != nil {
+		processor.Close()
+		return nil, fmt.Errorf("failed to open file [%v] with [%w]", name, err)
+	}
+	return processor, nil
+}
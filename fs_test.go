@@ -0,0 +1,59 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"image"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	raw, err := os.ReadFile(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previewPath, _ := previewDNG(t)
+	preview, err := os.ReadFile(previewPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"raw/test.dng":    {Data: raw},
+		"raw/preview.dng": {Data: preview},
+	}
+
+	img, err := ImportRawFS(fsys, "raw/test.dng", WithHalfSize())
+	if err != nil {
+		t.Fatalf("ImportRawFS failed: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, testWidth/2, testHeight/2) {
+		t.Errorf("bounds = %v, want the half size %vx%v", got, testWidth/2, testHeight/2)
+	}
+
+	md, err := ExtractMetadataFS(fsys, "raw/test.dng")
+	if err != nil {
+		t.Fatalf("ExtractMetadataFS failed: %v", err)
+	}
+	if md.Camera != testMetadata().Camera {
+		t.Errorf("camera = %+v, want %+v", md.Camera, testMetadata().Camera)
+	}
+
+	thumbnail, err := ImportThumbnailFS(fsys, "raw/preview.dng")
+	if err != nil {
+		t.Fatalf("ImportThumbnailFS failed: %v", err)
+	}
+	if got := thumbnail.Bounds(); got != image.Rect(0, 0, previewWidth, previewHeight) {
+		t.Errorf("thumbnail bounds = %v, want %vx%v", got, previewWidth, previewHeight)
+	}
+
+	if _, err := ExtractMetadataFS(fsys, "raw/missing.dng"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ExtractMetadataFS of a missing file error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := ImportThumbnailFS(fsys, "raw/test.dng"); err == nil {
+		t.Errorf("ImportThumbnailFS of a file without thumbnail succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Standard X-Trans pattern of Fujifilm sensors
+const testXTrans = "GGRGGB" + "GGBGGR" + "BRGRBG" + "GGBGGR" + "GGRGGB" + "RBGBRG"
+
+// Seeds the fuzz corpus with synthetic DNGs of every layout and the known-corrupt files of testdata/corrupt
+func addFuzzSeeds(f *testing.F) {
+	for _, pattern := range []string{"RGGB", testXTrans, ""} {
+		data, err := os.ReadFile(testDNG(f, pattern))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	paths, _ := filepath.Glob(filepath.Join("testdata", "corrupt", "*"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+// Opens arbitrary data with a processor limited to 64 MB, keeping the fuzzer from spending its time on huge
+// allocations. Returns nil if libraw rejects the data.
+func fuzzProcessor(t *testing.T, data []byte) *Processor {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.SetMemoryLimit(64)
+	if err = processor.OpenBytes(data); err != nil {
+		processor.Close()
+		return nil
+	}
+	return processor
+}
+
+func FuzzDecodeBytes(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		processor := fuzzProcessor(t, data)
+		if processor == nil {
+			return
+		}
+		defer processor.Close()
+		if processor.Unpack() != nil {
+			return
+		}
+		processor.Bayer()
+		if processor.Process(WithHalfSize()) == nil {
+			processor.Image()
+		}
+	})
+}
+
+func FuzzExtractMetadataBytes(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DetectFormat(data)
+		processor := fuzzProcessor(t, data)
+		if processor == nil {
+			return
+		}
+		defer processor.Close()
+		if _, err := processor.Metadata(); err != nil {
+			t.Errorf("metadata of an opened file failed: %v", err)
+		}
+	})
+}
+
+func FuzzExtractThumbnailBytes(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		processor := fuzzProcessor(t, data)
+		if processor == nil {
+			return
+		}
+		defer processor.Close()
+		processor.Thumbnails()
+		if processor.UnpackThumbnail() == nil {
+			processor.Thumbnail()
+		}
+	})
+}
@@ -1,5 +1,3 @@
 module github.com/inokone/golibraw
 
 go 1.21
-
-require github.com/lmittmann/ppm v1.0.2
@@ -2,68 +2,88 @@
 
 package golibraw
 
-// #cgo LDFLAGS: -lraw
+// #include <stdlib.h>
 // #include <libraw/libraw.h>
 import "C"
 
 import (
-	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"image"
-	"os"
+	"image/color"
+	"io"
+	"log/slog"
+	"time"
 	"unsafe"
-
-	"github.com/lmittmann/ppm"
 )
 
-type Camera struct {
-	Make     string
-	Model    string
-	Software string
-	Colors   uint
-}
-
-type Lens struct {
-	Make           string
-	Model          string
-	Serial         string
-	MinFocal       float64
-	MaxFocal       float64
-	MaxAp4MinFocal float64
-	MaxAp4MaxFocal float64
-}
-
-type Metadata struct {
-	Timestamp int64
-	Width     int
-	Height    int
-	DataSize  int64
-	Camera    Camera
-	Lens      Lens
-	ISO       int
-	Aperture  float64
-	Shutter   float64
-}
-
 type rawImg struct {
 	Height   int
 	Width    int
 	Bits     uint
+	Colors   int
 	DataSize int
 	Data     []byte
 }
 
-func (r rawImg) fullBytes() []byte {
-	header := fmt.Sprintf("P6\n%d %d\n%d\n", r.Width, r.Height, (1<<r.Bits)-1)
-	return append([]byte(header), r.Data...)
+func (r rawImg) rgba() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	for i, j := 0, 0; i+2 < len(r.Data) && j+3 < len(img.Pix); i, j = i+3, j+4 {
+		img.Pix[j] = r.Data[i]
+		img.Pix[j+1] = r.Data[i+1]
+		img.Pix[j+2] = r.Data[i+2]
+		img.Pix[j+3] = 0xff
+	}
+	return img
+}
+
+// libraw stores 16-bit samples in native byte order
+func (r rawImg) nrgba64() *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, r.Width, r.Height))
+	for i, j := 0, 0; i+5 < len(r.Data) && j+7 < len(img.Pix); i, j = i+6, j+8 {
+		for c := 0; c < 3; c++ {
+			v := binary.NativeEndian.Uint16(r.Data[i+2*c:])
+			img.Pix[j+2*c] = uint8(v >> 8)
+			img.Pix[j+2*c+1] = uint8(v)
+		}
+		img.Pix[j+6] = 0xff
+		img.Pix[j+7] = 0xff
+	}
+	return img
+}
+
+// Takes the first sample of every pixel for monochrome sensors, the largest for images rendered WithMonochrome:
+// without demosaic, only the channel of the filter of the pixel is filled
+func (r rawImg) gray() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, r.Width, r.Height))
+	for i, j := 0, 0; i+r.Colors <= len(r.Data) && j < len(img.Pix); i, j = i+r.Colors, j+1 {
+		v := r.Data[i]
+		for c := 1; c < r.Colors; c++ {
+			v = max(v, r.Data[i+c])
+		}
+		img.Pix[j] = v
+	}
+	return img
+}
+
+func (r rawImg) gray16() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, r.Width, r.Height))
+	for i, j := 0, 0; i+2*r.Colors <= len(r.Data) && j+1 < len(img.Pix); i, j = i+2*r.Colors, j+2 {
+		v := binary.NativeEndian.Uint16(r.Data[i:])
+		for c := 1; c < r.Colors; c++ {
+			v = max(v, binary.NativeEndian.Uint16(r.Data[i+2*c:]))
+		}
+		putUint16(img.Pix[j:], v)
+	}
+	return img
 }
 
 func goResult(result C.int) error {
 	if int(result) == 0 {
 		return nil
 	}
-	p := C.libraw_strerror(result)
-	return fmt.Errorf("libraw error: %v", C.GoString(p))
+	return newError(result)
 }
 
 func lrInit() *C.libraw_data_t {
@@ -72,49 +92,176 @@ func lrInit() *C.libraw_data_t {
 }
 
 // Reads a RAW image file from file system and exports the embedded thumbnail image - if it exists - to the path defined by exportPath parameter.
-// This method is significantly faster than importing the RAW image file.
-func ExtractThumbnail(inputPath string, exportPath string) error {
-	if _, err := os.Stat(exportPath); err == nil {
-		return fmt.Errorf("output file [%v] already exists", exportPath)
+// This method is significantly faster than importing the RAW image file. See WithOutputOptions for replacing an existing file.
+func ExtractThumbnail(inputPath string, exportPath string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.output.prepare(exportPath); err != nil {
+		return err
 	}
 
-	if _, err := os.Stat(inputPath); err != nil {
-		return fmt.Errorf("input file [%v] does not exist", exportPath)
+	processor, err := NewProcessor()
+	if err != nil {
+		return err
 	}
+	defer processor.Close()
+	processor.configure(o)
 
-	librawProcessor := lrInit()
-	defer C.libraw_recycle(librawProcessor)
+	if err = processor.Open(inputPath); err != nil {
+		return err
+	}
 
-	if err := goResult(C.libraw_open_file(librawProcessor, C.CString(inputPath))); err != nil {
-		return fmt.Errorf("failed to open input file [%v]", inputPath)
+	if err = processor.UnpackThumbnail(); err != nil {
+		return err
 	}
 
-	if err := goResult(C.libraw_unpack_thumb(librawProcessor)); err != nil {
-		return fmt.Errorf("unpacking thumbnail from RAW failed with [%v]", err)
+	out, err := o.output.create(exportPath)
+	if err != nil {
+		return err
 	}
+	out.Close()
+	return o.output.finish(out.Name(), exportPath, processor.ExportThumbnail(out.Name()))
+}
 
-	if err := goResult(C.libraw_dcraw_thumb_writer(librawProcessor, C.CString(exportPath))); err != nil {
-		return fmt.Errorf("writing thumbnail failed with [%v]", err)
+// Reads a RAW image file from file system and returns the embedded ICC profile, nil if there is none.
+func ExtractICCProfile(path string) ([]byte, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
 	}
+	defer processor.Close()
 
-	return nil
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+	return processor.ICCProfile(), nil
+}
+
+// Reads a RAW image file from file system and returns the embedded XMP packet, nil if there is none.
+func ExtractXMP(path string) ([]byte, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+	return processor.XMP(), nil
 }
 
 // Reads a RAW image file from file system and exports collected metadata.
 // This method is significantly faster than importing the RAW image file.
 func ExtractMetadata(path string) (Metadata, error) {
-	stat, err := os.Stat(path)
+	processor, err := NewProcessor()
 	if err != nil {
-		return Metadata{}, fmt.Errorf("input file does not exist [%v]", path)
+		return Metadata{}, err
 	}
+	defer processor.Close()
 
-	librawProcessor := lrInit()
-	defer C.libraw_recycle(librawProcessor)
+	if err = processor.Open(path); err != nil {
+		return Metadata{}, err
+	}
+	return processor.Metadata()
+}
 
-	if err := goResult(C.libraw_open_file(librawProcessor, C.CString(path))); err != nil {
-		return Metadata{}, fmt.Errorf("failed to open input file [%v]", path)
+// Reads a RAW image from memory and exports collected metadata.
+// This method is significantly faster than importing the RAW image.
+func ExtractMetadataBytes(data []byte) (Metadata, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return Metadata{}, err
 	}
+	defer processor.Close()
 
+	if err = processor.OpenBytes(data); err != nil {
+		return Metadata{}, err
+	}
+	return processor.Metadata()
+}
+
+// Reads the dimensions and color model of the image ImportRawWithOptions would return for a RAW image file with the
+// same options, without unpacking or processing the pixel data. Like image.DecodeConfig, it is meant for laying out
+// images before decoding them.
+func DecodeConfig(path string, opts ...Option) (image.Config, error) {
+	o := newOptions(opts)
+	if o.err != nil {
+		return image.Config{}, o.err
+	}
+	processor, err := NewProcessor()
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer processor.Close()
+	processor.configure(o)
+
+	if err = processor.Open(path); err != nil {
+		return image.Config{}, err
+	}
+	o.apply(processor)
+	params := &processor.handle.params
+	sizes := &processor.handle.sizes
+
+	width, height := int(sizes.width), int(sizes.height)
+	if crop := params.cropbox; crop[2] > 0 && crop[3] > 0 {
+		width = min(int(crop[2]), width-int(crop[0]))
+		height = min(int(crop[3]), height-int(crop[1]))
+		if width <= 0 || height <= 0 {
+			return image.Config{}, ErrBadCrop
+		}
+	}
+	if params.half_size != 0 {
+		width, height = (width+1)/2, (height+1)/2
+	}
+	// a flip requested with the options replaces the orientation of the file
+	flip := int(sizes.flip)
+	if params.user_flip >= 0 {
+		flip = int(params.user_flip)
+	}
+	width, height = outputSize(width, height, float64(sizes.pixel_aspect), flip)
+	if o.resize.maxDim > 0 {
+		width, height = fitSize(width, height, o.resize.maxDim)
+	}
+
+	var model color.Model
+	switch {
+	case processor.handle.idata.colors == 1 || o.monochrome:
+		model = color.GrayModel
+		if params.output_bps == 16 {
+			model = color.Gray16Model
+		}
+	case params.output_bps == 16:
+		model = color.NRGBA64Model
+	default:
+		model = color.RGBAModel
+	}
+	return image.Config{ColorModel: model, Width: width, Height: height}, nil
+}
+
+// Verifies that a RAW image file is readable and not truncated by opening and unpacking it without processing,
+// e.g. after copying files from a memory card. Files libraw can only read by substituting corrupt or missing data
+// fail with a *DataError. The open options (WithShot, WithMemoryLimit, ...) are applied.
+func Validate(path string, opts ...Option) error {
+	processor, err := NewProcessor()
+	if err != nil {
+		return err
+	}
+	defer processor.Close()
+	processor.configure(newOptions(opts))
+
+	if err = processor.Open(path); err != nil {
+		return err
+	}
+	if err = processor.Unpack(); err != nil {
+		return err
+	}
+	if err = processor.DataError(); err != nil {
+		return fmt.Errorf("file [%v] is damaged: [%w]", path, err)
+	}
+	return nil
+}
+
+func metadataOf(librawProcessor *C.libraw_data_t, dataSize int64) Metadata {
 	iparam := C.libraw_get_iparams(librawProcessor)
 	lensinfo := C.libraw_get_lensinfo(librawProcessor)
 	other := C.libraw_get_imgother(librawProcessor)
@@ -125,113 +272,410 @@ func ExtractMetadata(path string) (Metadata, error) {
 		Timestamp: int64(other.timestamp),
 		Width:     int(width),
 		Height:    int(height),
-		DataSize:  stat.Size(),
+		DataSize:  dataSize,
 		Camera: Camera{
 			Make:     C.GoString(&iparam.normalized_make[0]),
 			Model:    C.GoString(&iparam.normalized_model[0]),
 			Software: C.GoString(&iparam.software[0]),
+			Serial:   cameraSerial(librawProcessor),
 			Colors:   uint(iparam.colors),
 		},
 		Lens: Lens{
-			Make:           C.GoString(&lensinfo.LensMake[0]),
-			Model:          C.GoString(&lensinfo.Lens[0]),
-			Serial:         C.GoString(&lensinfo.LensSerial[0]),
-			MinFocal:       float64(lensinfo.MinFocal),
-			MaxFocal:       float64(lensinfo.MaxFocal),
-			MaxAp4MinFocal: float64(lensinfo.MaxAp4MinFocal),
-			MaxAp4MaxFocal: float64(lensinfo.MaxAp4MaxFocal),
+			Make:             C.GoString(&lensinfo.LensMake[0]),
+			Model:            C.GoString(&lensinfo.Lens[0]),
+			Serial:           C.GoString(&lensinfo.LensSerial[0]),
+			MinFocal:         float64(lensinfo.MinFocal),
+			MaxFocal:         float64(lensinfo.MaxFocal),
+			MaxAp4MinFocal:   float64(lensinfo.MaxAp4MinFocal),
+			MaxAp4MaxFocal:   float64(lensinfo.MaxAp4MaxFocal),
+			CurFocal:         float64(lensinfo.makernotes.CurFocal),
+			CurAp:            float64(lensinfo.makernotes.CurAp),
+			MaxAp4CurFocal:   float64(lensinfo.makernotes.MaxAp4CurFocal),
+			FocalLength35mm:  float64(lensinfo.FocalLengthIn35mmFormat),
+			MinFocusDistance: float64(lensinfo.makernotes.MinFocusDistance),
 		},
 		ISO:      int(other.iso_speed),
 		Aperture: float64(other.aperture),
 		Shutter:  float64(other.shutter),
+		Warnings: Warnings(librawProcessor.process_warnings),
+
+		FocalLength: float64(other.focal_len),
+		Flash:       librawProcessor.color.flash_used > 0,
+		Orientation: exifOrientation(int(librawProcessor.sizes.flip)),
+		Artist:      C.GoString(&other.artist[0]),
+		Description: C.GoString(&other.desc[0]),
+		ShotOrder:   int(other.shot_order),
+		RawType:     rawTypeOf(librawProcessor),
+		RawCount:    int(iparam.raw_count),
+		PixelShift:  pixelShiftOf(&librawProcessor.makernotes.sony),
+		MakerNotes:  makerNotesOf(librawProcessor),
+		Temperature: temperaturesOf(&librawProcessor.makernotes.common),
+		GPS:         gpsOf(&other.parsed_gps),
+		Sizes:       sizesOf(&librawProcessor.sizes),
+		CFA:         cfaOf(librawProcessor),
+		Calibration: calibrationOf(&librawProcessor.color),
+	}
+	// libraw only reports a flash which fired, the EXIF data tells whether one did not
+	metadata.FlashRecorded = metadata.Flash
+	if iparam.maker_index == C.LIBRAW_CAMERAMAKER_Sony {
+		metadata.ShutterCount = int(librawProcessor.makernotes.sony.ImageCount3)
+	}
+	if metadata.Lens.CurFocal <= 0 {
+		metadata.Lens.CurFocal = metadata.FocalLength
+	}
+	if metadata.Lens.CurAp <= 0 {
+		metadata.Lens.CurAp = metadata.Aperture
+	}
+	if metadata.Lens.FocalLength35mm <= 0 {
+		metadata.Lens.FocalLength35mm = float64(lensinfo.makernotes.FocalLengthIn35mmFormat)
+	}
+	metadata.SensorFormat, metadata.CropFactor = sensorFormatOf(librawProcessor, metadata.Lens.CurFocal, metadata.Lens.FocalLength35mm)
+	metadata.ColorTemperature, metadata.Tint, _ = colorTemperature(metadata.Calibration.CamMul, metadata.Calibration.CamXYZ)
+	return metadata
+}
+
+func pixelShiftOf(sony *C.libraw_sony_info_t) PixelShift {
+	return PixelShift{
+		GroupID: uint(sony.PixelShiftGroupID),
+		Index:   int(sony.numInPixelShiftGroup),
+		Shots:   int(sony.nShotsInPixelShiftGroup),
+	}
+}
+
+func calibrationOf(color *C.libraw_colordata_t) Calibration {
+	calibration := Calibration{
+		Black:   uint(color.black),
+		Maximum: uint(color.maximum),
+	}
+	for c := 0; c < 4; c++ {
+		calibration.CBlack[c] = uint(color.cblack[c])
+		calibration.LinearMax[c] = int(color.linear_max[c])
+		calibration.CamMul[c] = float64(color.cam_mul[c])
+		calibration.PreMul[c] = float64(color.pre_mul[c])
+	}
+
+	calibration.CamXYZ = make([][]float64, len(color.cam_xyz))
+	for i, row := range color.cam_xyz {
+		calibration.CamXYZ[i] = make([]float64, len(row))
+		for j, v := range row {
+			calibration.CamXYZ[i][j] = float64(v)
+		}
+	}
+	calibration.RGBCam = make([][]float64, len(color.rgb_cam))
+	for i, row := range color.rgb_cam {
+		calibration.RGBCam[i] = make([]float64, len(row))
+		for j, v := range row {
+			calibration.RGBCam[i][j] = float64(v)
+		}
+	}
+	return calibration
+}
+
+func sizesOf(sizes *C.libraw_image_sizes_t) Sizes {
+	result := Sizes{
+		RawWidth:    int(sizes.raw_width),
+		RawHeight:   int(sizes.raw_height),
+		Width:       int(sizes.width),
+		Height:      int(sizes.height),
+		TopMargin:   int(sizes.top_margin),
+		LeftMargin:  int(sizes.left_margin),
+		PixelAspect: float64(sizes.pixel_aspect),
+	}
+
+	width, height := outputSize(result.Width, result.Height, result.PixelAspect, int(sizes.flip))
+	result.OutputWidth, result.OutputHeight = width, height
+	if height > 0 {
+		result.AspectRatio = float64(width) / float64(height)
+	}
+	return result
+}
+
+// Returns the size of the image libraw outputs for width x height sensor pixels, stretched like libraw does for
+// non-square pixels and rotated by the flip
+func outputSize(width int, height int, pixelAspect float64, flip int) (int, int) {
+	if pixelAspect > 0 && pixelAspect < 1 {
+		height = int(float64(height)/pixelAspect + 0.5)
+	} else if pixelAspect > 1 {
+		width = int(float64(width)*pixelAspect + 0.5)
+	}
+	if flip&4 != 0 {
+		width, height = height, width
+	}
+	return width, height
+}
+
+// Converts the parsed GPS data to signed decimal degrees and meters, nil if the image has no GPS data
+func gpsOf(gps *C.libraw_gps_info_t) *GPS {
+	if gps.gpsparsed == 0 {
+		return nil
+	}
+
+	degrees := func(v [3]C.float, ref C.char, negative byte) float64 {
+		d := float64(v[0]) + float64(v[1])/60 + float64(v[2])/3600
+		if byte(ref) == negative {
+			d = -d
+		}
+		return d
+	}
+	altitude := float64(gps.altitude)
+	if gps.altref == 1 {
+		altitude = -altitude
+	}
+	timestamp := time.Duration(float64(gps.gpstimestamp[0])*float64(time.Hour) +
+		float64(gps.gpstimestamp[1])*float64(time.Minute) +
+		float64(gps.gpstimestamp[2])*float64(time.Second))
+
+	return &GPS{
+		Latitude:  degrees(gps.latitude, gps.latref, 'S'),
+		Longitude: degrees(gps.longitude, gps.longref, 'W'),
+		Altitude:  altitude,
+		Timestamp: timestamp,
+		Status:    byte(gps.gpsstatus),
+	}
+}
+
+func cameraSerial(librawProcessor *C.libraw_data_t) string {
+	serial := C.GoString(&librawProcessor.shootinginfo.BodySerial[0])
+	if serial == "" {
+		serial = C.GoString(&librawProcessor.shootinginfo.InternalBodySerial[0])
 	}
-	return metadata, nil
+	return serial
+}
+
+// EXIF orientations (1-8) indexed by the libraw flip bits (1: horizontal flip, 2: vertical flip, 4: transpose)
+var orientations = [8]int{1, 2, 4, 3, 5, 8, 6, 7}
+
+// Converts the libraw flip bits to EXIF orientation
+func exifOrientation(flip int) int {
+	return orientations[flip&7]
+}
+
+// Converts an EXIF orientation to libraw flip bits, invalid orientations are treated as normal
+func librawFlip(orientation int) int {
+	for flip, o := range orientations {
+		if o == orientation {
+			return flip
+		}
+	}
+	return 0
 }
 
 // Reads a RAW image file from file system and converts it to standard image.Image
 func ImportRaw(path string) (image.Image, error) {
-	if _, err := os.Stat(path); err != nil {
-		return nil, fmt.Errorf("input file [%v] does not exist", path)
+	return ImportRawWithOptions(path)
+}
+
+// Reads a RAW image file from file system and converts it to standard image.Image, processing it with the provided options
+func ImportRawWithOptions(path string, opts ...Option) (image.Image, error) {
+	return ImportRawContext(context.Background(), path, opts...)
+}
+
+// Reads a RAW image file from file system and converts it to standard image.Image, processing it with the provided options.
+// The import is aborted when ctx is cancelled or its deadline passes.
+func ImportRawContext(ctx context.Context, path string, opts ...Option) (image.Image, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
 	}
+	defer processor.Close()
+	processor.SetContext(ctx)
+	processor.configure(newOptions(opts))
 
-	librawProcessor := lrInit()
-	defer C.libraw_recycle(librawProcessor)
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+
+	if err = processor.Unpack(); err != nil {
+		return nil, err
+	}
 
-	err := goResult(C.libraw_open_file(librawProcessor, C.CString(path)))
+	if err = processor.Process(opts...); err != nil {
+		return nil, err
+	}
+
+	return processor.Image()
+}
+
+// Reads a RAW image file from file system and renders a preview scaled down to fit in maxDim x maxDim.
+// Half size processing is used whenever the target size allows it, which is much faster than a full size import.
+func ImportRawPreview(path string, maxDim int, opts ...Option) (image.Image, error) {
+	processor, err := NewProcessor()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file [%v]", path)
+		return nil, err
 	}
+	defer processor.Close()
+	processor.configure(newOptions(opts))
 
-	err = goResult(C.libraw_unpack(librawProcessor))
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+
+	metadata, err := processor.Metadata()
 	if err != nil {
-		return nil, fmt.Errorf("failed to unpack file [%v]", path)
+		return nil, err
+	}
+	longest := max(metadata.Sizes.OutputWidth, metadata.Sizes.OutputHeight)
+	if maxDim > 0 && maxDim <= longest/2 {
+		processor.log(slog.LevelInfo, "rendering preview at half size", "path", path, "max_dim", maxDim, "longest", longest)
+		opts = append([]Option{WithHalfSize()}, opts...)
+	}
+
+	if err = processor.Unpack(); err != nil {
+		return nil, err
 	}
 
-	err = goResult(C.libraw_dcraw_process(librawProcessor))
+	if err = processor.Process(opts...); err != nil {
+		return nil, err
+	}
+
+	img, err := processor.Image()
+	if err != nil {
+		return nil, err
+	}
+	return resample(img, maxDim, newOptions(opts).resize.filter), nil
+}
+
+// Reads a RAW image from memory and converts it to standard image.Image.
+// Useful when the RAW file is fetched from network or object storage, no temporary file is needed.
+func ImportRawBytes(data []byte) (image.Image, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+
+	if err = processor.OpenBytes(data); err != nil {
+		return nil, err
+	}
+
+	if err = processor.Unpack(); err != nil {
+		return nil, err
+	}
+
+	if err = processor.Process(); err != nil {
+		return nil, err
+	}
+
+	return processor.Image()
+}
+
+// Reads a RAW image from r and converts it to standard image.Image.
+// The whole input is buffered in memory, as libraw needs random access to the data.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input with [%w]", err)
+	}
+	return ImportRawBytes(data)
+}
+
+// Reads a RAW image from r and exports collected metadata.
+// The whole input is buffered in memory, as libraw needs random access to the data.
+func DecodeMetadata(r io.Reader) (Metadata, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to import file [%v]", path)
+		return Metadata{}, fmt.Errorf("failed to read input with [%w]", err)
 	}
+	return ExtractMetadataBytes(data)
+}
 
+// Converts the processed image, to grayscale for monochrome sensors and when monochrome is set
+func imageOf(librawProcessor *C.libraw_data_t, monochrome bool) (image.Image, error) {
 	var result C.int
 
 	img := C.libraw_dcraw_make_mem_image(librawProcessor, &result)
 	defer C.libraw_dcraw_clear_mem(img)
 
-	if goResult(result) != nil {
-		return nil, fmt.Errorf("failed to import file [%v]", path)
+	if err := goResult(result); err != nil {
+		return nil, fmt.Errorf("failed to create image with [%w]", err)
 	}
-	dataBytes := make([]uint8, int(img.data_size))
-	start := unsafe.Pointer(&img.data)
-	size := unsafe.Sizeof(uint8(0))
-	for i := 0; i < int(img.data_size); i++ {
-		item := *(*uint8)(unsafe.Pointer(uintptr(start) + size*uintptr(i)))
-		dataBytes[i] = item
+	if img == nil {
+		return nil, fmt.Errorf("failed to create image")
 	}
+	dataBytes := C.GoBytes(unsafe.Pointer(&img.data), C.int(img.data_size))
 
 	rawImage := rawImg{
 		Height:   int(img.height),
 		Width:    int(img.width),
 		DataSize: int(img.data_size),
 		Bits:     uint(img.bits),
+		Colors:   int(img.colors),
 		Data:     dataBytes,
 	}
 
-	fullbytes := rawImage.fullBytes()
-	return ppm.Decode(bytes.NewReader(fullbytes))
+	if rawImage.Colors == 1 || monochrome {
+		if rawImage.Bits == 16 {
+			return rawImage.gray16(), nil
+		}
+		return rawImage.gray(), nil
+	}
+	if rawImage.Bits == 16 {
+		return rawImage.nrgba64(), nil
+	}
+	return rawImage.rgba(), nil
+}
+
+// Reads a RAW image file from file system and exports it to PPM format, processing it with the provided options.
+// An existing output file is only replaced with WithOutputOptions.
+func ExportPPM(inputPath string, exportPath string, opts ...Option) error {
+	return ExportPPMContext(context.Background(), inputPath, exportPath, opts...)
 }
 
-// Reads a RAW image file from file system and exports it to PPM format
-func ExportPPM(inputPath string, exportPath string) error {
-	if _, err := os.Stat(exportPath); err == nil {
-		return fmt.Errorf("output file [%v] already exists", exportPath)
+// Reads a RAW image file from file system and exports it to PPM format.
+// The export is aborted when ctx is cancelled or its deadline passes.
+func ExportPPMContext(ctx context.Context, inputPath string, exportPath string, opts ...Option) error {
+	return export(ctx, inputPath, exportPath, opts...)
+}
+
+// Reads a RAW image file from file system and exports it to TIFF format, processing it with the provided options.
+// Use WithBitDepth(16) for 16-bit output.
+func ExportTIFF(inputPath string, exportPath string, opts ...Option) error {
+	return export(context.Background(), inputPath, exportPath, append([]Option{withTIFF()}, opts...)...)
+}
+
+func export(ctx context.Context, inputPath string, exportPath string, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.output.prepare(exportPath); err != nil {
+		return err
 	}
 
-	if _, err := os.Stat(inputPath); err != nil {
-		return fmt.Errorf("input file [%v] does not exist", exportPath)
+	processor, err := NewProcessor()
+	if err != nil {
+		return err
 	}
+	defer processor.Close()
+	processor.SetContext(ctx)
+	processor.configure(o)
 
-	librawProcessor := lrInit()
-	defer C.libraw_recycle(librawProcessor)
+	if err = processor.Open(inputPath); err != nil {
+		return err
+	}
 
-	err := goResult(C.libraw_open_file(librawProcessor, C.CString(inputPath)))
-	if err != nil {
-		return fmt.Errorf("failed to open file [%v]", inputPath)
+	if err = processor.Unpack(); err != nil {
+		return err
 	}
 
-	err = goResult(C.libraw_unpack(librawProcessor))
-	if err != nil {
-		return fmt.Errorf("failed to unpack file [%v]", inputPath)
+	if err = processor.Process(opts...); err != nil {
+		return err
 	}
 
-	err = goResult(C.libraw_dcraw_process(librawProcessor))
+	out, err := o.output.create(exportPath)
 	if err != nil {
-		return fmt.Errorf("failed to import file [%v]", inputPath)
+		return err
 	}
+	out.Close()
+	return o.output.finish(out.Name(), exportPath, processor.Export(out.Name()))
+}
 
-	if err = goResult(C.libraw_dcraw_ppm_tiff_writer(librawProcessor, C.CString(exportPath))); err != nil {
-		return fmt.Errorf("failed to export file to [%v]", exportPath)
+// Reads a RAW image file from file system and writes it in PPM format to w
+func ExportPPMTo(inputPath string, w io.Writer) error {
+	img, err := ImportRawMem(inputPath)
+	if err != nil {
+		return err
 	}
-	return nil
+	defer img.Close()
+
+	return writePNM(w, img.Rect.Dx(), img.Rect.Dy(), img.Colors, img.Bits, img.Pix)
 }
 
 func lrClose(iprc *C.libraw_data_t) {
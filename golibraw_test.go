@@ -0,0 +1,881 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// Test image size, small enough to decode quickly and large enough for the demosaic algorithms
+const (
+	testWidth  = 96
+	testHeight = 64
+)
+
+// Writes a synthetic DNG with a gradient of the given CFA pattern ("RGGB", a 36 letter X-Trans pattern, or empty
+// for a linear RGB image) to a temporary directory and returns its path.
+func testDNG(t testing.TB, pattern string) string {
+	t.Helper()
+	return writeTestDNG(t, testImage(pattern), testMetadata())
+}
+
+// Writes img with metadata to a DNG file in a temporary directory, returning its path
+func writeTestDNG(t testing.TB, img dngImage, metadata Metadata) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeDNG(&buf, img, metadata); err != nil {
+		t.Fatalf("failed to write test DNG: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "test.dng")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write test DNG: %v", err)
+	}
+	return path
+}
+
+func testImage(pattern string) dngImage {
+	img := dngImage{Width: testWidth, Height: testHeight, Samples: 1, White: 0xfff}
+	switch len(pattern) {
+	case 0:
+		img.Samples = 3
+	case 4:
+		img.CFA = CFA{Pattern: pattern, Width: 2, Height: 2}
+	default:
+		img.CFA = CFA{Pattern: pattern, Width: 6, Height: 6}
+	}
+	for y := 0; y < testHeight; y++ {
+		for x := 0; x < testWidth; x++ {
+			for c := 0; c < img.Samples; c++ {
+				img.Pix = append(img.Pix, uint16((x*0xfff/testWidth+y*0x7ff/testHeight+c*0x100)%0x1000))
+			}
+		}
+	}
+	return img
+}
+
+// Returns a DNG of 2 MB 16-bit sensor data, over a memory limit of 1 MB
+func largeDNG(t testing.TB) []byte {
+	t.Helper()
+	img := dngImage{Width: 1024, Height: 1024, Samples: 1, White: 0xfff, CFA: CFA{Pattern: "RGGB", Width: 2, Height: 2}}
+	img.Pix = make([]uint16, img.Width*img.Height)
+	var buf bytes.Buffer
+	if err := writeDNG(&buf, img, testMetadata()); err != nil {
+		t.Fatalf("failed to write test DNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func testMetadata() Metadata {
+	return Metadata{
+		Camera: Camera{Make: "Golibraw", Model: "Test"},
+		Calibration: Calibration{
+			CamMul: [4]float64{2, 1, 1.5, 1},
+			// sRGB primaries, D65
+			CamXYZ: [][]float64{{3.2406, -1.5372, -0.4986}, {-0.9689, 1.8758, 0.0415}, {0.0557, -0.2040, 1.0570}},
+		},
+	}
+}
+
+// Returns the path of a sample RAW file of testdata/samples, or of the directory set by GOLIBRAW_SAMPLES.
+// The test is skipped if the sample is missing, the samples are too large to be kept in the repository.
+func sample(t testing.TB, name string) string {
+	t.Helper()
+	dir := os.Getenv("GOLIBRAW_SAMPLES")
+	if dir == "" {
+		dir = filepath.Join("testdata", "samples")
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("sample [%v] is missing", path)
+	}
+	return path
+}
+
+func TestImportRaw(t *testing.T) {
+	img, err := ImportRaw(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatalf("ImportRaw failed: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, testWidth, testHeight) {
+		t.Errorf("bounds = %v, want %vx%v", got, testWidth, testHeight)
+	}
+}
+
+// Decodes the same files from many goroutines with the package functions and a shared pool, run with -race
+func TestConcurrentDecode(t *testing.T) {
+	paths := []string{testDNG(t, "RGGB"), testDNG(t, "GBRG")}
+	pool, err := NewProcessorPool(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := paths[i%len(paths)]
+			if _, err := ImportRawWithOptions(path, WithHalfSize()); err != nil {
+				errs <- err
+				return
+			}
+			if _, err := ExtractMetadata(path); err != nil {
+				errs <- err
+				return
+			}
+
+			processor, err := pool.Get(context.Background())
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer pool.Put(processor)
+			if err = processor.Open(path); err == nil {
+				if err = processor.Unpack(); err == nil {
+					if err = processor.Process(); err == nil {
+						_, err = processor.Image()
+					}
+				}
+			}
+			if err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestImportRawCorrupt(t *testing.T) {
+	for name, data := range corruptFiles(t) {
+		if _, err := ImportRawBytes(data); err == nil {
+			t.Errorf("ImportRawBytes(%v) succeeded", name)
+		}
+		if _, err := ImportRaw(filepath.Join("testdata", "corrupt", name)); err == nil {
+			t.Errorf("ImportRaw(%v) succeeded", name)
+		}
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	paths := map[string]string{"bayer": testDNG(t, "RGGB"), "linear": testDNG(t, "")}
+	for name, opts := range map[string][]Option{
+		"default":    nil,
+		"16 bit":     {WithBitDepth(16)},
+		"half size":  {WithHalfSize()},
+		"monochrome": {WithMonochrome(), WithBitDepth(16)},
+		"resize":     {WithResize(40, ResizeLanczos)},
+		"rotated":    {WithUserFlip(6)},
+		"cropped":    {WithCrop(8, 8, 32, 16)},
+	} {
+		for kind, path := range paths {
+			config, err := DecodeConfig(path, opts...)
+			if err != nil {
+				t.Fatalf("%v %v: DecodeConfig failed: %v", kind, name, err)
+			}
+			img, err := ImportRawWithOptions(path, opts...)
+			if err != nil {
+				t.Fatalf("%v %v: ImportRawWithOptions failed: %v", kind, name, err)
+			}
+			if size := img.Bounds().Size(); config.Width != size.X || config.Height != size.Y {
+				t.Errorf("%v %v: DecodeConfig size = %vx%v, want %v", kind, name, config.Width, config.Height, size)
+			}
+			if config.ColorModel != img.ColorModel() {
+				t.Errorf("%v %v: DecodeConfig color model does not match the %T image", kind, name, img)
+			}
+		}
+	}
+}
+
+// Smartphone DNGs rely on the processing data libraw does not apply. The samples are skipped if missing.
+func TestPixelDNG(t *testing.T) {
+	md, err := ExtractMetadata(sample(t, "pixel.dng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one gain map per Bayer channel
+	if md.DNG == nil || md.DNG.GainMaps != 4 {
+		t.Errorf("Pixel DNG info = %+v, want 4 gain maps", md.DNG)
+	}
+}
+
+func TestIPhoneDNG(t *testing.T) {
+	md, err := ExtractMetadata(sample(t, "iphone.dng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Camera.Make != "Apple" || md.DNG == nil || md.DNG.Version == "" {
+		t.Errorf("iPhone %v DNG info = %+v, want the DNG version", md.Camera.Make, md.DNG)
+	}
+}
+
+func TestRawType(t *testing.T) {
+	for pattern, want := range map[string]RawType{"RGGB": RawTypeBayer, testXTrans: RawTypeXTrans, "": RawTypeLinear} {
+		md, err := ExtractMetadata(testDNG(t, pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if md.RawType != want {
+			t.Errorf("raw type of a %q DNG = %v, want %v", pattern, md.RawType, want)
+		}
+	}
+}
+
+// Formats storing full color pixels, decoded without demosaic. The samples are skipped if missing.
+func TestRawTypeSamples(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want RawType
+	}{
+		{"sigma.x3f", RawTypeFoveon},
+		{"canon-sraw.cr2", RawTypeLinear},
+		{"canon-mraw.cr2", RawTypeLinear},
+		{"nikon-small.nef", RawTypeLinear},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.want == RawTypeFoveon && !Capabilities().X3FTools {
+				t.Skip("libraw is built without X3F support")
+			}
+			path := sample(t, test.name)
+			md, err := ExtractMetadata(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.RawType != test.want {
+				t.Errorf("raw type = %v, want %v", md.RawType, test.want)
+			}
+			if _, err = ImportRawWithOptions(path, WithHalfSize()); err != nil {
+				t.Errorf("decoding failed: %v", err)
+			}
+		})
+	}
+}
+
+// Decoding from memory gives the same image and metadata as decoding the file
+func TestImportRawBytes(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromFile, err := ImportRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBytes, err := ImportRawBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := fromFile.(*image.RGBA), fromBytes.(*image.RGBA); a.Rect != b.Rect || !bytes.Equal(a.Pix, b.Pix) {
+		t.Error("image decoded from memory differs from the one decoded from the file")
+	}
+
+	md, err := ExtractMetadataBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Camera.Make != "Golibraw" || md.Camera.Model != "Test" {
+		t.Errorf("camera = %v %v, want Golibraw Test", md.Camera.Make, md.Camera.Model)
+	}
+
+	if _, err = ImportRawBytes(nil); err == nil {
+		t.Error("ImportRawBytes of no data succeeded")
+	}
+	if _, err = ExtractMetadataBytes(nil); err == nil {
+		t.Error("ExtractMetadataBytes of no data succeeded")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	f, err := os.Open(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, testWidth, testHeight) {
+		t.Errorf("bounds = %v, want %vx%v", got, testWidth, testHeight)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	md, err := DecodeMetadata(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Sizes.RawWidth != testWidth || md.Sizes.RawHeight != testHeight {
+		t.Errorf("raw size = %vx%v, want %vx%v", md.Sizes.RawWidth, md.Sizes.RawHeight, testWidth, testHeight)
+	}
+
+	readErr := errors.New("read failed")
+	if _, err = Decode(iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("Decode of a failing reader: %v, want %v", err, readErr)
+	}
+	if _, err = DecodeMetadata(iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("DecodeMetadata of a failing reader: %v, want %v", err, readErr)
+	}
+}
+
+// 16-bit output keeps the full precision instead of 8-bit values scaled up
+func TestImportRaw16Bit(t *testing.T) {
+	img, err := ImportRawWithOptions(testDNG(t, "RGGB"), WithBitDepth(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrgba, ok := img.(*image.NRGBA64)
+	if !ok {
+		t.Fatalf("16-bit image type = %T, want *image.NRGBA64", img)
+	}
+	if nrgba.Rect != image.Rect(0, 0, testWidth, testHeight) {
+		t.Errorf("bounds = %v, want %vx%v", nrgba.Rect, testWidth, testHeight)
+	}
+	precise := false
+	for i := 0; i < len(nrgba.Pix); i += 2 {
+		precise = precise || nrgba.Pix[i] != nrgba.Pix[i+1]
+	}
+	if !precise {
+		t.Error("all 16-bit samples are 8-bit values scaled up")
+	}
+}
+
+// The libraw memory image is converted to image types directly, 8-bit RGB and 16-bit samples in native byte order
+func TestRawImgConversion(t *testing.T) {
+	rgb := rawImg{Width: 2, Height: 1, Bits: 8, Colors: 3, Data: []byte{1, 2, 3, 4, 5, 6}}
+	if got, want := rgb.rgba().Pix, []byte{1, 2, 3, 0xff, 4, 5, 6, 0xff}; !bytes.Equal(got, want) {
+		t.Errorf("RGBA pixels = %v, want %v", got, want)
+	}
+
+	data := make([]byte, 12)
+	for i, v := range []uint16{0x0102, 0x0304, 0x0506, 0x0708, 0x090a, 0x0b0c} {
+		binary.NativeEndian.PutUint16(data[2*i:], v)
+	}
+	rgb16 := rawImg{Width: 2, Height: 1, Bits: 16, Colors: 3, Data: data}
+	want := []byte{1, 2, 3, 4, 5, 6, 0xff, 0xff, 7, 8, 9, 10, 11, 12, 0xff, 0xff}
+	if got := rgb16.nrgba64().Pix; !bytes.Equal(got, want) {
+		t.Errorf("NRGBA64 pixels = %v, want %v", got, want)
+	}
+
+	// short data leaves the missing pixels black instead of reading past the buffer
+	short := rawImg{Width: 2, Height: 2, Bits: 8, Colors: 3, Data: []byte{1, 2, 3}}
+	if got := short.rgba().Pix; got[3] != 0xff || got[7] != 0 {
+		t.Errorf("RGBA pixels of short data = %v", got)
+	}
+}
+
+// The image is copied out of libraw memory and stays valid after the processor is closed
+func TestImageCopy(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Open(path); err == nil {
+		if err = processor.Unpack(); err == nil {
+			err = processor.Process()
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := processor.Image()
+	processor.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ImportRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, want.(*image.RGBA).Pix) {
+		t.Error("image changed after the processor was closed")
+	}
+}
+
+func BenchmarkImportRaw(b *testing.B) {
+	path := testDNG(b, "RGGB")
+	for i := 0; i < b.N; i++ {
+		if _, err := ImportRaw(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestImportRawContext(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	if _, err := ImportRawContext(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ImportRawContext(cancelled, path); !errors.Is(err, context.Canceled) {
+		t.Errorf("import with a cancelled context: %v, want %v", err, context.Canceled)
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := ImportRawContext(expired, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("import past the deadline: %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// cancelled while processing
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := WithProgress(func(stage string, iteration int, total int) { cancel() })
+	if _, err := ImportRawContext(ctx, path, progress); !errors.Is(err, context.Canceled) {
+		t.Errorf("import cancelled while processing: %v, want %v", err, context.Canceled)
+	}
+
+	output := filepath.Join(t.TempDir(), "out.ppm")
+	if err := ExportPPMContext(cancelled, path, output); !errors.Is(err, context.Canceled) {
+		t.Errorf("export with a cancelled context: %v, want %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("cancelled export left its output: %v", err)
+	}
+}
+
+func TestExportPPMTo(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportPPMTo(testDNG(t, "RGGB"), &buf); err != nil {
+		t.Fatalf("ExportPPMTo failed: %v", err)
+	}
+	header := fmt.Sprintf("P6\n%d %d\n255\n", testWidth, testHeight)
+	if !strings.HasPrefix(buf.String(), header) {
+		t.Fatalf("PPM starts with %q, want %q", buf.Bytes()[:min(buf.Len(), len(header))], header)
+	}
+	if got, want := buf.Len()-len(header), testWidth*testHeight*3; got != want {
+		t.Errorf("PPM has %v bytes of pixels, want %v", got, want)
+	}
+	if err := ExportPPMTo(filepath.Join(t.TempDir(), "missing.dng"), io.Discard); err == nil {
+		t.Errorf("ExportPPMTo succeeded on a missing file")
+	}
+}
+
+func TestExportTIFF(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	for _, bits := range []int{8, 16} {
+		output := filepath.Join(t.TempDir(), "out.tiff")
+		if err := ExportTIFF(path, output, WithBitDepth(bits)); err != nil {
+			t.Fatalf("ExportTIFF failed: %v", err)
+		}
+		f, err := os.Open(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r, ok := newTiffReader(f)
+		if !ok {
+			t.Fatalf("%v-bit export is not a TIFF file", bits)
+		}
+		entries, _ := r.ifd(r.first)
+		for tag, want := range map[uint16]int{tagImageWidth: testWidth, tagImageLength: testHeight, tagBitsPerSample: bits} {
+			e, ok := findEntry(entries, tag)
+			if !ok {
+				t.Errorf("%v-bit TIFF has no tag %#x", bits, tag)
+				continue
+			}
+			if got := int(r.uint(e, 0)); got != want {
+				t.Errorf("%v-bit TIFF tag %#x = %v, want %v", bits, tag, got, want)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	if err := Validate(path); err != nil {
+		t.Errorf("Validate of a valid file failed: %v", err)
+	}
+
+	// the sensor data ends in the middle of the strip
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(t.TempDir(), "truncated.dng")
+	if err := os.WriteFile(truncated, data[:len(data)-testWidth*testHeight], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(truncated); err == nil {
+		t.Errorf("Validate of a truncated file succeeded")
+	}
+	if err := Validate(filepath.Join("testdata", "corrupt", "garbage.raw")); !errors.Is(err, ErrUnsupportedFile) {
+		t.Errorf("Validate of garbage error = %v, want %v", err, ErrUnsupportedFile)
+	}
+}
+
+// Reads back the EXIF fields written to a DNG
+func TestMetadataFields(t *testing.T) {
+	metadata := testMetadata()
+	metadata.Artist = "Golibraw Artist"
+	metadata.Copyright = "(c) Golibraw"
+	metadata.Orientation = 6
+	metadata.ISO = 400
+	metadata.Shutter = 1.0 / 125
+	metadata.Aperture = 2.8
+	metadata.FocalLength = 50
+	var buf bytes.Buffer
+	if err := writeDNG(&buf, testImage("RGGB"), metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := ExtractMetadataBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ExtractMetadataBytes failed: %v", err)
+	}
+	if md.Artist != metadata.Artist || md.Copyright != metadata.Copyright {
+		t.Errorf("artist, copyright = %q, %q, want %q, %q", md.Artist, md.Copyright, metadata.Artist, metadata.Copyright)
+	}
+	if md.Orientation != metadata.Orientation || md.ISO != metadata.ISO {
+		t.Errorf("orientation, ISO = %v, %v, want %v, %v", md.Orientation, md.ISO, metadata.Orientation, metadata.ISO)
+	}
+	for name, got := range map[string][2]float64{
+		"shutter":      {md.Shutter, metadata.Shutter},
+		"aperture":     {md.Aperture, metadata.Aperture},
+		"focal length": {md.FocalLength, metadata.FocalLength},
+	} {
+		if got[0] < got[1]*0.999 || got[0] > got[1]*1.001 {
+			t.Errorf("%v = %v, want %v", name, got[0], got[1])
+		}
+	}
+}
+
+// The lens focal length and aperture at capture fall back to the EXIF values without maker notes
+func TestLensAtCapture(t *testing.T) {
+	metadata := testMetadata()
+	metadata.Aperture = 4
+	metadata.FocalLength = 35
+	md, err := ExtractMetadata(writeTestDNG(t, testImage("RGGB"), metadata))
+	if err != nil {
+		t.Fatalf("ExtractMetadata failed: %v", err)
+	}
+	if md.Lens.CurFocal != md.FocalLength || md.Lens.CurAp != md.Aperture || md.Lens.CurFocal <= 0 || md.Lens.CurAp <= 0 {
+		t.Errorf("lens at capture = %vmm f/%v, want the EXIF %vmm f/%v", md.Lens.CurFocal, md.Lens.CurAp, md.FocalLength, md.Aperture)
+	}
+
+	distance := binary.LittleEndian.AppendUint32(nil, 3)
+	distance = binary.LittleEndian.AppendUint32(distance, 2)
+	md, err = ExtractMetadataBytes(subIFDDNG(t, tagExifIFD, testEntry{tagSubjectDistance, typeRational, distance}))
+	if err != nil {
+		t.Fatalf("ExtractMetadataBytes failed: %v", err)
+	}
+	if md.FocusDistance != 1.5 {
+		t.Errorf("focus distance = %v, want 1.5", md.FocusDistance)
+	}
+}
+
+// The crop factor is derived from the 35mm equivalent focal length recorded in EXIF
+func TestCropFactor(t *testing.T) {
+	const tagFocalLength, tagFocalLength35mm = 0x920a, 0xa405
+	focal := binary.LittleEndian.AppendUint32(nil, 50)
+	focal = binary.LittleEndian.AppendUint32(focal, 1)
+	md, err := ExtractMetadataBytes(subIFDDNG(t, tagExifIFD,
+		testEntry{tagFocalLength, typeRational, focal},
+		shortEntry(tagFocalLength35mm, 75),
+	))
+	if err != nil {
+		t.Fatalf("ExtractMetadataBytes failed: %v", err)
+	}
+	if md.Lens.FocalLength35mm != 75 || md.CropFactor != 1.5 {
+		t.Errorf("35mm focal length, crop factor = %v, %v, want 75, 1.5", md.Lens.FocalLength35mm, md.CropFactor)
+	}
+	if got := md.EquivalentFocalLength(); got != 75 {
+		t.Errorf("EquivalentFocalLength() = %v, want 75", got)
+	}
+
+	if md, err := ExtractMetadata(testDNG(t, "RGGB")); err != nil || md.CropFactor != 0 || md.EquivalentFocalLength() != 0 {
+		t.Errorf("crop factor of a file without focal length = %v, %v, want 0", md.CropFactor, err)
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	// libraw flip bits of the EXIF orientations 1-8
+	for orientation, flip := range []int{0, 1, 3, 2, 4, 6, 7, 5} {
+		if got := exifOrientation(flip); got != orientation+1 {
+			t.Errorf("exifOrientation(%v) = %v, want %v", flip, got, orientation+1)
+		}
+	}
+}
+
+// Returns a test DNG with a GPS IFD of entries
+func gpsDNG(t *testing.T, entries ...testEntry) []byte {
+	t.Helper()
+	const tagGPSIFD = 0x8825
+	return subIFDDNG(t, tagGPSIFD, entries...)
+}
+
+// Returns a test DNG with an IFD of entries linked from the raw IFD by the tag, e.g. the EXIF or the GPS IFD
+func subIFDDNG(t *testing.T, tag uint16, entries ...testEntry) []byte {
+	t.Helper()
+	fields, strip := dngFields(testImage("RGGB"), testMetadata())
+	fields.long(tag, 0)
+	var buf bytes.Buffer
+	if err := fields.write(&buf, tagStripOffsets, strip); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for i := 0; i < int(binary.LittleEndian.Uint16(data[8:])); i++ {
+		if entry := data[10+12*i:]; binary.LittleEndian.Uint16(entry) == tag {
+			binary.LittleEndian.PutUint32(entry[8:], uint32(len(data)))
+		}
+	}
+	return appendIFD(data, entries...)
+}
+
+func TestGPS(t *testing.T) {
+	rationals := func(values ...uint32) []byte {
+		var data []byte
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint32(data, v)
+			data = binary.LittleEndian.AppendUint32(data, 1)
+		}
+		return data
+	}
+	data := gpsDNG(t,
+		testEntry{0x0000, typeByte, []byte{2, 2, 0, 0}},
+		testEntry{0x0001, typeASCII, []byte("N\x00")},
+		testEntry{0x0002, typeRational, rationals(47, 30, 36)},
+		testEntry{0x0003, typeASCII, []byte("W\x00")},
+		testEntry{0x0004, typeRational, rationals(19, 3, 0)},
+		testEntry{0x0005, typeByte, []byte{1}},
+		testEntry{0x0006, typeRational, rationals(120)},
+		testEntry{0x0007, typeRational, rationals(12, 30, 15)},
+		testEntry{0x0009, typeASCII, []byte("A\x00")},
+	)
+	md, err := ExtractMetadataBytes(data)
+	if err != nil {
+		t.Fatalf("ExtractMetadataBytes failed: %v", err)
+	}
+	if md.GPS == nil {
+		t.Fatal("GPS = nil, want the GPS data")
+	}
+	near := func(a, b float64) bool { return a-b < 1e-4 && b-a < 1e-4 }
+	if !near(md.GPS.Latitude, 47.51) || !near(md.GPS.Longitude, -19.05) || !near(md.GPS.Altitude, -120) {
+		t.Errorf("position = %v, %v, %v m, want 47.51, -19.05, -120 m", md.GPS.Latitude, md.GPS.Longitude, md.GPS.Altitude)
+	}
+	if want := 12*time.Hour + 30*time.Minute + 15*time.Second; md.GPS.Timestamp != want {
+		t.Errorf("timestamp = %v, want %v", md.GPS.Timestamp, want)
+	}
+	if md.GPS.Status != 'A' {
+		t.Errorf("status = %q, want 'A'", md.GPS.Status)
+	}
+
+	if md, err := ExtractMetadata(testDNG(t, "RGGB")); err != nil || md.GPS != nil {
+		t.Errorf("GPS of a file without GPS data = %+v, %v, want nil", md.GPS, err)
+	}
+}
+
+func TestLibrawFlip(t *testing.T) {
+	for orientation := 1; orientation <= 8; orientation++ {
+		if got := exifOrientation(librawFlip(orientation)); got != orientation {
+			t.Errorf("exifOrientation(librawFlip(%v)) = %v", orientation, got)
+		}
+	}
+	for _, orientation := range []int{0, 9, -1} {
+		if got := librawFlip(orientation); got != 0 {
+			t.Errorf("librawFlip(%v) = %v, want 0", orientation, got)
+		}
+	}
+}
+
+func TestSizes(t *testing.T) {
+	metadata := testMetadata()
+	metadata.Orientation = 6
+	for name, test := range map[string]struct {
+		path          string
+		width, height int
+	}{
+		"landscape": {testDNG(t, "RGGB"), testWidth, testHeight},
+		"portrait":  {writeTestDNG(t, testImage("RGGB"), metadata), testHeight, testWidth},
+	} {
+		md, err := ExtractMetadata(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes := md.Sizes
+		if sizes.RawWidth != testWidth || sizes.RawHeight != testHeight || sizes.Width != testWidth || sizes.Height != testHeight {
+			t.Errorf("%v sensor sizes = %+v, want %vx%v", name, sizes, testWidth, testHeight)
+		}
+		if sizes.OutputWidth != test.width || sizes.OutputHeight != test.height {
+			t.Errorf("%v output size = %vx%v, want %vx%v", name, sizes.OutputWidth, sizes.OutputHeight, test.width, test.height)
+		}
+		if want := float64(test.width) / float64(test.height); sizes.AspectRatio != want {
+			t.Errorf("%v aspect ratio = %v, want %v", name, sizes.AspectRatio, want)
+		}
+	}
+}
+
+func TestOutputSize(t *testing.T) {
+	tests := []struct {
+		width, height int
+		pixelAspect   float64
+		flip          int
+		wantW, wantH  int
+	}{
+		{100, 50, 1, 0, 100, 50},
+		{100, 50, 0, 0, 100, 50},
+		{100, 50, 2, 0, 200, 50},
+		{100, 50, 0.5, 0, 100, 100},
+		{100, 50, 1, 6, 50, 100},
+		{100, 50, 2, 5, 50, 200},
+	}
+	for _, tt := range tests {
+		if w, h := outputSize(tt.width, tt.height, tt.pixelAspect, tt.flip); w != tt.wantW || h != tt.wantH {
+			t.Errorf("outputSize(%v, %v, %v, %v) = %vx%v, want %vx%v", tt.width, tt.height, tt.pixelAspect, tt.flip, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	md, err := ExtractMetadata(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calibration := md.Calibration
+	if calibration.Maximum != 0xfff || calibration.Black != 0 {
+		t.Errorf("levels = %v - %v, want 0 - 4095", calibration.Black, calibration.Maximum)
+	}
+	// the multipliers are read back from the AsShotNeutral of testMetadata, relative to green
+	mul := calibration.CamMul
+	if mul[1] <= 0 {
+		t.Fatalf("camera multipliers = %v, want the ones written", mul)
+	}
+	want := testMetadata().Calibration.CamMul
+	for _, c := range []int{0, 2} {
+		if got := mul[c] / mul[1]; got < want[c]*0.99 || got > want[c]*1.01 {
+			t.Errorf("camera multiplier %v relative to green = %v, want %v", c, got, want[c])
+		}
+	}
+	if calibration.PreMul[0] <= 0 || calibration.PreMul[1] <= 0 || calibration.PreMul[2] <= 0 {
+		t.Errorf("daylight multipliers = %v, want positive", calibration.PreMul)
+	}
+}
+
+// The sRGB primaries of testMetadata give an identity camera to sRGB matrix
+func TestColorMatrices(t *testing.T) {
+	md, err := ExtractMetadata(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calibration := md.Calibration
+	if len(calibration.CamXYZ) < 3 || len(calibration.RGBCam) != 3 {
+		t.Fatalf("matrices of %v and %v rows, want 4 x 3 and 3 x 4", len(calibration.CamXYZ), len(calibration.RGBCam))
+	}
+	near := func(a, b, tolerance float64) bool { return a-b < tolerance && b-a < tolerance }
+	for i, row := range testMetadata().Calibration.CamXYZ {
+		for j, want := range row {
+			if got := calibration.CamXYZ[i][j]; !near(got, want, 1e-3) {
+				t.Errorf("CamXYZ[%v][%v] = %v, want %v", i, j, got, want)
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if got := calibration.RGBCam[i][j]; !near(got, want, 1e-2) {
+				t.Errorf("RGBCam[%v][%v] = %v, want %v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestImportRawPreview(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	for _, test := range []struct {
+		maxDim int
+		want   image.Rectangle
+	}{
+		// half size processing, then scaled down
+		{40, image.Rect(0, 0, 40, 26)},
+		{testWidth / 2, image.Rect(0, 0, testWidth/2, testHeight/2)},
+		// full size processing
+		{60, image.Rect(0, 0, 60, 40)},
+		{0, image.Rect(0, 0, testWidth, testHeight)},
+		{2 * testWidth, image.Rect(0, 0, testWidth, testHeight)},
+	} {
+		img, err := ImportRawPreview(path, test.maxDim)
+		if err != nil {
+			t.Fatalf("ImportRawPreview with %v failed: %v", test.maxDim, err)
+		}
+		if got := img.Bounds(); got != test.want {
+			t.Errorf("bounds with %v = %v, want %v", test.maxDim, got, test.want)
+		}
+	}
+}
+
+func TestExtractICCProfile(t *testing.T) {
+	const tagICCProfile = 0x8773
+	profile := []byte("synthetic ICC profile of the test")
+	fields, strip := dngFields(testImage("RGGB"), testMetadata())
+	fields.add(tagICCProfile, typeUndefined, len(profile), profile)
+	var buf bytes.Buffer
+	if err := fields.write(&buf, tagStripOffsets, strip); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "profile.dng")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExtractICCProfile(path)
+	if err != nil {
+		t.Fatalf("ExtractICCProfile failed: %v", err)
+	}
+	if !bytes.Equal(got, profile) {
+		t.Errorf("profile = %q, want %q", got, profile)
+	}
+	if got, err := ExtractICCProfile(testDNG(t, "RGGB")); err != nil || got != nil {
+		t.Errorf("profile of a file without profile = %q, %v, want nil", got, err)
+	}
+}
+
+func TestExtractXMP(t *testing.T) {
+	const tagXMP = 0x02bc
+	packet := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF/></x:xmpmeta>`)
+	fields, strip := dngFields(testImage("RGGB"), testMetadata())
+	fields.add(tagXMP, typeByte, len(packet), packet)
+	var buf bytes.Buffer
+	if err := fields.write(&buf, tagStripOffsets, strip); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "xmp.dng")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExtractXMP(path)
+	if err != nil {
+		t.Fatalf("ExtractXMP failed: %v", err)
+	}
+	if !bytes.Equal(bytes.TrimRight(got, "\x00"), packet) {
+		t.Errorf("XMP = %q, want %q", got, packet)
+	}
+	if got, err := ExtractXMP(testDNG(t, "RGGB")); err != nil || got != nil {
+		t.Errorf("XMP of a file without packet = %q, %v, want nil", got, err)
+	}
+}
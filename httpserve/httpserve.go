@@ -0,0 +1,271 @@
+//go:build cgo
+
+// Package httpserve serves resized JPEG previews and the metadata of the RAW image files under a root directory over
+// HTTP, e.g. for self-hosted photo browsers.
+//
+// The path of the request is the path of the RAW file relative to the root directory:
+//
+//	GET /2024/DSC_0001.NEF?size=1024     JPEG preview fitting in 1024 x 1024 pixels
+//	GET /2024/DSC_0001.NEF?thumb=1       the embedded thumbnail, without rendering the RAW data
+//	GET /2024/DSC_0001.NEF?format=json   the metadata as JSON
+//
+// Responses carry Last-Modified and ETag headers derived from the file and the request parameters, conditional
+// requests of browsers and proxies are answered with 304 Not Modified without decoding the file. Symbolic links are
+// only followed to files under the root directory, and errors are answered with their status text only, the details
+// are logged.
+package httpserve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	raw "github.com/inokone/golibraw"
+)
+
+// Default limits of a Handler
+const (
+	DefaultSize    = 1024
+	DefaultMaxSize = 4096
+	DefaultMaxAge  = 24 * time.Hour
+)
+
+// Handler serves previews and metadata of the RAW files under Root, see the package documentation.
+type Handler struct {
+	// Directory of the served RAW files, requests cannot reach files outside of it
+	Root string
+	// Size of the previews without a size parameter, DefaultSize if not set
+	Size int
+	// Largest preview size a request may ask for, DefaultMaxSize if not set
+	MaxSize int
+	// JPEG quality of the previews, the default JPEG quality if not set
+	Quality int
+	// Value of the max-age directive of the Cache-Control header, DefaultMaxAge if not set
+	MaxAge time.Duration
+	// Processing options of the previews
+	Options []raw.Option
+	// Number of files decoded at the same time, the number of CPUs if not set. Further requests wait for a slot.
+	MaxDecodes int
+	// Logger of the failed requests, slog.Default() if not set
+	Logger *slog.Logger
+
+	once    sync.Once
+	decodes chan struct{}
+}
+
+// Creates a handler serving the RAW files under root with the default settings.
+func New(root string) *Handler {
+	return &Handler{Root: root}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, info, err := h.open(r.URL.Path)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	var variant string
+	var render func() ([]byte, string, error)
+	switch {
+	case query.Get("format") == "json":
+		variant = "json"
+		render = func() ([]byte, string, error) { return h.metadata(name) }
+	case query.Get("thumb") != "":
+		variant = "thumb"
+		render = func() ([]byte, string, error) { return h.thumbnail(name) }
+	default:
+		size, err := h.size(query.Get("size"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		variant = strconv.Itoa(size)
+		render = func() ([]byte, string, error) { return h.preview(name, size) }
+	}
+
+	etag := fmt.Sprintf(`"%x-%x-%v"`, info.ModTime().UnixNano(), info.Size(), variant)
+	if notModified(r, etag, info.ModTime()) {
+		h.cacheHeaders(w, etag, info)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, contentType, err := h.decode(r, render)
+	if err != nil {
+		h.logger().Warn("failed to serve RAW file", "path", r.URL.Path, "variant", variant, "error", err)
+		code := status(err)
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	h.cacheHeaders(w, etag, info)
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(data))
+}
+
+// Sets the validators and the caching policy of a successful response
+func (h *Handler) cacheHeaders(w http.ResponseWriter, etag string, info fs.FileInfo) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge().Seconds())))
+}
+
+// Renders once one of the MaxDecodes slots is free, or fails when the request is cancelled while waiting
+func (h *Handler) decode(r *http.Request, render func() ([]byte, string, error)) ([]byte, string, error) {
+	h.once.Do(func() {
+		slots := h.MaxDecodes
+		if slots <= 0 {
+			slots = runtime.NumCPU()
+		}
+		h.decodes = make(chan struct{}, slots)
+	})
+	select {
+	case h.decodes <- struct{}{}:
+		defer func() { <-h.decodes }()
+		return render()
+	case <-r.Context().Done():
+		return nil, "", r.Context().Err()
+	}
+}
+
+func (h *Handler) logger() *slog.Logger {
+	if h.Logger != nil {
+		return h.Logger
+	}
+	return slog.Default()
+}
+
+// Resolves the URL path to a regular file under Root. Symbolic links are resolved, links leading out of Root are
+// reported as missing files.
+func (h *Handler) open(urlPath string) (string, fs.FileInfo, error) {
+	rel := path.Clean("/" + urlPath)[1:]
+	if rel == "" || !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", nil, fs.ErrNotExist
+	}
+	root, err := filepath.EvalSymlinks(h.Root)
+	if err != nil {
+		return "", nil, err
+	}
+	name, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", nil, err
+	}
+	if inRoot, err := filepath.Rel(root, name); err != nil || !filepath.IsLocal(inRoot) {
+		return "", nil, fs.ErrNotExist
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return "", nil, fs.ErrNotExist
+	}
+	return name, info, nil
+}
+
+func (h *Handler) size(param string) (int, error) {
+	if param == "" {
+		if h.Size > 0 {
+			return h.Size, nil
+		}
+		return DefaultSize, nil
+	}
+	size, err := strconv.Atoi(param)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid size [%v]", param)
+	}
+	maxSize := h.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return min(size, maxSize), nil
+}
+
+func (h *Handler) maxAge() time.Duration {
+	if h.MaxAge > 0 {
+		return h.MaxAge
+	}
+	return DefaultMaxAge
+}
+
+func (h *Handler) preview(name string, size int) ([]byte, string, error) {
+	img, err := raw.ImportRawPreview(name, size, h.Options...)
+	if err != nil {
+		return nil, "", err
+	}
+	quality := h.Quality
+	if quality <= 0 {
+		quality = jpeg.DefaultQuality
+	}
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, "", fmt.Errorf("encoding preview failed with [%w]", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+func (h *Handler) thumbnail(name string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := raw.ExtractThumbnailTo(name, &buf); err != nil {
+		return nil, "", err
+	}
+	if http.DetectContentType(buf.Bytes()) == "image/jpeg" {
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	return buf.Bytes(), "image/x-portable-pixmap", nil
+}
+
+func (h *Handler) metadata(name string) ([]byte, string, error) {
+	metadata, err := raw.ExtractMetadata(name)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "application/json", nil
+}
+
+// Reports whether the conditional headers of r match the response, checked before rendering to skip the decoding
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		return match == etag || match == "*"
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modTime.Truncate(time.Second).After(since)
+}
+
+// Maps the errors of the package to HTTP status codes: files libraw cannot read are unsupported media
+func status(err error) int {
+	var lrErr *raw.Error
+	var dataErr *raw.DataError
+	switch {
+	case errors.Is(err, raw.ErrOutOfMemory), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &lrErr), errors.As(err, &dataErr), errors.Is(err, raw.ErrUnsupportedByLibraw):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
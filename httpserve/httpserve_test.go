@@ -0,0 +1,49 @@
+//go:build cgo
+
+package httpserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeErrors(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "link.NEF")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "plain.NEF"), []byte("not a RAW file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	handler := New(root)
+
+	for _, test := range []struct {
+		path   string
+		status int
+	}{
+		{"/link.NEF?format=json", http.StatusNotFound},
+		{"/../" + filepath.Base(outside) + "/secret.txt?format=json", http.StatusNotFound},
+		{"/plain.NEF?format=json", http.StatusUnsupportedMediaType},
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if recorder.Code != test.status {
+			t.Errorf("GET %v: status = %v, want %v", test.path, recorder.Code, test.status)
+		}
+		if body := recorder.Body.String(); strings.Contains(body, root) || strings.Contains(body, "secret") {
+			t.Errorf("GET %v: response %q leaks the file system", test.path, body)
+		}
+		if etag := recorder.Header().Get("ETag"); etag != "" {
+			t.Errorf("GET %v: failed response has ETag %v", test.path, etag)
+		}
+	}
+}
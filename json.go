@@ -0,0 +1,28 @@
+package golibraw
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Serializes the metadata with the snake case field names of the json struct tags. Besides the fields, the capture
+// time is emitted as "time" in RFC 3339 format (omitted if unknown), the raw type as "raw_type" by its name and the
+// warnings as a "warnings" list of names.
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	// the alias drops the methods of Metadata, avoiding infinite recursion
+	type metadata Metadata
+	out := struct {
+		metadata
+		Time     string   `json:"time,omitempty"`
+		RawType  string   `json:"raw_type"`
+		Warnings []string `json:"warnings"`
+	}{
+		metadata: metadata(m),
+		RawType:  m.RawType.String(),
+		Warnings: m.Warnings.names(),
+	}
+	if captured := m.Time(); !captured.IsZero() {
+		out.Time = captured.Format(time.RFC3339)
+	}
+	return json.Marshal(out)
+}
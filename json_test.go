@@ -0,0 +1,73 @@
+package golibraw
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMetadataJSON(t *testing.T) {
+	wall := time.Date(2024, 5, 1, 10, 20, 30, 0, time.Local)
+	m := Metadata{
+		Timestamp:  wall.Unix(),
+		TimeOffset: "+02:00",
+		Width:      6000,
+		Camera:     Camera{Make: "Sony", Model: "ILCE-7RM4"},
+		Lens:       Lens{MaxAp4MinFocal: 2.8},
+		ISO:        400,
+		RawType:    RawTypeBayer,
+		CFA:        CFA{Pattern: "RGGB", Width: 2, Height: 2, ColorDesc: "RGBG"},
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	for key, want := range map[string]any{
+		"time":        "2024-05-01T10:20:30+02:00",
+		"time_offset": "+02:00",
+		"width":       6000.0,
+		"iso":         400.0,
+		"raw_type":    "bayer",
+	} {
+		if got := fields[key]; got != want {
+			t.Errorf("%q = %v, want %v", key, got, want)
+		}
+	}
+	if warnings, ok := fields["warnings"].([]any); !ok || len(warnings) != 0 {
+		t.Errorf("warnings = %v, want an empty list", fields["warnings"])
+	}
+	for _, key := range []string{"gps", "Warnings", "RawType"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("%q is emitted, want it omitted", key)
+		}
+	}
+	if camera, _ := fields["camera"].(map[string]any); camera["model"] != "ILCE-7RM4" {
+		t.Errorf("camera = %v, want the model under \"model\"", fields["camera"])
+	}
+	if lens, _ := fields["lens"].(map[string]any); lens["max_aperture_min_focal"] != 2.8 {
+		t.Errorf("lens = %v, want the aperture under \"max_aperture_min_focal\"", fields["lens"])
+	}
+	if cfa, _ := fields["cfa"].(map[string]any); cfa["pattern"] != "RGGB" || cfa["color_desc"] != "RGBG" {
+		t.Errorf("cfa = %v, want the pattern and the color description", fields["cfa"])
+	}
+
+	// the fields decode back into Metadata with the same names
+	var decoded Metadata
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal into Metadata failed: %v", err)
+	}
+	if decoded.Timestamp != m.Timestamp || decoded.Camera != m.Camera || decoded.CFA != m.CFA {
+		t.Errorf("decoded metadata = %+v, want %+v", decoded, m)
+	}
+
+	data, _ = json.Marshal(Metadata{})
+	fields = nil
+	if err := json.Unmarshal(data, &fields); err != nil || fields["time"] != nil {
+		t.Errorf("time of metadata without timestamp = %v, want it omitted", fields["time"])
+	}
+}
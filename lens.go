@@ -0,0 +1,20 @@
+package golibraw
+
+// Returns the hyperfocal distance in meters for the focal length and aperture at capture, with the circle of confusion
+// coc given in millimeters (e.g. 0.03 for full frame sensors). Zero is returned if the focal length or aperture is unknown.
+func (m Metadata) HyperfocalDistance(coc float64) float64 {
+	focal, aperture := m.Lens.CurFocal, m.Lens.CurAp
+	if focal <= 0 || aperture <= 0 || coc <= 0 {
+		return 0
+	}
+	return (focal*focal/(aperture*coc) + focal) / 1000
+}
+
+// Returns the 35mm equivalent of the focal length at capture, e.g. for displaying "24mm (36mm equiv.)".
+// Zero is returned if neither the equivalent focal length nor the sensor format is known.
+func (m Metadata) EquivalentFocalLength() float64 {
+	if m.Lens.FocalLength35mm > 0 {
+		return m.Lens.FocalLength35mm
+	}
+	return m.Lens.CurFocal * m.CropFactor
+}
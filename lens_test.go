@@ -0,0 +1,42 @@
+package golibraw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHyperfocalDistance(t *testing.T) {
+	md := Metadata{Lens: Lens{CurFocal: 50, CurAp: 8}}
+	// 50mm at f/8 on full frame: 50^2 / (8 * 0.03) + 50 = 10466.7mm
+	if got := md.HyperfocalDistance(0.03); math.Abs(got-10.4667) > 1e-3 {
+		t.Errorf("HyperfocalDistance(0.03) = %v, want 10.4667", got)
+	}
+	for _, test := range []struct {
+		lens Lens
+		coc  float64
+	}{
+		{Lens{CurAp: 8}, 0.03},
+		{Lens{CurFocal: 50}, 0.03},
+		{Lens{CurFocal: 50, CurAp: 8}, 0},
+	} {
+		if got := (Metadata{Lens: test.lens}).HyperfocalDistance(test.coc); got != 0 {
+			t.Errorf("HyperfocalDistance(%v) of %+v = %v, want 0", test.coc, test.lens, got)
+		}
+	}
+}
+
+func TestEquivalentFocalLength(t *testing.T) {
+	for _, test := range []struct {
+		md   Metadata
+		want float64
+	}{
+		{Metadata{Lens: Lens{CurFocal: 24, FocalLength35mm: 36}, CropFactor: 1.6}, 36},
+		{Metadata{Lens: Lens{CurFocal: 24}, CropFactor: 1.5}, 36},
+		{Metadata{Lens: Lens{CurFocal: 24}}, 0},
+		{Metadata{CropFactor: 2}, 0},
+	} {
+		if got := test.md.EquivalentFocalLength(); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("EquivalentFocalLength() of %+v = %v, want %v", test.md.Lens, got, test.want)
+		}
+	}
+}
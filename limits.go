@@ -0,0 +1,27 @@
+package golibraw
+
+import (
+	"sync/atomic"
+)
+
+// Memory limit of libraw's own default, in megabytes
+const defaultMemoryLimit = 2048
+
+var memoryLimit atomic.Int64
+
+func init() {
+	memoryLimit.Store(defaultMemoryLimit)
+}
+
+// Sets the memory limit in megabytes of the processors created afterwards, protecting the process from corrupt or
+// malicious files claiming huge sensor sizes. Images exceeding the limit fail with ErrMemoryLimit. 2048 MB by default.
+func SetDefaultMemoryLimit(mb int) {
+	if mb > 0 {
+		memoryLimit.Store(int64(mb))
+	}
+}
+
+// Returns the memory limit in megabytes of new processors, see SetDefaultMemoryLimit.
+func DefaultMemoryLimit() int {
+	return int(memoryLimit.Load())
+}
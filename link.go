@@ -0,0 +1,6 @@
+//go:build !(golibraw_static && linux)
+
+package golibraw
+
+// #cgo LDFLAGS: -lraw_r
+import "C"
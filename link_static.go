@@ -0,0 +1,10 @@
+//go:build golibraw_static && linux
+
+package golibraw
+
+// libraw is linked statically, its dependencies (as of the default libraw build: OpenMP, lcms2, libjpeg, zlib and
+// the C++ runtime) dynamically. A libraw source tree built in third_party/LibRaw takes precedence over the system one.
+
+// #cgo CFLAGS: -I${SRCDIR}/third_party/LibRaw
+// #cgo LDFLAGS: -L${SRCDIR}/third_party/LibRaw/lib/.libs -Wl,-Bstatic -lraw_r -Wl,-Bdynamic -lstdc++ -lgomp -llcms2 -ljpeg -lz -lm
+import "C"
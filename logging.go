@@ -0,0 +1,83 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"image"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// Sets the logger of the processors created afterwards and of the package level functions. The package reports
+// the duration of every libraw stage at debug level, libraw warnings and corrupt data at warning level and
+// the fallbacks it takes (e.g. rendering the RAW data when there is no usable thumbnail) at info level.
+// Logging is disabled by default, nil disables it again.
+func SetLogger(logger *slog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// Sets the logger of the processor, see SetLogger. nil disables logging.
+func (p *Processor) SetLogger(logger *slog.Logger) {
+	p.logger = logger
+}
+
+// Logs a message with the logger of the processor, the context of the processor is passed to the handler
+func (p *Processor) log(level slog.Level, msg string, args ...any) {
+	if p.logger == nil {
+		return
+	}
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p.logger.Log(ctx, level, msg, args...)
+}
+
+// Reports the duration and the result of a libraw stage started at start to the metrics callback and the logger,
+// along with the warnings and data errors libraw reported since the previous stage
+func (p *Processor) endStage(stage string, start time.Time, err error, args ...any) {
+	duration := time.Since(start)
+	p.observe(stage, duration, err)
+	if p.logger == nil {
+		return
+	}
+	args = append([]any{"stage", stage, "duration", duration, "bytes", p.dataSize}, args...)
+	if p.path != "" {
+		args = append(args, "path", p.path)
+	}
+	if err != nil {
+		p.log(slog.LevelWarn, "libraw stage failed", append(args, "error", err)...)
+		return
+	}
+	p.log(slog.LevelDebug, "libraw stage", args...)
+
+	if warnings := p.Warnings() &^ p.logged; warnings != 0 {
+		p.logged |= warnings
+		p.log(slog.LevelWarn, "libraw warnings", "stage", stage, "path", p.path, "warnings", warnings.String())
+	}
+	if p.dataErr != nil && !p.loggedDataErr {
+		p.loggedDataErr = true
+		p.log(slog.LevelWarn, "corrupt image data", "stage", stage, "path", p.path, "offset", p.dataErr.Offset)
+	}
+}
+
+// Logs a fallback decision of a package level function with the package logger
+func logFallback(msg string, args ...any) {
+	if logger := defaultLogger.Load(); logger != nil {
+		logger.Info(msg, args...)
+	}
+}
+
+// Returns the embedded preview of a RAW image file, or a half size render if there is no usable preview
+func previewOf(path string) (image.Image, error) {
+	img, err := ImportThumbnail(path)
+	if err == nil {
+		return img, nil
+	}
+	logFallback("no usable thumbnail, rendering RAW data", "path", path, "error", err)
+	return ImportRawWithOptions(path, WithHalfSize())
+}
@@ -0,0 +1,76 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessorLogger(t *testing.T) {
+	var buf bytes.Buffer
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	processor.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	path := testDNG(t, "RGGB")
+	if err = processor.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Unpack(); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Process(); err != nil {
+		t.Fatal(err)
+	}
+	for _, stage := range []string{"open", "unpack", "process"} {
+		if want := `msg="libraw stage" stage=` + stage; !strings.Contains(buf.String(), want) {
+			t.Errorf("log has no %q:\n%v", want, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "path="+path) {
+		t.Errorf("log has no path of the image:\n%v", buf.String())
+	}
+
+	buf.Reset()
+	processor.Recycle()
+	if err = processor.Open(filepath.Join("testdata", "corrupt", "garbage.raw")); err == nil {
+		t.Fatal("Open of garbage succeeded")
+	}
+	if !strings.Contains(buf.String(), `level=WARN msg="libraw stage failed" stage=open`) {
+		t.Errorf("log has no failed open stage:\n%v", buf.String())
+	}
+
+	buf.Reset()
+	processor.SetLogger(nil)
+	processor.Recycle()
+	if err = processor.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("processor without logger logged:\n%v", buf.String())
+	}
+}
+
+func TestLogFallback(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer SetLogger(nil)
+
+	if _, err := previewOf(testDNG(t, "RGGB")); err != nil {
+		t.Fatalf("previewOf failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `level=INFO msg="no usable thumbnail, rendering RAW data"`) {
+		t.Errorf("log has no fallback to rendering:\n%v", buf.String())
+	}
+	// debug messages of the stages are filtered by the handler
+	if strings.Contains(buf.String(), "libraw stage") {
+		t.Errorf("log has debug messages over the info level:\n%v", buf.String())
+	}
+}
@@ -0,0 +1,179 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+//
+// // OM Digital Solutions cameras are reported separately from Olympus since libraw 0.21
+// #if !LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// #define LIBRAW_CAMERAMAKER_OmDigital LIBRAW_CAMERAMAKER_TheLastOne
+// #endif
+import "C"
+
+import "strings"
+
+func temperaturesOf(common *C.libraw_metadata_common_t) Temperatures {
+	// libraw marks the missing values with absolute zero
+	temperature := func(values ...C.float) *float64 {
+		for _, v := range values {
+			if v > -273 {
+				t := float64(v)
+				return &t
+			}
+		}
+		return nil
+	}
+	return Temperatures{
+		Camera:  temperature(common.CameraTemperature),
+		Sensor:  temperature(common.SensorTemperature, common.SensorTemperature2),
+		Lens:    temperature(common.LensTemperature),
+		Battery: temperature(common.BatteryTemperature),
+		Ambient: temperature(common.AmbientTemperature, common.exifAmbientTemperature),
+	}
+}
+
+func makerNotesOf(librawProcessor *C.libraw_data_t) MakerNotes {
+	shooting := &librawProcessor.shootinginfo
+	common := &librawProcessor.makernotes.common
+	notes := MakerNotes{
+		Shooting: ShootingInfo{
+			DriveMode:          int(shooting.DriveMode),
+			FocusMode:          int(shooting.FocusMode),
+			MeteringMode:       int(shooting.MeteringMode),
+			AFPoint:            int(shooting.AFPoint),
+			ExposureMode:       int(shooting.ExposureMode),
+			ExposureProgram:    int(shooting.ExposureProgram),
+			ImageStabilization: int(shooting.ImageStabilization),
+			BodySerial:         C.GoString(&shooting.BodySerial[0]),
+			InternalBodySerial: C.GoString(&shooting.InternalBodySerial[0]),
+		},
+		Common: CommonNotes{
+			FlashEC:       float64(common.FlashEC),
+			FlashGN:       float64(common.FlashGN),
+			RealISO:       float64(common.real_ISO),
+			ExposureIndex: float64(common.exifExposureIndex),
+			Firmware:      C.GoString(&common.firmware[0]),
+		},
+	}
+
+	switch librawProcessor.idata.maker_index {
+	case C.LIBRAW_CAMERAMAKER_Canon:
+		notes.Canon = canonNotesOf(&librawProcessor.makernotes.canon)
+	case C.LIBRAW_CAMERAMAKER_Nikon:
+		notes.Nikon = nikonNotesOf(&librawProcessor.makernotes.nikon)
+	case C.LIBRAW_CAMERAMAKER_Sony, C.LIBRAW_CAMERAMAKER_Minolta:
+		notes.Sony = sonyNotesOf(&librawProcessor.makernotes.sony)
+	case C.LIBRAW_CAMERAMAKER_Fujifilm:
+		notes.Fuji = fujiNotesOf(&librawProcessor.makernotes.fuji)
+	case C.LIBRAW_CAMERAMAKER_Olympus, C.LIBRAW_CAMERAMAKER_OmDigital:
+		notes.Olympus = olympusNotesOf(&librawProcessor.makernotes.olympus)
+	case C.LIBRAW_CAMERAMAKER_Pentax, C.LIBRAW_CAMERAMAKER_Ricoh:
+		notes.Pentax = pentaxNotesOf(&librawProcessor.makernotes.pentax)
+	}
+	return notes
+}
+
+func canonNotesOf(canon *C.libraw_canon_makernotes_t) *CanonNotes {
+	return &CanonNotes{
+		ImageStabilization:    int(canon.ImageStabilization),
+		ContinuousDrive:       int(canon.ContinuousDrive),
+		ExposureMode:          int(canon.ExposureMode),
+		MeteringMode:          int(canon.MeteringMode),
+		FlashMode:             int(canon.FlashMode),
+		AFMicroAdjMode:        int(canon.AFMicroAdjMode),
+		AFMicroAdjValue:       float64(canon.AFMicroAdjValue),
+		HighlightTonePriority: int(canon.HighlightTonePriority),
+		AutoLightingOptimizer: int(canon.AutoLightingOptimizer),
+		Quality:               int(canon.Quality),
+		RecordMode:            int(canon.RecordMode),
+		SRAWQuality:           int(canon.SRAWQuality),
+		CanonLog:              int(canon.CanonLog),
+	}
+}
+
+func nikonNotesOf(nikon *C.libraw_nikon_makernotes_t) *NikonNotes {
+	return &NikonNotes{
+		ActiveDLighting:    int(nikon.ActiveDLighting),
+		ShootingMode:       int(nikon.ShootingMode),
+		VibrationReduction: int(nikon.VibrationReduction),
+		VRMode:             int(nikon.VRMode),
+		FlashSetting:       C.GoString(&nikon.FlashSetting[0]),
+		FlashType:          C.GoString(&nikon.FlashType[0]),
+		NEFCompression:     int(nikon.NEFCompression),
+		ExposureProgram:    int(nikon.ExposureProgram),
+		AFFineTune:         int(nikon.AFFineTune),
+		AFFineTuneAdj:      int(nikon.AFFineTuneAdj),
+		RollAngle:          float64(nikon.RollAngle),
+		PitchAngle:         float64(nikon.PitchAngle),
+		YawAngle:           float64(nikon.YawAngle),
+	}
+}
+
+func sonyNotesOf(sony *C.libraw_sony_info_t) *SonyNotes {
+	return &SonyNotes{
+		AFAreaMode:                    int(sony.AFAreaMode),
+		AFPointSelected:               int(sony.AFPointSelected),
+		AFTracking:                    int(sony.AFTracking),
+		AFType:                        int(sony.AFType),
+		FocusPosition:                 int(sony.FocusPosition),
+		AFMicroAdjOn:                  sony.AFMicroAdjOn > 0,
+		AFMicroAdjValue:               int(sony.AFMicroAdjValue),
+		LongExposureNoiseReduction:    int(sony.LongExposureNoiseReduction),
+		HighISONoiseReduction:         int(sony.HighISONoiseReduction),
+		ElectronicFrontCurtainShutter: int(sony.ElectronicFrontCurtainShutter),
+		ShotNumberSincePowerUp:        int(sony.ShotNumberSincePowerUp),
+		RawFileType:                   int(sony.SonyRawFileType),
+		Quality:                       int(sony.Quality),
+	}
+}
+
+func fujiNotesOf(fuji *C.libraw_fuji_info_t) *FujiNotes {
+	return &FujiNotes{
+		FilmMode:                int(fuji.FilmMode),
+		DynamicRange:            int(fuji.DynamicRange),
+		DynamicRangeSetting:     int(fuji.DynamicRangeSetting),
+		DevelopmentDynamicRange: int(fuji.DevelopmentDynamicRange),
+		FocusMode:               int(fuji.FocusMode),
+		AFMode:                  int(fuji.AFMode),
+		FocusPixel:              [2]int{int(fuji.FocusPixel[0]), int(fuji.FocusPixel[1])},
+		ImageStabilization:      [3]int{int(fuji.ImageStabilization[0]), int(fuji.ImageStabilization[1]), int(fuji.ImageStabilization[2])},
+		ShutterType:             int(fuji.ShutterType),
+		DriveMode:               int(fuji.DriveMode),
+		Rating:                  int(fuji.Rating),
+		SensorID:                C.GoString(&fuji.SensorID[0]),
+		RAFVersion:              C.GoString(&fuji.RAFVersion[0]),
+		ImageCount:              int(fuji.ImageCount),
+	}
+}
+
+func olympusNotesOf(olympus *C.libraw_olympus_makernotes_t) *OlympusNotes {
+	notes := &OlympusNotes{
+		CameraType:   strings.TrimRight(C.GoStringN(&olympus.CameraType[0], C.int(len(olympus.CameraType))), "\x00"),
+		FocusMode:    [2]int{int(olympus.FocusMode[0]), int(olympus.FocusMode[1])},
+		AutoFocus:    int(olympus.AutoFocus),
+		AFPoint:      int(olympus.AFPoint),
+		AFResult:     int(olympus.AFResult),
+		LiveND:       olympus.isLiveND > 0,
+		LiveNDFactor: int(olympus.LiveNDfactor),
+	}
+	for i := range notes.DriveMode {
+		notes.DriveMode[i] = int(olympus.DriveMode[i])
+	}
+	return notes
+}
+
+func pentaxNotesOf(pentax *C.libraw_pentax_makernotes_t) *PentaxNotes {
+	notes := &PentaxNotes{
+		FocusMode:       int(pentax.FocusMode),
+		AFPointsInFocus: int(pentax.AFPointsInFocus),
+		FocusPosition:   int(pentax.FocusPosition),
+		AFAdjustment:    int(pentax.AFAdjustment),
+		AFPointMode:     int(pentax.AFPointMode),
+		MultiExposure:   int(pentax.MultiExposure),
+		Quality:         int(pentax.Quality),
+	}
+	for i := range notes.AFPointSelected {
+		notes.AFPointSelected[i] = int(uint8(pentax.AFPointSelected[i]))
+	}
+	return notes
+}
@@ -0,0 +1,67 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// Only the maker notes section of the camera vendor is set
+func TestMakerNotesVendor(t *testing.T) {
+	vendors := func(notes MakerNotes) map[string]bool {
+		return map[string]bool{
+			"Canon":   notes.Canon != nil,
+			"Nikon":   notes.Nikon != nil,
+			"Sony":    notes.Sony != nil,
+			"Fuji":    notes.Fuji != nil,
+			"Olympus": notes.Olympus != nil,
+			"Pentax":  notes.Pentax != nil,
+		}
+	}
+	tests := []struct {
+		make string
+		want string
+	}{
+		{"Golibraw", ""},
+		{"Canon", "Canon"},
+		{"PENTAX", "Pentax"},
+		{"OLYMPUS IMAGING CORP.", "Olympus"},
+	}
+	for _, test := range tests {
+		t.Run(test.make, func(t *testing.T) {
+			metadata := testMetadata()
+			metadata.Camera.Make = test.make
+			md, err := ExtractMetadata(writeTestDNG(t, testImage("RGGB"), metadata))
+			if err != nil {
+				t.Fatalf("ExtractMetadata failed: %v", err)
+			}
+			for vendor, set := range vendors(md.MakerNotes) {
+				if set != (vendor == test.want) {
+					t.Errorf("%v maker notes set = %v, want %v", vendor, set, vendor == test.want)
+				}
+			}
+		})
+	}
+}
+
+// Reads the ambient temperature from the EXIF AmbientTemperature tag, the maker notes temperatures are missing
+func TestTemperatures(t *testing.T) {
+	const tagAmbientTemperature = 0x9400
+	temperature := binary.LittleEndian.AppendUint32(nil, uint32(215))
+	temperature = binary.LittleEndian.AppendUint32(temperature, 10)
+	md, err := ExtractMetadataBytes(subIFDDNG(t, tagExifIFD, testEntry{tagAmbientTemperature, typeSRational, temperature}))
+	if err != nil {
+		t.Fatalf("ExtractMetadataBytes failed: %v", err)
+	}
+	if md.Temperature.Ambient == nil || *md.Temperature.Ambient != 21.5 {
+		t.Errorf("ambient temperature = %v, want 21.5", md.Temperature.Ambient)
+	}
+	if md.Temperature.Camera != nil || md.Temperature.Sensor != nil || md.Temperature.Lens != nil || md.Temperature.Battery != nil {
+		t.Errorf("temperatures = %+v, want only the ambient one", md.Temperature)
+	}
+
+	if md, err := ExtractMetadata(testDNG(t, "RGGB")); err != nil || md.Temperature != (Temperatures{}) {
+		t.Errorf("temperatures of a file without temperatures = %+v, %v, want none", md.Temperature, err)
+	}
+}
@@ -0,0 +1,138 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"unsafe"
+)
+
+// MemImage is an image.Image backed directly by the memory buffer allocated by libraw, so the pixel data is never copied.
+// Samples are stored interleaved (R, G, B for color images), 16-bit samples in native byte order.
+// Close has to be called to free the buffer, the image must not be used afterwards.
+type MemImage struct {
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+	Bits   int
+	Colors int
+	mem    *C.libraw_processed_image_t
+}
+
+func newMemImage(mem *C.libraw_processed_image_t) *MemImage {
+	bytesPerSample := int(mem.bits) / 8
+	return &MemImage{
+		Pix:    unsafe.Slice((*byte)(unsafe.Pointer(&mem.data)), int(mem.data_size)),
+		Stride: int(mem.width) * int(mem.colors) * bytesPerSample,
+		Rect:   image.Rect(0, 0, int(mem.width), int(mem.height)),
+		Bits:   int(mem.bits),
+		Colors: int(mem.colors),
+		mem:    mem,
+	}
+}
+
+func (m *MemImage) ColorModel() color.Model {
+	if m.Colors == 1 {
+		if m.Bits == 16 {
+			return color.Gray16Model
+		}
+		return color.GrayModel
+	}
+	if m.Bits == 16 {
+		return color.RGBA64Model
+	}
+	return color.RGBAModel
+}
+
+func (m *MemImage) Bounds() image.Rectangle {
+	return m.Rect
+}
+
+func (m *MemImage) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(m.Rect)) || m.Pix == nil {
+		return color.RGBA{}
+	}
+	if m.Bits == 16 {
+		i := y*m.Stride + x*m.Colors*2
+		r := binary.NativeEndian.Uint16(m.Pix[i:])
+		if m.Colors == 1 {
+			return color.Gray16{Y: r}
+		}
+		g, b := r, r
+		if m.Colors >= 3 {
+			g = binary.NativeEndian.Uint16(m.Pix[i+2:])
+			b = binary.NativeEndian.Uint16(m.Pix[i+4:])
+		}
+		return color.RGBA64{R: r, G: g, B: b, A: 0xffff}
+	}
+	i := y*m.Stride + x*m.Colors
+	r := m.Pix[i]
+	if m.Colors == 1 {
+		return color.Gray{Y: r}
+	}
+	g, b := r, r
+	if m.Colors >= 3 {
+		g = m.Pix[i+1]
+		b = m.Pix[i+2]
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}
+}
+
+// Frees the libraw memory buffer backing the image.
+func (m *MemImage) Close() {
+	if m.mem == nil {
+		return
+	}
+	C.libraw_dcraw_clear_mem(m.mem)
+	m.mem = nil
+	m.Pix = nil
+}
+
+// Returns the processed image backed by libraw memory, without copying the pixel data. Process has to be called first.
+func (p *Processor) MemImage() (*MemImage, error) {
+	if p.handle == nil {
+		return nil, fmt.Errorf("processor is closed")
+	}
+
+	var result C.int
+	mem := C.libraw_dcraw_make_mem_image(p.handle, &result)
+	if err := goResult(result); err != nil {
+		C.libraw_dcraw_clear_mem(mem)
+		return nil, fmt.Errorf("failed to create image with [%w]", err)
+	}
+	if mem == nil {
+		return nil, fmt.Errorf("failed to create image")
+	}
+	return newMemImage(mem), nil
+}
+
+// Reads a RAW image file from file system and converts it to a MemImage, avoiding to copy the pixel data.
+// The returned image has to be closed by the caller.
+func ImportRawMem(path string, opts ...Option) (*MemImage, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+	processor.configure(newOptions(opts))
+
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+
+	if err = processor.Unpack(); err != nil {
+		return nil, fmt.Errorf("failed to unpack file [%v] with [%w]", path, err)
+	}
+
+	if err = processor.Process(opts...); err != nil {
+		return nil, fmt.Errorf("failed to import file [%v] with [%w]", path, err)
+	}
+
+	return processor.MemImage()
+}
@@ -0,0 +1,58 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The image backed by libraw memory has the pixels of the copied image
+func TestImportRawMem(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	img, err := ImportRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, err := ImportRawMem(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+
+	if mem.Bounds() != img.Bounds() {
+		t.Fatalf("bounds = %v, want %v", mem.Bounds(), img.Bounds())
+	}
+	if len(mem.Pix) != mem.Stride*mem.Rect.Dy() {
+		t.Errorf("%v bytes of pixel data, want %v rows of %v bytes", len(mem.Pix), mem.Rect.Dy(), mem.Stride)
+	}
+	rgba := img.(*image.RGBA)
+	for y := 0; y < testHeight; y++ {
+		for x := 0; x < testWidth; x++ {
+			if got, want := mem.At(x, y), rgba.RGBAAt(x, y); got != want {
+				t.Fatalf("pixel at %v,%v = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	mem.Close()
+	mem.Close()
+	if mem.Pix != nil || mem.At(0, 0) != (color.RGBA{}) {
+		t.Error("closed image still has pixel data")
+	}
+}
+
+// The libraw errors of MemImage decoding are wrapped for errors.Is
+func TestImportRawMemErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.dng")
+	if err := os.WriteFile(path, largeDNG(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportRawMem(path, WithMemoryLimit(1)); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("ImportRawMem over the memory limit: %v, want %v", err, ErrMemoryLimit)
+	}
+}
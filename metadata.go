@@ -0,0 +1,402 @@
+package golibraw
+
+import (
+	"strings"
+	"time"
+)
+
+type Camera struct {
+	Make     string `json:"make"`
+	Model    string `json:"model"`
+	Software string `json:"software"`
+	Serial   string `json:"serial"`
+	Colors   uint   `json:"colors"`
+}
+
+type Lens struct {
+	Make           string  `json:"make"`
+	Model          string  `json:"model"`
+	Serial         string  `json:"serial"`
+	MinFocal       float64 `json:"min_focal"`
+	MaxFocal       float64 `json:"max_focal"`
+	MaxAp4MinFocal float64 `json:"max_aperture_min_focal"`
+	MaxAp4MaxFocal float64 `json:"max_aperture_max_focal"`
+	// Focal length and aperture of the lens at capture, from the maker notes if recorded, otherwise from EXIF
+	CurFocal       float64 `json:"cur_focal"`
+	CurAp          float64 `json:"cur_aperture"`
+	MaxAp4CurFocal float64 `json:"max_aperture_cur_focal"`
+	// 35mm equivalent focal length at capture as recorded by the camera, zero if not recorded
+	FocalLength35mm float64 `json:"focal_length_35mm"`
+	// Minimum focus distance of the lens in meters
+	MinFocusDistance float64 `json:"min_focus_distance"`
+}
+
+// Sizes describes the sensor area and the size of the processed image.
+type Sizes struct {
+	RawWidth  int `json:"raw_width"`
+	RawHeight int `json:"raw_height"`
+	// Visible area of the sensor, starting at LeftMargin x TopMargin
+	Width      int `json:"width"`
+	Height     int `json:"height"`
+	TopMargin  int `json:"top_margin"`
+	LeftMargin int `json:"left_margin"`
+	// Size of the processed image at full resolution, after pixel aspect correction and rotation
+	OutputWidth  int     `json:"output_width"`
+	OutputHeight int     `json:"output_height"`
+	PixelAspect  float64 `json:"pixel_aspect"`
+	// Aspect ratio (width / height) of the processed image
+	AspectRatio float64 `json:"aspect_ratio"`
+}
+
+// Calibration holds the sensor levels and white balance multipliers libraw uses to scale the raw values.
+type Calibration struct {
+	Black uint `json:"black"`
+	// Per-channel black level, added to Black
+	CBlack  [4]uint `json:"cblack"`
+	Maximum uint    `json:"maximum"`
+	// Per-channel maximum linear value, if reported by the camera
+	LinearMax [4]int `json:"linear_max"`
+	// White balance multipliers of the camera (as shot) and of daylight
+	CamMul [4]float64 `json:"cam_mul"`
+	PreMul [4]float64 `json:"pre_mul"`
+	// Camera to XYZ (D65) matrix, one row per camera color (4 x 3)
+	CamXYZ [][]float64 `json:"cam_xyz"`
+	// Camera to sRGB matrix, one row per sRGB channel (3 x 4)
+	RGBCam [][]float64 `json:"rgb_cam"`
+}
+
+type GPS struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+	// UTC time of day of the GPS fix
+	Timestamp time.Duration `json:"timestamp_ns"`
+	// 'A' for a valid fix, 'V' for a void one
+	Status byte `json:"status"`
+}
+
+// Position of a frame in a Sony Pixel Shift Multi Shooting sequence, zero if the frame is not part of one
+type PixelShift struct {
+	GroupID uint `json:"group_id"`
+	// 1-based index of the frame and the number of frames in the group
+	Index int `json:"index"`
+	Shots int `json:"shots"`
+}
+
+type Metadata struct {
+	// Capture time as parsed by libraw, see Time for the conversion to time.Time
+	Timestamp int64 `json:"timestamp"`
+	// Offset of the capture time from UTC (e.g. "+02:00") if recorded by the camera
+	TimeOffset string   `json:"time_offset"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	DataSize   int64    `json:"data_size"`
+	Camera     Camera   `json:"camera"`
+	Lens       Lens     `json:"lens"`
+	ISO        int      `json:"iso"`
+	Aperture   float64  `json:"aperture"`
+	Shutter    float64  `json:"shutter"`
+	Warnings   Warnings `json:"-"`
+
+	FocalLength          float64 `json:"focal_length"`
+	ExposureCompensation float64 `json:"exposure_compensation"`
+	Flash                bool    `json:"flash"`
+	Orientation          int     `json:"orientation"`
+	Artist               string  `json:"artist"`
+	Copyright            string  `json:"copyright"`
+	Description          string  `json:"description"`
+	ShotOrder            int     `json:"shot_order"`
+	// Set if the file records whether the flash fired, Flash is false for files without the record
+	FlashRecorded bool `json:"flash_recorded"`
+	// Sensor format (e.g. "Full Frame", "APS-C", "Four Thirds") and its crop factor, see EquivalentFocalLength
+	SensorFormat string  `json:"sensor_format"`
+	CropFactor   float64 `json:"crop_factor"`
+	// Distance of the focused subject in meters from the EXIF SubjectDistance tag, zero if unknown
+	FocusDistance float64 `json:"focus_distance"`
+	// Number of shutter actuations of the camera, zero if unknown. Read from Sony and Nikon maker notes,
+	// Pentax and other vendors encrypt or do not record it.
+	ShutterCount int `json:"shutter_count"`
+	// Kind of sensor data, which selects the processing pipeline of libraw
+	RawType RawType `json:"-"`
+	// Number of frames in the RAW file, see WithShot
+	RawCount   int        `json:"raw_count"`
+	PixelShift PixelShift `json:"pixel_shift"`
+	MakerNotes MakerNotes `json:"maker_notes"`
+	// Temperatures of the camera at capture, e.g. for correlating dark current with the sensor temperature
+	Temperature Temperatures `json:"temperature"`
+	GPS         *GPS         `json:"gps,omitempty"`
+	Sizes       Sizes        `json:"sizes"`
+	CFA         CFA          `json:"cfa"`
+	// Processing data of DNG files, nil for other formats
+	DNG         *DNGInfo    `json:"dng,omitempty"`
+	Calibration Calibration `json:"calibration"`
+	// Correlated color temperature (Kelvin) and tint estimated from the camera white balance, zero if unknown
+	ColorTemperature float64 `json:"color_temperature"`
+	Tint             float64 `json:"tint"`
+}
+
+// MakerNotes holds the vendor specific data libraw parses from the maker notes. Numeric modes are the vendor codes
+// as recorded by the camera (see the ExifTool tag documentation of the vendor for their meaning).
+// Only the section of the camera vendor is set, the others are nil.
+type MakerNotes struct {
+	Shooting ShootingInfo  `json:"shooting"`
+	Common   CommonNotes   `json:"common"`
+	Canon    *CanonNotes   `json:"canon,omitempty"`
+	Nikon    *NikonNotes   `json:"nikon,omitempty"`
+	Sony     *SonyNotes    `json:"sony,omitempty"`
+	Fuji     *FujiNotes    `json:"fuji,omitempty"`
+	Olympus  *OlympusNotes `json:"olympus,omitempty"`
+	Pentax   *PentaxNotes  `json:"pentax,omitempty"`
+}
+
+// ShootingInfo holds the shooting settings libraw collects from the maker notes of all vendors.
+type ShootingInfo struct {
+	DriveMode          int    `json:"drive_mode"`
+	FocusMode          int    `json:"focus_mode"`
+	MeteringMode       int    `json:"metering_mode"`
+	AFPoint            int    `json:"af_point"`
+	ExposureMode       int    `json:"exposure_mode"`
+	ExposureProgram    int    `json:"exposure_program"`
+	ImageStabilization int    `json:"image_stabilization"`
+	BodySerial         string `json:"body_serial"`
+	InternalBodySerial string `json:"internal_body_serial"`
+}
+
+type CommonNotes struct {
+	FlashEC       float64 `json:"flash_ec"`
+	FlashGN       float64 `json:"flash_gn"`
+	RealISO       float64 `json:"real_iso"`
+	ExposureIndex float64 `json:"exposure_index"`
+	Firmware      string  `json:"firmware"`
+}
+
+type CanonNotes struct {
+	ImageStabilization    int     `json:"image_stabilization"`
+	ContinuousDrive       int     `json:"continuous_drive"`
+	ExposureMode          int     `json:"exposure_mode"`
+	MeteringMode          int     `json:"metering_mode"`
+	FlashMode             int     `json:"flash_mode"`
+	AFMicroAdjMode        int     `json:"af_micro_adj_mode"`
+	AFMicroAdjValue       float64 `json:"af_micro_adj_value"`
+	HighlightTonePriority int     `json:"highlight_tone_priority"`
+	AutoLightingOptimizer int     `json:"auto_lighting_optimizer"`
+	Quality               int     `json:"quality"`
+	RecordMode            int     `json:"record_mode"`
+	SRAWQuality           int     `json:"sraw_quality"`
+	CanonLog              int     `json:"canon_log"`
+}
+
+type NikonNotes struct {
+	ActiveDLighting    int    `json:"active_d_lighting"`
+	ShootingMode       int    `json:"shooting_mode"`
+	VibrationReduction int    `json:"vibration_reduction"`
+	VRMode             int    `json:"vr_mode"`
+	FlashSetting       string `json:"flash_setting"`
+	FlashType          string `json:"flash_type"`
+	NEFCompression     int    `json:"nef_compression"`
+	ExposureProgram    int    `json:"exposure_program"`
+	AFFineTune         int    `json:"af_fine_tune"`
+	AFFineTuneAdj      int    `json:"af_fine_tune_adj"`
+	// Camera attitude in degrees
+	RollAngle  float64 `json:"roll_angle"`
+	PitchAngle float64 `json:"pitch_angle"`
+	YawAngle   float64 `json:"yaw_angle"`
+}
+
+type SonyNotes struct {
+	AFAreaMode                    int  `json:"af_area_mode"`
+	AFPointSelected               int  `json:"af_point_selected"`
+	AFTracking                    int  `json:"af_tracking"`
+	AFType                        int  `json:"af_type"`
+	FocusPosition                 int  `json:"focus_position"`
+	AFMicroAdjOn                  bool `json:"af_micro_adj_on"`
+	AFMicroAdjValue               int  `json:"af_micro_adj_value"`
+	LongExposureNoiseReduction    int  `json:"long_exposure_noise_reduction"`
+	HighISONoiseReduction         int  `json:"high_iso_noise_reduction"`
+	ElectronicFrontCurtainShutter int  `json:"electronic_front_curtain_shutter"`
+	ShotNumberSincePowerUp        int  `json:"shot_number_since_power_up"`
+	RawFileType                   int  `json:"raw_file_type"`
+	Quality                       int  `json:"quality"`
+}
+
+type FujiNotes struct {
+	FilmMode                int    `json:"film_mode"`
+	DynamicRange            int    `json:"dynamic_range"`
+	DynamicRangeSetting     int    `json:"dynamic_range_setting"`
+	DevelopmentDynamicRange int    `json:"development_dynamic_range"`
+	FocusMode               int    `json:"focus_mode"`
+	AFMode                  int    `json:"af_mode"`
+	FocusPixel              [2]int `json:"focus_pixel"`
+	ImageStabilization      [3]int `json:"image_stabilization"`
+	ShutterType             int    `json:"shutter_type"`
+	DriveMode               int    `json:"drive_mode"`
+	Rating                  int    `json:"rating"`
+	SensorID                string `json:"sensor_id"`
+	RAFVersion              string `json:"raf_version"`
+	ImageCount              int    `json:"image_count"`
+}
+
+type OlympusNotes struct {
+	CameraType   string `json:"camera_type"`
+	FocusMode    [2]int `json:"focus_mode"`
+	AutoFocus    int    `json:"auto_focus"`
+	AFPoint      int    `json:"af_point"`
+	AFResult     int    `json:"af_result"`
+	DriveMode    [5]int `json:"drive_mode"`
+	LiveND       bool   `json:"live_nd"`
+	LiveNDFactor int    `json:"live_nd_factor"`
+}
+
+type PentaxNotes struct {
+	FocusMode       int    `json:"focus_mode"`
+	AFPointSelected [4]int `json:"af_point_selected"`
+	AFPointsInFocus int    `json:"af_points_in_focus"`
+	FocusPosition   int    `json:"focus_position"`
+	AFAdjustment    int    `json:"af_adjustment"`
+	AFPointMode     int    `json:"af_point_mode"`
+	MultiExposure   int    `json:"multi_exposure"`
+	Quality         int    `json:"quality"`
+}
+
+// Temperatures recorded by the camera in degrees Celsius, nil if not recorded.
+type Temperatures struct {
+	Camera  *float64 `json:"camera,omitempty"`
+	Sensor  *float64 `json:"sensor,omitempty"`
+	Lens    *float64 `json:"lens,omitempty"`
+	Battery *float64 `json:"battery,omitempty"`
+	// Ambient temperature from the maker notes, or from the EXIF AmbientTemperature tag
+	Ambient *float64 `json:"ambient,omitempty"`
+}
+
+// CFA describes the color filter array of the sensor.
+type CFA struct {
+	// Colors of the repeating filter pattern row by row from the top left corner of the visible area,
+	// e.g. "RGGB" for a 2x2 Bayer pattern, a 36 letter 6x6 pattern for Fuji X-Trans sensors, 16x16 for Leaf backs.
+	// Empty for sensors without a filter array (Foveon, monochrome, linear DNG).
+	Pattern string `json:"pattern"`
+	// The filter pattern from the top left corner of the raw data including the margins, see BayerImage
+	RawPattern string `json:"raw_pattern"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	// Set for the 6x6 pattern of Fuji X-Trans sensors, demosaiced with the Markesteijn algorithm, see WithXTransPasses
+	XTrans bool `json:"xtrans"`
+	// Letters of the color indexes used by libraw, e.g. "RGBG"
+	ColorDesc string `json:"color_desc"`
+	// Raw libraw (dcraw) filters value
+	Filters uint `json:"filters"`
+}
+
+// Returns the color letter of the visible pixel at row and col, zero if the sensor has no filter array.
+func (c CFA) ColorAt(row int, col int) byte {
+	if c.Width <= 0 || c.Height <= 0 || len(c.Pattern) < c.Width*c.Height {
+		return 0
+	}
+	row, col = (row%c.Height+c.Height)%c.Height, (col%c.Width+c.Width)%c.Width
+	return c.Pattern[row*c.Width+col]
+}
+
+// Returns the rows of the filter pattern, e.g. ["RG", "GB"] for a Bayer sensor or 6 rows of 6 letters for X-Trans.
+func (c CFA) Rows() []string {
+	if c.Pattern == "" || c.Width == 0 {
+		return nil
+	}
+	rows := make([]string, 0, c.Height)
+	for i := 0; i+c.Width <= len(c.Pattern); i += c.Width {
+		rows = append(rows, c.Pattern[i:i+c.Width])
+	}
+	return rows
+}
+
+// Reports whether the image is from a monochrome sensor without color filters, e.g. a Leica Monochrom or a
+// monochrome astronomy camera, imported as grayscale.
+func (m Metadata) Monochrome() bool {
+	return m.Camera.Colors == 1
+}
+
+// Warnings is a set of non-fatal problems libraw encountered while opening or processing an image,
+// e.g. a partially damaged file which could still be decoded.
+type Warnings uint
+
+// Reports whether all the warnings of flag are set.
+func (w Warnings) Has(flag Warnings) bool {
+	return w&flag == flag
+}
+
+func (w Warnings) String() string {
+	return strings.Join(w.names(), ", ")
+}
+
+func (w Warnings) names() []string {
+	names := make([]string, 0)
+	for _, warning := range warningNames {
+		if w.Has(warning.flag) {
+			names = append(names, warning.name)
+		}
+	}
+	return names
+}
+
+// RawType is the kind of sensor data stored in a RAW file. Bayer and X-Trans data is demosaiced by libraw, the
+// other types already have the complete samples of every pixel.
+type RawType int
+
+const (
+	RawTypeUnknown RawType = iota
+	// One color per pixel under a Bayer filter array, see CFA
+	RawTypeBayer
+	// One color per pixel under the 6x6 filter array of Fuji X-Trans sensors, see WithXTransPasses
+	RawTypeXTrans
+	// Sensor without color filters, imported as grayscale, see Metadata.Monochrome
+	RawTypeMonochrome
+	// Sigma Foveon X3 sensor recording three colors at every pixel, decoded by libraw builds with X3F support only
+	RawTypeFoveon
+	// Full color pixels without demosaic: Canon sRAW and mRAW, Nikon small NEF and linear DNG files
+	RawTypeLinear
+)
+
+func (t RawType) String() string {
+	switch t {
+	case RawTypeBayer:
+		return "bayer"
+	case RawTypeXTrans:
+		return "xtrans"
+	case RawTypeMonochrome:
+		return "monochrome"
+	case RawTypeFoveon:
+		return "foveon"
+	case RawTypeLinear:
+		return "linear"
+	default:
+		return "unknown"
+	}
+}
+
+type ThumbnailFormat int
+
+const (
+	ThumbnailUnknown ThumbnailFormat = iota
+	ThumbnailJPEG
+	ThumbnailBitmap
+)
+
+func (f ThumbnailFormat) String() string {
+	switch f {
+	case ThumbnailJPEG:
+		return "jpeg"
+	case ThumbnailBitmap:
+		return "bitmap"
+	default:
+		return "unknown"
+	}
+}
+
+// ThumbnailInfo describes an embedded thumbnail. Bitmap thumbnails are stored as interleaved samples
+// of Colors channels with Bits bits each, JPEG thumbnails as a complete JPEG file.
+type ThumbnailInfo struct {
+	Format ThumbnailFormat
+	Width  int
+	Height int
+	Colors int
+	Bits   int
+	Size   int
+}
@@ -0,0 +1,30 @@
+package golibraw
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCFAColorAt(t *testing.T) {
+	cfa := CFA{Pattern: "RGGB", Width: 2, Height: 2}
+	for _, test := range []struct {
+		row, col int
+		want     byte
+	}{
+		{0, 0, 'R'}, {0, 1, 'G'}, {1, 0, 'G'}, {1, 1, 'B'},
+		{2, 3, 'G'}, {-1, -1, 'B'}, {-2, 0, 'R'},
+	} {
+		if got := cfa.ColorAt(test.row, test.col); got != test.want {
+			t.Errorf("ColorAt(%v, %v) = %c, want %c", test.row, test.col, got, test.want)
+		}
+	}
+	if got := (CFA{}).ColorAt(0, 0); got != 0 {
+		t.Errorf("ColorAt without pattern = %v, want 0", got)
+	}
+	if got := cfa.Rows(); !slices.Equal(got, []string{"RG", "GB"}) {
+		t.Errorf("Rows() = %q, want [RG GB]", got)
+	}
+	if got := (CFA{}).Rows(); got != nil {
+		t.Errorf("Rows() without pattern = %q, want none", got)
+	}
+}
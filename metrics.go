@@ -0,0 +1,68 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Stage describes a finished libraw stage of a processor, reported to a MetricsFunc.
+type Stage struct {
+	// Name of the stage: "open", "unpack", "unpack_thumb", "process" or "export"
+	Name     string
+	Duration time.Duration
+	// Size of the input file or buffer being processed in bytes
+	Bytes int64
+	// Error of the stage, nil on success
+	Err error
+	// libraw error code of Err, 0 on success and for errors not reported by libraw, e.g. cancellation
+	Code int
+}
+
+// MetricsFunc receives every finished libraw stage, e.g. for exporting decode durations and error counts to
+// Prometheus or OpenTelemetry. It is called on the goroutine using the processor.
+type MetricsFunc func(stage Stage)
+
+var defaultMetrics atomic.Pointer[MetricsFunc]
+
+// Sets the metrics callback of the processors created afterwards and of the package level functions, nil disables it.
+func SetMetrics(fn MetricsFunc) {
+	if fn == nil {
+		defaultMetrics.Store(nil)
+		return
+	}
+	defaultMetrics.Store(&fn)
+}
+
+func packageMetrics() MetricsFunc {
+	if fn := defaultMetrics.Load(); fn != nil {
+		return *fn
+	}
+	return nil
+}
+
+// Sets the metrics callback of the processor, see SetMetrics. nil disables it.
+func (p *Processor) SetMetrics(fn MetricsFunc) {
+	p.metrics = fn
+}
+
+// Reports the libraw stages of processing the image to fn, overriding SetMetrics.
+func WithMetrics(fn MetricsFunc) Option {
+	return func(o *options) {
+		o.metrics = fn
+	}
+}
+
+func (p *Processor) observe(name string, duration time.Duration, err error) {
+	if p.metrics == nil {
+		return
+	}
+	stage := Stage{Name: name, Duration: duration, Bytes: p.dataSize, Err: err}
+	var lrErr *Error
+	if errors.As(err, &lrErr) {
+		stage.Code = lrErr.Code
+	}
+	p.metrics(stage)
+}
@@ -0,0 +1,52 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithMetrics(t *testing.T) {
+	var stages []Stage
+	path := testDNG(t, "RGGB")
+	if _, err := ImportRawWithOptions(path, WithMetrics(func(stage Stage) { stages = append(stages, stage) })); err != nil {
+		t.Fatalf("ImportRawWithOptions failed: %v", err)
+	}
+	names := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		names = append(names, stage.Name)
+		if stage.Err != nil || stage.Code != 0 || stage.Bytes <= 0 || stage.Duration < 0 {
+			t.Errorf("stage %+v, want a successful stage of the file size", stage)
+		}
+	}
+	if len(names) != 3 || names[0] != "open" || names[1] != "unpack" || names[2] != "process" {
+		t.Errorf("stages = %v, want [open unpack process]", names)
+	}
+}
+
+func TestSetMetrics(t *testing.T) {
+	var stages []Stage
+	SetMetrics(func(stage Stage) { stages = append(stages, stage) })
+	defer SetMetrics(nil)
+
+	if _, err := ImportRaw(filepath.Join("testdata", "corrupt", "garbage.raw")); err == nil {
+		t.Fatal("ImportRaw of garbage succeeded")
+	}
+	if len(stages) != 1 || stages[0].Name != "open" {
+		t.Fatalf("stages = %+v, want the open stage", stages)
+	}
+	if !errors.Is(stages[0].Err, ErrUnsupportedFile) || stages[0].Code != ErrUnsupportedFile.Code {
+		t.Errorf("open stage error = %v with code %v, want %v", stages[0].Err, stages[0].Code, ErrUnsupportedFile)
+	}
+
+	SetMetrics(nil)
+	stages = nil
+	if _, err := ExtractMetadata(testDNG(t, "RGGB")); err != nil {
+		t.Fatal(err)
+	}
+	if len(stages) != 0 {
+		t.Errorf("stages = %+v after disabling the metrics, want none", stages)
+	}
+}
@@ -0,0 +1,275 @@
+//go:build !cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"time"
+)
+
+// TIFF and EXIF tags read without libraw
+const (
+	tagImageDescription    = 0x010e
+	tagModel               = 0x0110
+	tagStripOffsets        = 0x0111
+	tagOrientation         = 0x0112
+	tagStripByteCounts     = 0x0117
+	tagSoftware            = 0x0131
+	tagArtist              = 0x013b
+	tagJPEGOffset          = 0x0201
+	tagJPEGLength          = 0x0202
+	tagExposureTime        = 0x829a
+	tagFNumber             = 0x829d
+	tagISO                 = 0x8827
+	tagDateTimeOriginal    = 0x9003
+	tagFocalLength         = 0x920a
+	tagFocalLengthIn35mm   = 0xa405
+	tagBodySerialNumber    = 0xa431
+	tagLensMake            = 0xa433
+	tagLensModel           = 0xa434
+	tagPanasonicJpgFromRaw = 0x002e
+)
+
+// Number of IFDs searched for previews, protecting against loops in malformed files
+const maxPreviewIFDs = 32
+
+// Offset of the preview offset and length fields in the header of RAF files
+const rafPreviewField = 84
+
+// Warnings are only reported by libraw
+var warningNames []struct {
+	flag Warnings
+	name string
+}
+
+// Reads a RAW image file from file system and exports the metadata recorded in its TIFF/EXIF container.
+// Without cgo, libraw is not available: only TIFF based formats (CR2, NEF, ARW, DNG, PEF, ...) and the EXIF data
+// of the embedded preview of RAF files are read, and only the camera, lens, exposure and capture time are set.
+func ExtractMetadata(path string) (Metadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("input file [%v] does not exist", path)
+	}
+	return ExtractMetadataBytes(data)
+}
+
+// Reads a RAW image from memory and exports the metadata recorded in its container, see ExtractMetadata.
+func ExtractMetadataBytes(data []byte) (Metadata, error) {
+	r := bytes.NewReader(data)
+	if _, ok := newTiffReader(r); !ok {
+		preview, _, previewErr := embeddedPreview(r)
+		if previewErr != nil {
+			return Metadata{}, fmt.Errorf("unsupported file format without libraw")
+		}
+		if r = jpegExif(preview); r == nil {
+			return Metadata{}, fmt.Errorf("no EXIF data found")
+		}
+	}
+	metadata := containerMetadata(r)
+	metadata.DataSize = int64(len(data))
+	return metadata, nil
+}
+
+// Reads a RAW image from r and exports the metadata recorded in its container, see ExtractMetadata.
+func DecodeMetadata(r io.Reader) (Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to read input with [%w]", err)
+	}
+	return ExtractMetadataBytes(data)
+}
+
+// Reads a RAW image file from file system and returns the largest JPEG preview embedded in its container.
+// Without cgo, only TIFF based formats and RAF files are supported.
+func ExtractThumbnailBytes(path string) ([]byte, ThumbnailInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ThumbnailInfo{}, fmt.Errorf("input file [%v] does not exist", path)
+	}
+	defer f.Close()
+	return embeddedPreview(f)
+}
+
+// Reads a RAW image file from file system and writes its largest embedded JPEG preview to w, see ExtractThumbnailBytes.
+func ExtractThumbnailTo(inputPath string, w io.Writer) error {
+	data, _, err := ExtractThumbnailBytes(inputPath)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Reads a RAW image file from file system and decodes its largest embedded JPEG preview, see ExtractThumbnailBytes.
+func ImportThumbnail(path string) (image.Image, error) {
+	data, _, err := ExtractThumbnailBytes(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding JPEG thumbnail failed with [%w]", err)
+	}
+	return img, nil
+}
+
+// Without libraw there is no rendering to fall back to
+func previewOf(path string) (image.Image, error) {
+	return ImportThumbnail(path)
+}
+
+func containerMetadata(r io.ReaderAt) Metadata {
+	metadata := Metadata{}
+	t, ok := newTiffReader(r)
+	if !ok {
+		return metadata
+	}
+
+	ifd0, _ := t.ifd(t.first)
+	ascii := func(entries []tiffEntry, tag uint16) string {
+		if e, ok := findEntry(entries, tag); ok {
+			return t.ascii(e)
+		}
+		return ""
+	}
+	number := func(entries []tiffEntry, tag uint16) float64 {
+		e, ok := findEntry(entries, tag)
+		switch {
+		case !ok:
+			return 0
+		case e.typ == typeRational || e.typ == typeSRational:
+			return t.rational(e, 0)
+		default:
+			return float64(t.uint(e, 0))
+		}
+	}
+
+	metadata.Camera.Make = ascii(ifd0, tagMake)
+	metadata.Camera.Model = ascii(ifd0, tagModel)
+	metadata.Camera.Software = ascii(ifd0, tagSoftware)
+	metadata.Artist = ascii(ifd0, tagArtist)
+	metadata.Description = ascii(ifd0, tagImageDescription)
+	metadata.Orientation = int(number(ifd0, tagOrientation))
+
+	if e, ok := findEntry(ifd0, tagExifIFD); ok {
+		exif, _ := t.ifd(int64(t.uint(e, 0)))
+		metadata.ISO = int(number(exif, tagISO))
+		metadata.Shutter = number(exif, tagExposureTime)
+		metadata.Aperture = number(exif, tagFNumber)
+		metadata.FocalLength = number(exif, tagFocalLength)
+		metadata.Camera.Serial = ascii(exif, tagBodySerialNumber)
+		metadata.Lens.Make = ascii(exif, tagLensMake)
+		metadata.Lens.Model = ascii(exif, tagLensModel)
+		metadata.Lens.CurFocal = metadata.FocalLength
+		metadata.Lens.CurAp = metadata.Aperture
+		metadata.Lens.FocalLength35mm = number(exif, tagFocalLengthIn35mm)
+		// interpreted in the local time zone, like libraw does
+		if captured, err := time.ParseInLocation("2006:01:02 15:04:05", ascii(exif, tagDateTimeOriginal), time.Local); err == nil {
+			metadata.Timestamp = captured.Unix()
+		}
+	}
+	metadata.addExif(readExif(r))
+	return metadata
+}
+
+// Returns the largest baseline JPEG embedded in the IFDs of a TIFF based RAW container, or the preview of a RAF file
+func embeddedPreview(r io.ReaderAt) ([]byte, ThumbnailInfo, error) {
+	header := make([]byte, rafPreviewField+8)
+	if n, _ := r.ReadAt(header, 0); bytes.HasPrefix(header[:n], []byte("FUJIFILMCCD-RAW")) && n == len(header) {
+		offset := int64(binary.BigEndian.Uint32(header[rafPreviewField:]))
+		length := int(binary.BigEndian.Uint32(header[rafPreviewField+4:]))
+		if data, info, ok := jpegAt(r, offset, length); ok {
+			return data, info, nil
+		}
+		return nil, ThumbnailInfo{}, fmt.Errorf("no embedded preview found")
+	}
+
+	t, ok := newTiffReader(r)
+	if !ok {
+		return nil, ThumbnailInfo{}, fmt.Errorf("unsupported file format without libraw")
+	}
+
+	var best []byte
+	var bestInfo ThumbnailInfo
+	consider := func(offset int64, length int) {
+		if data, info, ok := jpegAt(r, offset, length); ok && info.Width*info.Height > bestInfo.Width*bestInfo.Height {
+			best, bestInfo = data, info
+		}
+	}
+
+	queue := []int64{t.first}
+	visited := map[int64]bool{}
+	for len(queue) > 0 && len(visited) < maxPreviewIFDs {
+		offset := queue[0]
+		queue = queue[1:]
+		if offset <= 0 || visited[offset] {
+			continue
+		}
+		visited[offset] = true
+
+		entries, next := t.ifd(offset)
+		queue = append(queue, next)
+		if e, ok := findEntry(entries, tagSubIFDs); ok {
+			for i := 0; i < e.count && i < maxPreviewIFDs; i++ {
+				queue = append(queue, int64(t.uint(e, i)))
+			}
+		}
+		if start, ok := findEntry(entries, tagJPEGOffset); ok {
+			if length, ok := findEntry(entries, tagJPEGLength); ok {
+				consider(int64(t.uint(start, 0)), int(t.uint(length, 0)))
+			}
+		}
+		if start, ok := findEntry(entries, tagStripOffsets); ok && start.count == 1 {
+			if length, ok := findEntry(entries, tagStripByteCounts); ok {
+				consider(int64(t.uint(start, 0)), int(t.uint(length, 0)))
+			}
+		}
+		if e, ok := findEntry(entries, tagPanasonicJpgFromRaw); ok {
+			consider(e.offset, e.count)
+		}
+	}
+
+	if best == nil {
+		return nil, ThumbnailInfo{}, fmt.Errorf("no embedded preview found")
+	}
+	return best, bestInfo, nil
+}
+
+// Reads the JPEG at offset, ok only if it is a JPEG image/jpeg can decode (not the lossless JPEG of raw data)
+func jpegAt(r io.ReaderAt, offset int64, length int) ([]byte, ThumbnailInfo, bool) {
+	if offset <= 0 || length < 4 || length > 1<<28 {
+		return nil, ThumbnailInfo{}, false
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil || !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return nil, ThumbnailInfo{}, false
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ThumbnailInfo{}, false
+	}
+	return data, ThumbnailInfo{Format: ThumbnailJPEG, Width: config.Width, Height: config.Height, Size: length}, true
+}
+
+// Returns the TIFF structure of the EXIF segment of a JPEG image, nil if there is none
+func jpegExif(data []byte) *bytes.Reader {
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		marker, size := data[i+1], int(data[i+2])<<8|int(data[i+3])
+		// the segment length includes its own two bytes
+		if marker == 0xda || size < 2 || i+2+size > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return bytes.NewReader(segment[6:])
+		}
+		i += 2 + size
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+//go:build !cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJpegExifMalformedSegments(t *testing.T) {
+	for _, data := range [][]byte{
+		{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x00},
+		{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x01, 'E', 'x'},
+		{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff, 'E', 'x', 'i', 'f', 0, 0},
+		{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x08, 'E', 'x', 'i', 'f', 0, 0},
+	} {
+		if r := jpegExif(data); r != nil && r.Len() > 0 {
+			t.Errorf("jpegExif(% x) found EXIF data", data)
+		}
+	}
+}
+
+func TestImportThumbnailCorrupt(t *testing.T) {
+	for name := range corruptFiles(t) {
+		if _, err := ImportThumbnail(filepath.Join("testdata", "corrupt", name)); err == nil {
+			t.Errorf("ImportThumbnail(%v) succeeded", name)
+		}
+	}
+}
+
+// Returns a JPEG image of width x height, with the EXIF segment of the TIFF structure exif if not nil
+func testJPEG(t *testing.T, width int, height int, exif []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if exif == nil {
+		return data
+	}
+	segment := append([]byte("Exif\x00\x00"), exif...)
+	app1 := binary.BigEndian.AppendUint16([]byte{0xff, 0xe1}, uint16(2+len(segment)))
+	return append(append(append([]byte{0xff, 0xd8}, app1...), segment...), data[2:]...)
+}
+
+func TestExtractMetadataWithoutLibraw(t *testing.T) {
+	ascii := func(tag uint16, s string) testEntry { return testEntry{tag, typeASCII, []byte(s + "\x00")} }
+	data := exifTIFF(
+		[]testEntry{ascii(tagMake, "NIKON CORPORATION"), ascii(tagModel, "NIKON Z 6"), shortEntry(tagOrientation, 6)},
+		shortEntry(tagISO, 800),
+		rationalEntry(tagExposureTime, typeRational, 1, 250),
+		rationalEntry(tagFNumber, typeRational, 28, 10),
+		ascii(tagDateTimeOriginal, "2024:05:01 10:20:30"),
+		ascii(tagLensModel, "NIKKOR Z 24-70mm f/4 S"),
+	)
+	md, err := ExtractMetadataBytes(data)
+	if err != nil {
+		t.Fatalf("ExtractMetadataBytes failed: %v", err)
+	}
+	if md.Camera.Make != "NIKON CORPORATION" || md.Camera.Model != "NIKON Z 6" || md.Orientation != 6 {
+		t.Errorf("camera = %+v, orientation %v, want the NIKON Z 6 rotated by 6", md.Camera, md.Orientation)
+	}
+	if md.ISO != 800 || md.Shutter != 1.0/250 || md.Aperture != 2.8 || md.Lens.CurAp != 2.8 {
+		t.Errorf("exposure = ISO %v, %vs, f/%v, want ISO 800, 1/250s, f/2.8", md.ISO, md.Shutter, md.Aperture)
+	}
+	if md.Lens.Model != "NIKKOR Z 24-70mm f/4 S" {
+		t.Errorf("lens = %q, want the NIKKOR", md.Lens.Model)
+	}
+	if want := time.Date(2024, 5, 1, 10, 20, 30, 0, time.Local); md.Timestamp != want.Unix() {
+		t.Errorf("timestamp = %v, want %v", time.Unix(md.Timestamp, 0), want)
+	}
+	if md.DataSize != int64(len(data)) {
+		t.Errorf("data size = %v, want %v", md.DataSize, len(data))
+	}
+
+	if _, err := ExtractMetadataBytes([]byte("not a RAW file")); err == nil {
+		t.Errorf("ExtractMetadataBytes of text succeeded")
+	}
+}
+
+// The largest of the JPEG previews in IFD0 and in a sub-IFD is extracted
+func TestEmbeddedPreviewTIFF(t *testing.T) {
+	small, large := testJPEG(t, 16, 8, nil), testJPEG(t, 64, 48, nil)
+	long := func(tag uint16) testEntry { return testEntry{tag, typeLong, make([]byte, 4)} }
+	data := testTIFF(long(tagJPEGOffset), long(tagJPEGLength), long(tagSubIFDs))
+	set := func(i int, value int) { binary.LittleEndian.PutUint32(data[8+2+12*i+8:], uint32(value)) }
+	set(0, len(data))
+	set(1, len(small))
+	data = append(data, small...)
+	set(2, len(data))
+	data = appendIFD(data, long(tagJPEGOffset), long(tagJPEGLength))
+	// the sub-IFD ends with its two entries and the next IFD offset, the large preview follows
+	binary.LittleEndian.PutUint32(data[len(data)-2*12-4+8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(data[len(data)-12-4+8:], uint32(len(large)))
+	data = append(data, large...)
+
+	path := filepath.Join(t.TempDir(), "preview.nef")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	preview, info, err := ExtractThumbnailBytes(path)
+	if err != nil {
+		t.Fatalf("ExtractThumbnailBytes failed: %v", err)
+	}
+	if !bytes.Equal(preview, large) || info.Width != 64 || info.Height != 48 || info.Format != ThumbnailJPEG {
+		t.Errorf("preview = %v bytes of %vx%v, want the %v bytes 64x48 JPEG", len(preview), info.Width, info.Height, len(large))
+	}
+	img, err := ImportThumbnail(path)
+	if err != nil {
+		t.Fatalf("ImportThumbnail failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 64, 48) {
+		t.Errorf("thumbnail bounds = %v, want 64x48", img.Bounds())
+	}
+}
+
+// RAF files have the preview at an offset in the header, the metadata is read from the EXIF data of the preview
+func TestEmbeddedPreviewRAF(t *testing.T) {
+	exif := testTIFF(testEntry{tagMake, typeASCII, []byte("FUJIFILM\x00")})
+	preview := testJPEG(t, 32, 24, exif)
+	header := make([]byte, rafPreviewField+8)
+	copy(header, "FUJIFILMCCD-RAW 0201FF383501")
+	binary.BigEndian.PutUint32(header[rafPreviewField:], uint32(len(header)))
+	binary.BigEndian.PutUint32(header[rafPreviewField+4:], uint32(len(preview)))
+	data := append(header, preview...)
+
+	path := filepath.Join(t.TempDir(), "preview.raf")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ExtractThumbnailTo(path, &buf); err != nil {
+		t.Fatalf("ExtractThumbnailTo failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), preview) {
+		t.Errorf("preview of %v bytes differs from the embedded one of %v bytes", buf.Len(), len(preview))
+	}
+	md, err := ExtractMetadata(path)
+	if err != nil {
+		t.Fatalf("ExtractMetadata failed: %v", err)
+	}
+	if md.Camera.Make != "FUJIFILM" {
+		t.Errorf("make = %q, want FUJIFILM", md.Camera.Make)
+	}
+}
@@ -0,0 +1,537 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+)
+
+// Option configures how libraw processes a RAW image. Options not set fall back to the libraw defaults.
+type Option func(*options)
+
+type options struct {
+	params   []func(*C.libraw_output_params_t)
+	paths    []pathParam
+	progress ProgressFunc
+	shot     int
+	// memory limit in megabytes, 0 for the default
+	memoryLimit int
+	logger      *slog.Logger
+	metrics     MetricsFunc
+	ctx         context.Context
+	output      OutputOptions
+	resize      resizeSpec
+	monochrome  bool
+	// passes of the X-Trans demosaic, 0 for the algorithm selected by WithDemosaic
+	xtransPasses int
+	// DNG handling, see WithDNGDefaultCrop and WithBaselineExposure
+	dngDefaultCrop   bool
+	baselineExposure bool
+	// first invalid option, reported by Process
+	err error
+}
+
+// Size the processed image is scaled down to, see WithResize
+type resizeSpec struct {
+	maxDim int
+	filter ResizeFilter
+}
+
+// File path parameter, the C string is owned by the processor until the next image is opened
+type pathParam struct {
+	path string
+	set  func(*C.libraw_output_params_t, *C.char)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) apply(p *Processor) {
+	for _, set := range o.params {
+		set(&p.handle.params)
+	}
+	for _, param := range o.paths {
+		param.set(&p.handle.params, p.cString(param.path))
+	}
+}
+
+// Records the first invalid option
+func (o *options) fail(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+func withParams(set func(*C.libraw_output_params_t)) Option {
+	return func(o *options) {
+		o.params = append(o.params, set)
+	}
+}
+
+type ColorSpace int
+
+const (
+	ColorSpaceRaw ColorSpace = iota
+	ColorSpaceSRGB
+	ColorSpaceAdobe
+	ColorSpaceWide
+	ColorSpaceProPhoto
+	ColorSpaceXYZ
+	ColorSpaceACES
+	ColorSpaceDCIP3
+	ColorSpaceRec2020
+)
+
+var colorSpaceNames = []string{"raw", "srgb", "adobe", "wide", "prophoto", "xyz", "aces", "dci-p3", "rec2020"}
+
+func (c ColorSpace) String() string {
+	if c >= 0 && int(c) < len(colorSpaceNames) {
+		return colorSpaceNames[c]
+	}
+	return fmt.Sprintf("colorspace(%d)", int(c))
+}
+
+// Returns the color space by its name, e.g. "srgb", "adobe" or "rec2020".
+func ParseColorSpace(name string) (ColorSpace, error) {
+	for c, n := range colorSpaceNames {
+		if strings.EqualFold(n, name) {
+			return ColorSpace(c), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color space [%v]", name)
+}
+
+type HighlightMode int
+
+const (
+	HighlightClip HighlightMode = iota
+	HighlightUnclip
+	HighlightBlend
+	HighlightRebuild
+)
+
+type Demosaic int
+
+const (
+	DemosaicLinear Demosaic = 0
+	DemosaicVNG    Demosaic = 1
+	DemosaicPPG    Demosaic = 2
+	DemosaicAHD    Demosaic = 3
+	DemosaicDCB    Demosaic = 4
+	DemosaicDHT    Demosaic = 11
+	DemosaicAAHD   Demosaic = 12
+)
+
+// Algorithms of the GPL2/GPL3 demosaic packs, only available in libraw builds before 0.20 linked with the packs.
+// Other builds fall back to AHD, reported by WarnFallbackToAHD.
+const (
+	DemosaicModifiedAHD Demosaic = 5
+	DemosaicAFD         Demosaic = 6
+	DemosaicVCD         Demosaic = 7
+	DemosaicVCDAHD      Demosaic = 8
+	DemosaicLMMSE       Demosaic = 9
+	DemosaicAMaZE       Demosaic = 10
+)
+
+var demosaicNames = map[Demosaic]string{
+	DemosaicLinear:      "linear",
+	DemosaicVNG:         "vng",
+	DemosaicPPG:         "ppg",
+	DemosaicAHD:         "ahd",
+	DemosaicDCB:         "dcb",
+	DemosaicModifiedAHD: "modified-ahd",
+	DemosaicAFD:         "afd",
+	DemosaicVCD:         "vcd",
+	DemosaicVCDAHD:      "vcd-ahd",
+	DemosaicLMMSE:       "lmmse",
+	DemosaicAMaZE:       "amaze",
+	DemosaicDHT:         "dht",
+	DemosaicAAHD:        "aahd",
+}
+
+func (d Demosaic) String() string {
+	if name, ok := demosaicNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("demosaic(%d)", int(d))
+}
+
+// Returns the demosaic algorithm by its name, e.g. "ahd" or "dht".
+func ParseDemosaic(name string) (Demosaic, error) {
+	for d, n := range demosaicNames {
+		if strings.EqualFold(n, name) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown demosaic algorithm [%v]", name)
+}
+
+// Uses the white balance recorded by the camera, if present in the RAW file.
+func WithCameraWhiteBalance() Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.use_camera_wb = 1
+		p.use_auto_wb = 0
+	})
+}
+
+// Calculates the white balance by averaging the whole image.
+func WithAutoWhiteBalance() Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.use_auto_wb = 1
+		p.use_camera_wb = 0
+	})
+}
+
+// Sets custom white balance multipliers for the R, G, B and second G channels, overriding camera and auto white balance.
+func WithWhiteBalanceMultipliers(r float64, g float64, b float64, g2 float64) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.user_mul = [4]C.float{C.float(r), C.float(g), C.float(b), C.float(g2)}
+		p.use_auto_wb = 0
+		p.use_camera_wb = 0
+	})
+}
+
+// Calculates the white balance by averaging the rectangle of the image at x, y with the given width and height,
+// e.g. a grey card in the frame.
+func WithGreyBox(x int, y int, width int, height int) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.greybox = [4]C.uint{C.uint(x), C.uint(y), C.uint(width), C.uint(height)}
+		p.use_auto_wb = 1
+		p.use_camera_wb = 0
+	})
+}
+
+// Sets the color space of the output image, sRGB by default. ACES, DCI-P3 and Rec. 2020 require libraw 0.19 or newer.
+func WithColorSpace(space ColorSpace) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.output_color = C.int(space)
+	})
+}
+
+// Sets the gamma curve of the output image by its power and toe slope, BT.709 (2.222, 4.5) by default.
+func WithGamma(power float64, slope float64) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.gamm[0] = C.double(1 / power)
+		p.gamm[1] = C.double(slope)
+	})
+}
+
+// Sets the brightness multiplier of the output image, 1.0 by default. Applied on top of the automatic brightening
+// unless it is disabled with WithAutoBright.
+func WithBrightness(brightness float64) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.bright = C.float(brightness)
+	})
+}
+
+// Enables or disables the automatic brightening of the output image, enabled by default. libraw scales every image so
+// that a fixed share of its pixels is clipped, which makes the brightness of similar frames vary; disable it for
+// deterministic output, e.g. for the frames of a timelapse, and set the brightness with WithBrightness instead.
+func WithAutoBright(enabled bool) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.no_auto_bright = 1
+		if enabled {
+			p.no_auto_bright = 0
+		}
+	})
+}
+
+// Sets the share of pixels clipped by the automatic brightening, 0.01 (1%) by default. Lower values brighten less.
+func WithAutoBrightThreshold(threshold float64) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.auto_bright_thr = C.float(threshold)
+	})
+}
+
+// Sets how clipped highlights are handled. Values above HighlightRebuild (up to 9) rebuild highlights more aggressively.
+func WithHighlightMode(mode HighlightMode) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.highlight = C.int(mode)
+	})
+}
+
+// Sets the bits per sample of the output image, either 8 (default) or 16.
+func WithBitDepth(bits int) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.output_bps = C.int(bits)
+	})
+}
+
+// Sets the demosaic (interpolation) algorithm, AHD by default.
+func WithDemosaic(algorithm Demosaic) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.user_qual = C.int(algorithm)
+	})
+}
+
+// Rotates the output image according to the orientation recorded by the camera (see Metadata.Orientation),
+// which is the default. When disabled, the image is kept in sensor orientation.
+func WithAutoRotate(enabled bool) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		if enabled {
+			p.user_flip = -1
+		} else {
+			p.user_flip = 0
+		}
+	})
+}
+
+// Overrides the orientation recorded by the camera with an EXIF orientation (1-8).
+func WithUserFlip(orientation int) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.user_flip = C.int(librawFlip(orientation))
+	})
+}
+
+// Sets the passes of the Markesteijn demosaic of Fuji X-Trans sensors: 1 for faster processing, 3 for the best
+// quality. It takes precedence over WithDemosaic on X-Trans sensors only, so the same options suit Bayer and X-Trans
+// images. Without it, DemosaicPPG selects one pass, AHD and the other Bayer only algorithms three passes.
+func WithXTransPasses(passes int) Option {
+	return func(o *options) {
+		o.xtransPasses = passes
+	}
+}
+
+// Sets the number of DCB correction passes (libraw default is 0) and enables the DCB false color suppression,
+// used with DemosaicDCB.
+func WithDCB(iterations int, enhance bool) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.dcb_iterations = C.int(iterations)
+		p.dcb_enhance_fl = 0
+		if enhance {
+			p.dcb_enhance_fl = 1
+		}
+	})
+}
+
+// Skips the color processing: the image is neither demosaiced nor converted from the camera colors, and is imported
+// as grayscale (*image.Gray or *image.Gray16), like dcraw -d. Monochrome sensors (Metadata.Monochrome) are imported
+// as grayscale anyway, the option saves the pointless color conversion. Color sensors give the undemosaiced image with
+// the filter pattern visible. The PPM and TIFF exports written by libraw keep the color channels.
+func WithMonochrome() Option {
+	return func(o *options) {
+		o.monochrome = true
+		o.params = append(o.params, func(p *C.libraw_output_params_t) {
+			p.output_color = C.int(ColorSpaceRaw)
+			p.no_interpolation = 1
+		})
+	}
+}
+
+// Interpolates the two greens of the Bayer pattern as separate colors, like dcraw -f. Fixes the maze pattern
+// artifacts of sensors with green channel imbalance, at the cost of some resolution.
+func WithFourColorRGB(enabled bool) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.four_color_rgb = 0
+		if enabled {
+			p.four_color_rgb = 1
+		}
+	})
+}
+
+// Equalizes the two green channels before demosaic, reducing the maze pattern artifacts of sensors with green
+// channel imbalance without the resolution loss of WithFourColorRGB.
+func WithGreenMatching(enabled bool) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.green_matching = 0
+		if enabled {
+			p.green_matching = 1
+		}
+	})
+}
+
+// Renders the image at half resolution by merging every 2x2 block of the sensor without demosaic,
+// roughly four times faster than full size processing.
+func WithHalfSize() Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.half_size = 1
+	})
+}
+
+// Scales the processed image down to fit in maxDim x maxDim with the resampling filter, keeping its aspect ratio.
+// Applies to the imports and to the JPEG and PNG exports, PPM and TIFF exports are written by libraw at full size.
+// The filter is also used by the functions taking a maximum size, e.g. ImportRawPreview and ExportJPEG, with maxDim 0.
+// Use WithHalfSize as well when maxDim is at most half of the image size, for much faster processing.
+func WithResize(maxDim int, filter ResizeFilter) Option {
+	return func(o *options) {
+		o.resize = resizeSpec{maxDim: maxDim, filter: filter}
+	}
+}
+
+// Range of the exposure shift supported by libraw, in linear scale (-2 to +3 EV)
+const (
+	minExposureShift = 0.25
+	maxExposureShift = 8
+)
+
+// Shifts the exposure by ev stops in linear raw space before demosaic, libraw supports -2 to +3 EV.
+// Highlight preservation (0-1) protects the highlights from clipping when brightening.
+// Values out of range fail the processing with ErrInvalidOption.
+func WithExposure(ev float64, preserveHighlights float64) Option {
+	return func(o *options) {
+		shift := math.Pow(2, ev)
+		switch {
+		case !(shift >= minExposureShift && shift <= maxExposureShift):
+			o.fail(fmt.Errorf("exposure shift [%v] EV is out of the -2 to +3 EV range with [%w]", ev, ErrInvalidOption))
+		case !(preserveHighlights >= 0 && preserveHighlights <= 1):
+			o.fail(fmt.Errorf("highlight preservation [%v] is out of the 0-1 range with [%w]", preserveHighlights, ErrInvalidOption))
+		}
+		o.params = append(o.params, func(p *C.libraw_output_params_t) {
+			p.exp_correc = 1
+			p.exp_shift = C.float(shift)
+			p.exp_preser = C.float(preserveHighlights)
+		})
+	}
+}
+
+type FBDDNoiseReduction int
+
+const (
+	FBDDOff FBDDNoiseReduction = iota
+	FBDDLight
+	FBDDFull
+)
+
+// Enables wavelet denoising with the given threshold, typically between 100 and 1000.
+func WithWaveletDenoise(threshold float64) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.threshold = C.float(threshold)
+	})
+}
+
+// Enables FBDD noise reduction before demosaic.
+func WithFBDDNoiseReduction(mode FBDDNoiseReduction) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.fbdd_noiserd = C.int(mode)
+	})
+}
+
+// Produces linear, scene-referred 16-bit output: gamma 1.0 without toe slope and no automatic brightening,
+// like dcraw -4. Used for HDR merging and scientific processing.
+func WithLinearOutput() Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.gamm[0] = 1
+		p.gamm[1] = 1
+		p.no_auto_bright = 1
+		p.output_bps = 16
+	})
+}
+
+// Corrects lateral chromatic aberration by scaling the red and blue channels, like dcraw -C.
+// Values are close to 1, e.g. 0.999 or 1.001.
+func WithCACorrection(red float64, blue float64) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		if red > 0 {
+			p.aber[0] = C.double(1 / red)
+		}
+		if blue > 0 {
+			p.aber[2] = C.double(1 / blue)
+		}
+	})
+}
+
+// Processes only the region of interest at x, y with the given width and height, in visible sensor coordinates,
+// saving time and memory on large sensors. Invalid regions fail with ErrBadCrop.
+func WithCrop(x int, y int, width int, height int) Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.cropbox = [4]C.uint{C.uint(x), C.uint(y), C.uint(width), C.uint(height)}
+	})
+}
+
+// Selects the frame to decode from RAW files containing multiple frames (see Metadata.RawCount), 0 by default.
+// Unlike the processing options, it takes effect when the file is opened.
+func WithShot(index int) Option {
+	return func(o *options) {
+		o.shot = index
+	}
+}
+
+// Crops DNG files to their DefaultCrop area, removing the border pixels smartphone DNGs keep for demosaic and lens
+// correction. Like WithShot, it takes effect when the file is opened. Requires libraw 0.20 or newer.
+func WithDNGDefaultCrop() Option {
+	return func(o *options) {
+		o.dngDefaultCrop = true
+	}
+}
+
+// Brightens DNG files by their baseline exposure (see DNGInfo), which libraw ignores. Smartphone DNGs, e.g. Apple
+// ProRAW, are stored 1-2 EV darker than they are meant to be shown. Combined with WithExposure, the shifts add up.
+// Other formats are not affected.
+func WithBaselineExposure() Option {
+	return func(o *options) {
+		o.baselineExposure = true
+	}
+}
+
+// Limits the memory used for decoding and processing the image to mb megabytes, overriding SetDefaultMemoryLimit.
+// Images exceeding the limit fail with ErrMemoryLimit. Like WithShot, it takes effect when the file is opened.
+func WithMemoryLimit(mb int) Option {
+	return func(o *options) {
+		o.memoryLimit = mb
+	}
+}
+
+// Logs the stage timings, warnings and fallbacks of processing the image with logger, overriding SetLogger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Aborts decoding the image when ctx is cancelled or its deadline passes, returning the context error, for the
+// functions without a context parameter (ExportJPEG, ExportTIFF, ...).
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+func withPath(path string, set func(*C.libraw_output_params_t, *C.char)) Option {
+	return func(o *options) {
+		o.paths = append(o.paths, pathParam{path: path, set: set})
+	}
+}
+
+// Sets the ICC profile of the output image, the profile is embedded in TIFF exports. Requires libraw built with LCMS.
+func WithOutputProfile(path string) Option {
+	return withPath(path, func(p *C.libraw_output_params_t, s *C.char) {
+		p.output_profile = s
+	})
+}
+
+// Sets the ICC profile describing the camera colors instead of the built-in color matrices. Requires libraw built with LCMS.
+func WithCameraProfile(path string) Option {
+	return withPath(path, func(p *C.libraw_output_params_t, s *C.char) {
+		p.camera_profile = s
+	})
+}
+
+// Uses the ICC profile embedded in the RAW file (see Processor.ICCProfile) as camera profile, if present.
+func WithEmbeddedCameraProfile() Option {
+	return WithCameraProfile("embed")
+}
+
+// Interpolates the stuck and hot pixels listed in the dcraw style bad pixel map file at path (see BuildBadPixelMap).
+func WithBadPixels(path string) Option {
+	return withPath(path, func(p *C.libraw_output_params_t, s *C.char) {
+		p.bad_pixels = s
+	})
+}
+
+func withTIFF() Option {
+	return withParams(func(p *C.libraw_output_params_t) {
+		p.output_tiff = 1
+	})
+}
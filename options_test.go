@@ -0,0 +1,426 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The options set the matching libraw processing parameters
+func TestOptionsParams(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+
+	for name, test := range map[string]struct {
+		opt   Option
+		check func(p *Processor) bool
+	}{
+		"camera white balance": {WithCameraWhiteBalance(), func(p *Processor) bool {
+			return p.handle.params.use_camera_wb == 1 && p.handle.params.use_auto_wb == 0
+		}},
+		"auto white balance": {WithAutoWhiteBalance(), func(p *Processor) bool {
+			return p.handle.params.use_auto_wb == 1 && p.handle.params.use_camera_wb == 0
+		}},
+		"multipliers": {WithWhiteBalanceMultipliers(2, 1, 1.5, 1), func(p *Processor) bool {
+			return p.handle.params.user_mul[0] == 2 && p.handle.params.user_mul[2] == 1.5
+		}},
+		"grey box": {WithGreyBox(10, 20, 30, 40), func(p *Processor) bool {
+			box := p.handle.params.greybox
+			return box[0] == 10 && box[1] == 20 && box[2] == 30 && box[3] == 40 && p.handle.params.use_auto_wb == 1
+		}},
+		"dcb": {WithDCB(3, true), func(p *Processor) bool {
+			return p.handle.params.dcb_iterations == 3 && p.handle.params.dcb_enhance_fl == 1
+		}},
+		"exposure": {WithExposure(1, 0.5), func(p *Processor) bool {
+			params := p.handle.params
+			return params.exp_correc == 1 && params.exp_shift == 2 && params.exp_preser == 0.5
+		}},
+		"four color rgb": {WithFourColorRGB(true), func(p *Processor) bool {
+			return p.handle.params.four_color_rgb == 1
+		}},
+		"green matching": {WithGreenMatching(true), func(p *Processor) bool {
+			return p.handle.params.green_matching == 1
+		}},
+		"wavelet denoise": {WithWaveletDenoise(100), func(p *Processor) bool {
+			return p.handle.params.threshold == 100
+		}},
+		"fbdd": {WithFBDDNoiseReduction(FBDDFull), func(p *Processor) bool {
+			return p.handle.params.fbdd_noiserd == 2
+		}},
+		"linear output": {WithLinearOutput(), func(p *Processor) bool {
+			params := p.handle.params
+			return params.gamm[0] == 1 && params.gamm[1] == 1 && params.no_auto_bright == 1 && params.output_bps == 16
+		}},
+		"auto bright disabled": {WithAutoBright(false), func(p *Processor) bool {
+			return p.handle.params.no_auto_bright == 1
+		}},
+		"auto bright enabled": {WithAutoBright(true), func(p *Processor) bool {
+			return p.handle.params.no_auto_bright == 0
+		}},
+		"auto bright threshold": {WithAutoBrightThreshold(0.001), func(p *Processor) bool {
+			return float64(p.handle.params.auto_bright_thr) > 0.00099 && float64(p.handle.params.auto_bright_thr) < 0.00101
+		}},
+		"chromatic aberration": {WithCACorrection(0.5, 2), func(p *Processor) bool {
+			return p.handle.params.aber[0] == 2 && p.handle.params.aber[2] == 0.5
+		}},
+		"chromatic aberration of red only": {WithCACorrection(0.5, 0), func(p *Processor) bool {
+			return p.handle.params.aber[0] == 2 && p.handle.params.aber[2] == 1
+		}},
+		"crop": {WithCrop(8, 6, 40, 30), func(p *Processor) bool {
+			box := p.handle.params.cropbox
+			return box[0] == 8 && box[1] == 6 && box[2] == 40 && box[3] == 30
+		}},
+		"color space": {WithColorSpace(ColorSpaceAdobe), func(p *Processor) bool {
+			return p.handle.params.output_color == 2
+		}},
+		"gamma": {WithGamma(2, 0), func(p *Processor) bool {
+			return p.handle.params.gamm[0] == 0.5 && p.handle.params.gamm[1] == 0
+		}},
+		"brightness": {WithBrightness(1.5), func(p *Processor) bool {
+			return p.handle.params.bright == 1.5
+		}},
+		"highlight": {WithHighlightMode(HighlightBlend), func(p *Processor) bool {
+			return p.handle.params.highlight == 2
+		}},
+		"bit depth": {WithBitDepth(16), func(p *Processor) bool {
+			return p.handle.params.output_bps == 16
+		}},
+		"demosaic": {WithDemosaic(DemosaicDHT), func(p *Processor) bool {
+			return p.handle.params.user_qual == 11
+		}},
+	} {
+		processor.Recycle()
+		newOptions([]Option{test.opt}).apply(processor)
+		if !test.check(processor) {
+			t.Errorf("%v option did not set its parameters", name)
+		}
+	}
+}
+
+// Returns the mean sample of the 8-bit image at path imported with opts, without automatic brightening
+func meanLevel(t *testing.T, path string, opts ...Option) float64 {
+	t.Helper()
+	img, err := ImportRawWithOptions(path, append(opts, WithAutoBright(false))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := img.(*image.RGBA)
+	sum := 0
+	for _, v := range rgba.Pix {
+		sum += int(v)
+	}
+	return float64(sum) / float64(len(rgba.Pix))
+}
+
+// Options change the rendering, the defaults are used without them
+func TestImportRawWithOptions(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	mean := func(opts ...Option) float64 { return meanLevel(t, path, opts...) }
+	if dark, bright := mean(WithBrightness(0.5)), mean(WithBrightness(2)); dark >= bright {
+		t.Errorf("mean with brightness 0.5 = %v, not below the one with brightness 2 = %v", dark, bright)
+	}
+}
+
+// Automatic brightening scales a darker frame up to the brightness of a brighter one, without it the levels follow
+// the RAW data
+func TestAutoBright(t *testing.T) {
+	img := testImage("RGGB")
+	bright := writeTestDNG(t, img, testMetadata())
+	for i := range img.Pix {
+		img.Pix[i] /= 2
+	}
+	dark := writeTestDNG(t, img, testMetadata())
+
+	auto := func(path string) float64 {
+		img, err := ImportRawWithOptions(path, WithAutoBright(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := 0
+		for _, v := range img.(*image.RGBA).Pix {
+			sum += int(v)
+		}
+		return float64(sum) / float64(len(img.(*image.RGBA).Pix))
+	}
+	if a, b := auto(dark), auto(bright); math.Abs(a-b) > 0.2*b {
+		t.Errorf("means with auto brightening = %v and %v, want them close", a, b)
+	}
+	if a, b := meanLevel(t, dark), meanLevel(t, bright); a >= 0.9*b {
+		t.Errorf("mean of the dark frame without auto brightening = %v, not below the bright one = %v", a, b)
+	}
+}
+
+// Portrait images are rotated by default, unless disabled or overridden
+func TestAutoRotate(t *testing.T) {
+	metadata := testMetadata()
+	metadata.Orientation = 6
+	portrait := writeTestDNG(t, testImage("RGGB"), metadata)
+	landscape := testDNG(t, "RGGB")
+
+	rotated, sensor := image.Rect(0, 0, testHeight, testWidth), image.Rect(0, 0, testWidth, testHeight)
+	for name, test := range map[string]struct {
+		path string
+		opts []Option
+		want image.Rectangle
+	}{
+		"portrait":              {portrait, nil, rotated},
+		"portrait not rotated":  {portrait, []Option{WithAutoRotate(false)}, sensor},
+		"portrait flipped back": {portrait, []Option{WithUserFlip(1)}, sensor},
+		"landscape":             {landscape, nil, sensor},
+		"landscape flipped":     {landscape, []Option{WithUserFlip(8)}, rotated},
+	} {
+		img, err := ImportRawWithOptions(test.path, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := img.Bounds(); got != test.want {
+			t.Errorf("bounds of %v = %v, want %v", name, got, test.want)
+		}
+	}
+}
+
+// The white balance multipliers scale the color channels
+func TestWhiteBalanceMultipliers(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	means := func(opts ...Option) [3]float64 {
+		img, err := ImportRawWithOptions(path, append(opts, WithAutoBright(false))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		var sums [3]float64
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			for c := range sums {
+				sums[c] += float64(rgba.Pix[i+c])
+			}
+		}
+		return sums
+	}
+	red := means(WithWhiteBalanceMultipliers(2, 1, 0.5, 1))
+	blue := means(WithWhiteBalanceMultipliers(0.5, 1, 2, 1))
+	if red[0] <= blue[0] || red[2] >= blue[2] {
+		t.Errorf("channel sums with red multipliers = %v, with blue multipliers = %v", red, blue)
+	}
+}
+
+func TestParseDemosaic(t *testing.T) {
+	for d, name := range demosaicNames {
+		if d.String() != name {
+			t.Errorf("String() of %v = %q, want %q", int(d), d.String(), name)
+		}
+		if got, err := ParseDemosaic(strings.ToUpper(name)); err != nil || got != d {
+			t.Errorf("ParseDemosaic(%q) = %v, %v, want %v", strings.ToUpper(name), got, err, d)
+		}
+	}
+	if _, err := ParseDemosaic("bilinear"); err == nil {
+		t.Errorf("ParseDemosaic of an unknown name succeeded")
+	}
+	if got := Demosaic(99).String(); got != "demosaic(99)" {
+		t.Errorf("String() of an unknown algorithm = %q", got)
+	}
+}
+
+// Every algorithm renders the full image, the ones missing from the linked libraw fall back to AHD
+func TestDemosaicAlgorithms(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	for d := range demosaicNames {
+		img, err := ImportRawWithOptions(path, WithDemosaic(d))
+		if err != nil {
+			t.Errorf("import with %v failed: %v", d, err)
+			continue
+		}
+		if got := img.Bounds(); got != image.Rect(0, 0, testWidth, testHeight) {
+			t.Errorf("bounds with %v = %v, want %vx%v", d, got, testWidth, testHeight)
+		}
+	}
+}
+
+// The exposure is shifted in linear space, shifts out of the libraw range are rejected
+func TestWithExposure(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	mean := func(opts ...Option) float64 { return meanLevel(t, path, opts...) }
+	if dark, bright := mean(WithExposure(-1, 0)), mean(WithExposure(1, 0)); dark >= bright {
+		t.Errorf("mean at -1 EV = %v, not below the one at +1 EV = %v", dark, bright)
+	}
+
+	for _, opt := range []Option{WithExposure(-3, 0), WithExposure(4, 0), WithExposure(0, 2)} {
+		if _, err := ImportRawWithOptions(path, opt); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("import with an exposure out of range: %v, want %v", err, ErrInvalidOption)
+		}
+	}
+}
+
+// Noise reduction smooths the differences of neighbour pixels of a noisy image
+func TestNoiseReduction(t *testing.T) {
+	img := testImage("RGGB")
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint16(min(int(img.Pix[i])+int(seed>>24), int(img.White)))
+	}
+	path := writeTestDNG(t, img, testMetadata())
+	roughness := func(opts ...Option) int {
+		img, err := ImportRawWithOptions(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		sum := 0
+		for i := 4; i < len(rgba.Pix); i++ {
+			sum += max(int(rgba.Pix[i])-int(rgba.Pix[i-4]), int(rgba.Pix[i-4])-int(rgba.Pix[i]))
+		}
+		return sum
+	}
+	noisy := roughness()
+	if got := roughness(WithWaveletDenoise(1000)); got >= noisy {
+		t.Errorf("roughness with wavelet denoising = %v, not below %v", got, noisy)
+	}
+	// FBDD only removes the outliers of the green channel
+	if got := roughness(WithFBDDNoiseReduction(FBDDFull)); got > noisy {
+		t.Errorf("roughness with FBDD noise reduction = %v, above %v", got, noisy)
+	}
+}
+
+// Green matching and four-color interpolation do not add structure to a flat image with imbalanced greens
+func TestGreenImbalance(t *testing.T) {
+	img := testImage("RGGB")
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			// the green of the red rows is 10% brighter than the green of the blue rows
+			v := 0x800
+			if x%2 == 1 && y%2 == 0 {
+				v = 0x8cc
+			}
+			img.Pix[y*img.Width+x] = uint16(v)
+		}
+	}
+	path := writeTestDNG(t, img, testMetadata())
+	roughness := func(opts ...Option) int {
+		img, err := ImportRawWithOptions(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		sum := 0
+		for i := 4; i < len(rgba.Pix); i++ {
+			sum += max(int(rgba.Pix[i])-int(rgba.Pix[i-4]), int(rgba.Pix[i-4])-int(rgba.Pix[i]))
+		}
+		return sum
+	}
+	imbalanced := roughness()
+	for name, opt := range map[string]Option{"green matching": WithGreenMatching(true), "four color": WithFourColorRGB(true)} {
+		if got := roughness(opt); got > imbalanced {
+			t.Errorf("roughness with %v = %v, above %v", name, got, imbalanced)
+		}
+	}
+}
+
+// Linear output is darker in the mid tones than the default gamma curve
+func TestLinearOutput(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	mean := func(opts ...Option) float64 {
+		img, err := ImportRawWithOptions(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba, ok := img.(*image.NRGBA64)
+		if !ok {
+			t.Fatalf("image is %T, want 16-bit", img)
+		}
+		sum := 0
+		for i := 0; i+1 < len(rgba.Pix); i += 2 {
+			sum += int(rgba.Pix[i])<<8 | int(rgba.Pix[i+1])
+		}
+		return float64(sum) / float64(len(rgba.Pix)/2)
+	}
+	if linear, gamma := mean(WithLinearOutput()), mean(WithBitDepth(16), WithAutoBright(false)); linear >= gamma {
+		t.Errorf("mean of linear output = %v, not below the one with gamma = %v", linear, gamma)
+	}
+}
+
+func TestParseColorSpace(t *testing.T) {
+	for c, name := range colorSpaceNames {
+		if got := ColorSpace(c).String(); got != name {
+			t.Errorf("String() of %v = %q, want %q", c, got, name)
+		}
+		if got, err := ParseColorSpace(strings.ToUpper(name)); err != nil || got != ColorSpace(c) {
+			t.Errorf("ParseColorSpace(%q) = %v, %v, want %v", strings.ToUpper(name), got, err, ColorSpace(c))
+		}
+	}
+	if _, err := ParseColorSpace("cmyk"); err == nil {
+		t.Errorf("ParseColorSpace of an unknown name succeeded")
+	}
+	if got := ColorSpace(-1).String(); got != "colorspace(-1)" {
+		t.Errorf("String() of an unknown color space = %q", got)
+	}
+}
+
+// The image is rendered in every output color space
+func TestColorSpaces(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	for c := range colorSpaceNames {
+		img, err := ImportRawWithOptions(path, WithColorSpace(ColorSpace(c)))
+		if err != nil {
+			t.Errorf("import in %v failed: %v", ColorSpace(c), err)
+			continue
+		}
+		if got := img.Bounds(); got != image.Rect(0, 0, testWidth, testHeight) {
+			t.Errorf("bounds in %v = %v, want %vx%v", ColorSpace(c), got, testWidth, testHeight)
+		}
+	}
+}
+
+func TestCACorrection(t *testing.T) {
+	img, err := ImportRawWithOptions(testDNG(t, "RGGB"), WithCACorrection(0.999, 1.001))
+	if err != nil {
+		t.Fatalf("import with chromatic aberration correction failed: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, testWidth, testHeight) {
+		t.Errorf("bounds = %v, want %vx%v", got, testWidth, testHeight)
+	}
+}
+
+func TestWithCrop(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	img, err := ImportRawWithOptions(path, WithCrop(8, 6, 40, 30))
+	if err != nil {
+		t.Fatalf("import of a region failed: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 40, 30) {
+		t.Errorf("bounds = %v, want 40x30", got)
+	}
+	if _, err := ImportRawWithOptions(path, WithCrop(2*testWidth, 0, 10, 10)); !errors.Is(err, ErrBadCrop) {
+		t.Errorf("import of a region outside the image: %v, want %v", err, ErrBadCrop)
+	}
+}
+
+// The context option aborts the export functions without a context parameter
+func TestWithContext(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir := t.TempDir()
+	for name, export := range map[string]func(output string) error{
+		"jpeg": func(output string) error { return ExportJPEG(path, output, 0, 0, WithContext(ctx)) },
+		"png":  func(output string) error { return ExportPNG(path, output, WithContext(ctx)) },
+		"tiff": func(output string) error { return ExportTIFF(path, output, WithContext(ctx)) },
+	} {
+		output := filepath.Join(dir, name)
+		if err := export(output); !errors.Is(err, context.Canceled) {
+			t.Errorf("%v export with a cancelled context: %v, want %v", name, err, context.Canceled)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("%v export with a cancelled context left its output: %v", name, err)
+		}
+	}
+}
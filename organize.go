@@ -0,0 +1,213 @@
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Placeholders of an Organizer template
+var templateToken = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// Organizer moves or copies RAW files into a directory layout built from their metadata, the usual ingest step after
+// copying a memory card. The layout is given as a template of the path relative to the destination root, with
+// placeholders filled from the metadata of every file:
+//
+//	{date}    capture date as 2006-01-02    {year} {month} {day}    capture date parts
+//	{time}    capture time as 150405        {make} {camera}         camera maker and model
+//	{serial}  camera body serial number     {lens}                  lens model
+//	{name}    original file name without extension
+//	{ext}     original extension in lower case, without the dot
+//	{counter} 4 digit sequence number of the file in its directory, in capture time order
+//
+// e.g. "{date}/{camera}/{counter}.{ext}". Unknown values are filled with "unknown". Existing files are never replaced.
+type Organizer struct {
+	Template string
+	// Copies the files instead of moving them
+	Copy bool
+	// Only computes the destinations without touching the files
+	DryRun bool
+}
+
+// OrganizeResult is the destination of a file organized by an Organizer.
+type OrganizeResult struct {
+	Source      string
+	Destination string
+	Err         error
+}
+
+type organizedFile struct {
+	index    int
+	captured time.Time
+	values   map[string]string
+}
+
+// Moves or copies the RAW files of paths under root with the template, see Organizer. The results are in the order of
+// paths, a failing file does not stop the others. The error is only set for an invalid template.
+func (o Organizer) Organize(paths []string, root string) ([]OrganizeResult, error) {
+	for _, token := range templateToken.FindAllStringSubmatch(o.Template, -1) {
+		if !knownToken(token[1]) {
+			return nil, fmt.Errorf("unknown placeholder [%v] in template [%v]", token[0], o.Template)
+		}
+	}
+	if strings.TrimSpace(o.Template) == "" {
+		return nil, fmt.Errorf("empty template")
+	}
+
+	results := make([]OrganizeResult, len(paths))
+	// files grouped by their directory, the counter is assigned within a directory
+	groups := map[string][]organizedFile{}
+	for i, path := range paths {
+		results[i].Source = path
+		metadata, err := ExtractMetadata(path)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		file := organizedFile{index: i, captured: metadata.Time(), values: templateValues(path, metadata)}
+		dir := filepath.Dir(o.expand(file.values))
+		groups[dir] = append(groups[dir], file)
+	}
+
+	// destinations taken by the files organized before, for templates mapping several files to the same path
+	taken := map[string]string{}
+	dirs := make([]string, 0, len(groups))
+	for dir := range groups {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		files := groups[dir]
+		sort.SliceStable(files, func(a, b int) bool { return files[a].captured.Before(files[b].captured) })
+		for n, file := range files {
+			file.values["counter"] = fmt.Sprintf("%04d", n+1)
+			destination := filepath.Join(root, o.expand(file.values))
+			results[file.index].Destination = destination
+			if other, ok := taken[destination]; ok {
+				results[file.index].Err = fmt.Errorf("destination [%v] is already used by [%v]", destination, other)
+				continue
+			}
+			taken[destination] = paths[file.index]
+			if !o.DryRun {
+				results[file.index].Err = o.transfer(paths[file.index], destination)
+			}
+		}
+	}
+	return results, nil
+}
+
+func knownToken(name string) bool {
+	switch name {
+	case "date", "year", "month", "day", "time", "make", "camera", "serial", "lens", "name", "ext", "counter":
+		return true
+	}
+	return false
+}
+
+// Fills the template, the counter is left in place until it is known
+func (o Organizer) expand(values map[string]string) string {
+	path := templateToken.ReplaceAllStringFunc(o.Template, func(token string) string {
+		if value, ok := values[token[1:len(token)-1]]; ok {
+			return value
+		}
+		return token
+	})
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+func templateValues(path string, m Metadata) map[string]string {
+	ext := filepath.Ext(path)
+	values := map[string]string{
+		"make":   pathSafe(m.Camera.Make),
+		"camera": pathSafe(m.Camera.Model),
+		"serial": pathSafe(m.Camera.Serial),
+		"lens":   pathSafe(m.Lens.Model),
+		"name":   pathSafe(strings.TrimSuffix(filepath.Base(path), ext)),
+		"ext":    pathSafe(strings.ToLower(strings.TrimPrefix(ext, "."))),
+	}
+	if captured := m.Time(); !captured.IsZero() {
+		values["date"] = captured.Format("2006-01-02")
+		values["year"] = captured.Format("2006")
+		values["month"] = captured.Format("01")
+		values["day"] = captured.Format("02")
+		values["time"] = captured.Format("150405")
+	} else {
+		for _, name := range []string{"date", "year", "month", "day", "time"} {
+			values[name] = "unknown"
+		}
+	}
+	return values
+}
+
+// Replaces the characters not allowed in file names on common file systems
+func pathSafe(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(value))
+	if value == "" || value == "." || value == ".." {
+		return "unknown"
+	}
+	return value
+}
+
+// Moves or copies source to destination, failing if destination exists
+func (o Organizer) transfer(source string, destination string) error {
+	if _, err := os.Lstat(destination); err == nil {
+		return fmt.Errorf("destination [%v] already exists", destination)
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for [%v] with [%w]", destination, err)
+	}
+	if !o.Copy {
+		err := os.Rename(source, destination)
+		if err == nil || !errors.Is(err, syscall.EXDEV) {
+			return err
+		}
+		// different file systems, copied and removed below
+	}
+	if err := copyFile(source, destination); err != nil {
+		return err
+	}
+	if !o.Copy {
+		return os.Remove(source)
+	}
+	return nil
+}
+
+// Copies the content and the modification time of source to a new file at destination
+func copyFile(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("input file [%v] does not exist", source)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read file [%v] with [%w]", source, err)
+	}
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create file [%v] with [%w]", destination, err)
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(destination)
+		return fmt.Errorf("failed to copy [%v] to [%v] with [%w]", source, destination, err)
+	}
+	if err = out.Close(); err != nil {
+		os.Remove(destination)
+		return fmt.Errorf("failed to copy [%v] to [%v] with [%w]", source, destination, err)
+	}
+	return os.Chtimes(destination, info.ModTime(), info.ModTime())
+}
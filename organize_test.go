@@ -0,0 +1,172 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPathSafe(t *testing.T) {
+	for value, want := range map[string]string{
+		"Canon EOS R5":      "Canon EOS R5",
+		" NIKON Z 6 ":       "NIKON Z 6",
+		"EF 24-70mm f/2.8L": "EF 24-70mm f_2.8L",
+		`a<b>c:d"e\f|g?h*i`: "a_b_c_d_e_f_g_h_i",
+		"tab\there":         "tab_here",
+		"":                  "unknown",
+		"..":                "unknown",
+	} {
+		if got := pathSafe(value); got != want {
+			t.Errorf("pathSafe(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestTemplateValues(t *testing.T) {
+	captured := time.Date(2024, 5, 1, 10, 20, 30, 0, time.Local)
+	m := Metadata{Timestamp: captured.Unix(), Camera: Camera{Make: "Canon", Model: "EOS R5"}, Lens: Lens{Model: "RF50mm F1.8 STM"}}
+	values := templateValues(filepath.Join("card", "IMG_0001.CR3"), m)
+	for name, want := range map[string]string{
+		"date": "2024-05-01", "year": "2024", "month": "05", "day": "01", "time": "102030", "make": "Canon",
+		"camera": "EOS R5", "serial": "unknown", "lens": "RF50mm F1.8 STM", "name": "IMG_0001", "ext": "cr3",
+	} {
+		if values[name] != want {
+			t.Errorf("{%v} = %q, want %q", name, values[name], want)
+		}
+	}
+
+	values = templateValues("noext", Metadata{})
+	for _, name := range []string{"date", "time", "make", "ext"} {
+		if values[name] != "unknown" {
+			t.Errorf("{%v} without metadata = %q, want unknown", name, values[name])
+		}
+	}
+}
+
+func TestOrganizerExpand(t *testing.T) {
+	values := map[string]string{"date": "2024-05-01", "camera": "EOS R5", "ext": "cr3"}
+	o := Organizer{Template: "{date}/{camera}/{counter}.{ext}"}
+	if got, want := o.expand(values), filepath.Join("2024-05-01", "EOS R5", "{counter}.cr3"); got != want {
+		t.Errorf("expand = %q, want %q with the counter left in place", got, want)
+	}
+
+	for _, template := range []string{"", "  ", "{date}/{unknown}.{ext}"} {
+		if _, err := (Organizer{Template: template}).Organize(nil, t.TempDir()); err == nil {
+			t.Errorf("Organize succeeded with template %q", template)
+		}
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.dng")
+	if err := os.WriteFile(source, []byte("raw data"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC)
+	if err := os.Chtimes(source, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	destination := filepath.Join(dir, "copy.dng")
+	if err := copyFile(source, destination); err != nil {
+		t.Fatalf("copyFile failed: %v", err)
+	}
+	data, err := os.ReadFile(destination)
+	if err != nil || string(data) != "raw data" {
+		t.Errorf("copy = %q, %v, want the source content", data, err)
+	}
+	if info, err := os.Stat(destination); err != nil || !info.ModTime().Equal(modified) {
+		t.Errorf("copy modification time = %v, want %v", info.ModTime(), modified)
+	}
+
+	// existing files are not replaced
+	if err := copyFile(source, destination); err == nil {
+		t.Errorf("copyFile replaced an existing file")
+	}
+	if err := copyFile(filepath.Join(dir, "missing.dng"), filepath.Join(dir, "other.dng")); err == nil {
+		t.Errorf("copyFile succeeded on a missing file")
+	}
+}
+
+// Returns the path of a test DNG captured at wall clock time captured
+func capturedDNG(t *testing.T, captured time.Time) string {
+	t.Helper()
+	metadata := testMetadata()
+	metadata.Timestamp = captured.Unix()
+	return writeTestDNG(t, testImage("RGGB"), metadata)
+}
+
+func TestOrganize(t *testing.T) {
+	morning := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	first, second := capturedDNG(t, morning), capturedDNG(t, morning.Add(time.Hour))
+	next := capturedDNG(t, morning.AddDate(0, 0, 1))
+	missing := filepath.Join(t.TempDir(), "missing.dng")
+	// the counter follows the capture time, not the order of the files
+	paths := []string{second, missing, next, first}
+	root := t.TempDir()
+	want := []string{
+		filepath.Join(root, "2024-05-01", "Test", "0002.dng"),
+		"",
+		filepath.Join(root, "2024-05-02", "Test", "0001.dng"),
+		filepath.Join(root, "2024-05-01", "Test", "0001.dng"),
+	}
+
+	o := Organizer{Template: "{date}/{camera}/{counter}.{ext}", DryRun: true}
+	results, err := o.Organize(paths, root)
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	for i, result := range results {
+		if result.Source != paths[i] || result.Destination != want[i] {
+			t.Errorf("result [%v] = %v -> %v, want %v -> %v", i, result.Source, result.Destination, paths[i], want[i])
+		}
+	}
+	if results[1].Err == nil {
+		t.Errorf("Organize of a missing file succeeded")
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Errorf("dry run created %v entries", len(entries))
+	}
+
+	o.DryRun, o.Copy = false, true
+	if _, err := o.Organize(paths, root); err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	for i, destination := range want {
+		if destination == "" {
+			continue
+		}
+		if _, err := os.Stat(destination); err != nil {
+			t.Errorf("copy of %v is missing: %v", paths[i], err)
+		}
+		if _, err := os.Stat(paths[i]); err != nil {
+			t.Errorf("copied source %v is removed: %v", paths[i], err)
+		}
+	}
+
+	// existing files are not replaced, moving the others removes their source
+	results, _ = Organizer{Template: "{date}/{camera}/{counter}.{ext}"}.Organize([]string{first}, root)
+	if results[0].Err == nil {
+		t.Errorf("Organize replaced an existing file")
+	}
+	results, _ = Organizer{Template: "moved/{name}.{ext}"}.Organize([]string{first}, root)
+	if results[0].Err != nil {
+		t.Fatalf("Organize failed: %v", results[0].Err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("moved source still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "moved", "test.dng")); err != nil {
+		t.Errorf("moved file is missing: %v", err)
+	}
+
+	// files mapped to the same destination are reported
+	results, _ = Organizer{Template: "{camera}.{ext}", DryRun: true}.Organize([]string{second, next}, root)
+	if results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("results of a shared destination = %v, %v, want the second failing", results[0].Err, results[1].Err)
+	}
+}
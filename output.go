@@ -0,0 +1,105 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// OutputOptions controls how the export functions create their output files.
+// By default an existing output file is never replaced and the output directory has to exist.
+type OutputOptions struct {
+	// Replaces an existing output file instead of failing, e.g. for re-rendering a batch. The output is written to a
+	// temporary file first, the existing file is only replaced once the export succeeded.
+	Overwrite bool
+	// Creates the missing parent directories of the output file
+	CreateDirs bool
+	// Writes a temporary file next to the output file and renames it when complete, so an interrupted export
+	// never leaves a truncated output file behind
+	Atomic bool
+}
+
+// Sets how the export functions create the output file.
+func WithOutputOptions(output OutputOptions) Option {
+	return func(o *options) {
+		o.output = output
+	}
+}
+
+// Prepares the file system for writing exportPath, failing early if the file exists and may not be replaced.
+// create and finish check it again when the file is written.
+func (o OutputOptions) prepare(exportPath string) error {
+	if o.CreateDirs {
+		if err := os.MkdirAll(filepath.Dir(exportPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory for [%v] with [%w]", exportPath, err)
+		}
+	}
+	if !o.Overwrite {
+		if _, err := os.Stat(exportPath); err == nil {
+			return fmt.Errorf("output file [%v] already exists", exportPath)
+		}
+	}
+	return nil
+}
+
+// Creates the file the output is written to: a temporary file next to exportPath for atomic output or when replacing
+// an existing file, otherwise exportPath itself, failing if it already exists.
+// finish has to be called with its name once the file is written and closed.
+func (o OutputOptions) create(exportPath string) (*os.File, error) {
+	if err := o.prepare(exportPath); err != nil {
+		return nil, err
+	}
+	if !o.Atomic && !o.Overwrite {
+		out, err := os.OpenFile(exportPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("output file [%v] already exists", exportPath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file [%v] with [%w]", exportPath, err)
+		}
+		return out, nil
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(exportPath), "."+filepath.Base(exportPath)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file for [%v] with [%w]", exportPath, err)
+	}
+	// temporary files are private by default
+	if err = out.Chmod(0o644); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return nil, fmt.Errorf("failed to create temporary file for [%v] with [%w]", exportPath, err)
+	}
+	return out, nil
+}
+
+// Completes the output written to name with err as the result of writing it. The incomplete file is removed on failure,
+// a temporary file is moved to exportPath on success. Without Overwrite, the temporary file is linked to exportPath,
+// which fails if a file was created there in the meantime.
+func (o OutputOptions) finish(name string, exportPath string, err error) error {
+	if err != nil {
+		os.Remove(name)
+		return err
+	}
+	if name == exportPath {
+		return nil
+	}
+	if o.Overwrite {
+		err = os.Rename(name, exportPath)
+	} else {
+		err = os.Link(name, exportPath)
+		os.Remove(name)
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("output file [%v] already exists", exportPath)
+	}
+	if err != nil {
+		os.Remove(name)
+		return fmt.Errorf("failed to move output file to [%v] with [%w]", exportPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputOptions(t *testing.T) {
+	failing := func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("encoding failed")
+	}
+	for name, output := range map[string]OutputOptions{
+		"default":            {},
+		"atomic":             {Atomic: true},
+		"overwrite":          {Overwrite: true},
+		"atomic overwrite":   {Atomic: true, Overwrite: true},
+		"create directories": {CreateDirs: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.jpg")
+			if err := os.WriteFile(path, []byte("previous"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			// a failed export never destroys the existing file
+			if err := encodeFile(path, output, failing); err == nil {
+				t.Error("failed export succeeded")
+			}
+			if data, _ := os.ReadFile(path); string(data) != "previous" {
+				t.Errorf("content after a failed export = %q, want the previous one", data)
+			}
+
+			err := encodeFile(path, output, func(w io.Writer) error {
+				_, err := io.WriteString(w, "new")
+				return err
+			})
+			want := "previous"
+			if output.Overwrite {
+				want = "new"
+			}
+			if (err == nil) != output.Overwrite {
+				t.Errorf("export over an existing file: %v, overwrite %v", err, output.Overwrite)
+			}
+			if data, _ := os.ReadFile(path); string(data) != want {
+				t.Errorf("content after the export = %q, want %q", data, want)
+			}
+		})
+	}
+}
+
+// A file created at the output path while an atomic export is running is not replaced
+func TestOutputOptionsAtomicRace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jpg")
+	err := encodeFile(path, OutputOptions{Atomic: true}, func(w io.Writer) error {
+		if err := os.WriteFile(path, []byte("concurrent"), 0o644); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err == nil {
+		t.Error("atomic export replaced a concurrently created file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "concurrent" {
+		t.Errorf("content after the export = %q, want the concurrent one", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("files after the export = %v, want the output only", entries)
+	}
+}
+
+// An atomic export only shows the output file once it is complete, and leaves nothing behind on failure
+func TestOutputOptionsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.jpg")
+	err := encodeFile(path, OutputOptions{Atomic: true}, func(w io.Writer) error {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("output file exists while being written: %v", err)
+		}
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("atomic export failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("content after the export = %q, want %q", data, "new")
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0o644 {
+		t.Errorf("output file mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o644))
+	}
+
+	failed := filepath.Join(dir, "failed.jpg")
+	err = encodeFile(failed, OutputOptions{Atomic: true}, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("encoding failed")
+	})
+	if err == nil {
+		t.Error("failed export succeeded")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("files after the failed export = %v, want the first output only", entries)
+	}
+}
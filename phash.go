@@ -0,0 +1,59 @@
+package golibraw
+
+import (
+	"image"
+	"math/bits"
+)
+
+// Reads a RAW image file from file system and computes the perceptual hash (dHash) of its embedded preview, or of
+// a half size render if there is no usable preview. Near-duplicate images have hashes with a small HashDistance.
+func PerceptualHash(path string) (uint64, error) {
+	img, err := previewOf(path)
+	if err != nil {
+		return 0, err
+	}
+	return ImageHash(img), nil
+}
+
+// Computes the perceptual difference hash (dHash) of an image: the image is reduced to 9x8 gray pixels,
+// and every bit tells whether a pixel is brighter than its right neighbour.
+func ImageHash(img image.Image) uint64 {
+	gray := grayCells(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray[y*9+x] > gray[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Returns the number of differing bits of two perceptual hashes, values up to about 10 indicate near-duplicates.
+func HashDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Returns the mean luminance of the cells of a cols x rows grid over img, row by row
+func grayCells(img image.Image, cols int, rows int) []float64 {
+	b := img.Bounds()
+	sums := make([]float64, cols*rows)
+	counts := make([]int, cols*rows)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := (y - b.Min.Y) * rows / max(b.Dy(), 1)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			col := (x - b.Min.X) * cols / max(b.Dx(), 1)
+			r, g, bl, _ := img.At(x, y).RGBA()
+			sums[row*cols+col] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			counts[row*cols+col]++
+		}
+	}
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= float64(counts[i])
+		}
+	}
+	return sums
+}
@@ -0,0 +1,59 @@
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// Returns a gray image of the pattern, scaled to width x height
+func scaledPattern(width int, height int, pattern func(x, y float64) uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: pattern(float64(x)/float64(width), float64(y)/float64(height))})
+		}
+	}
+	return img
+}
+
+func TestImageHash(t *testing.T) {
+	darkening := scaledPattern(90, 80, func(x, y float64) uint8 { return uint8(255 * (1 - x)) })
+	if got := ImageHash(darkening); got != ^uint64(0) {
+		t.Errorf("hash of a darkening gradient = %016x, want all bits set", got)
+	}
+	brightening := scaledPattern(90, 80, func(x, y float64) uint8 { return uint8(255 * x) })
+	if got := ImageHash(brightening); got != 0 {
+		t.Errorf("hash of a brightening gradient = %016x, want no bits set", got)
+	}
+
+	scene := func(x, y float64) uint8 { return uint8(128 + 127*math.Sin(10*x)*math.Cos(7*y)) }
+	original, resized := ImageHash(scaledPattern(360, 240, scene)), ImageHash(scaledPattern(90, 60, scene))
+	if d := HashDistance(original, resized); d > 10 {
+		t.Errorf("distance of the resized image = %v, want a near-duplicate", d)
+	}
+	if d := HashDistance(ImageHash(darkening), ImageHash(brightening)); d != 64 {
+		t.Errorf("distance of opposite gradients = %v, want 64", d)
+	}
+	if _, err := PerceptualHash(filepath.Join(t.TempDir(), "missing.dng")); err == nil {
+		t.Errorf("PerceptualHash of a missing file succeeded")
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	for _, test := range []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 0xff, 8},
+		{0xf0f0, 0x0ff0, 8},
+		{^uint64(0), 0, 64},
+	} {
+		if got := HashDistance(test.a, test.b); got != test.want {
+			t.Errorf("HashDistance(%x, %x) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
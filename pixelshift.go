@@ -0,0 +1,241 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Size of the central patch used to find the sensor offsets of the frames
+const pixelShiftPatch = 128
+
+type pixelShiftOffset struct {
+	dy int
+	dx int
+}
+
+// Merges a 4 shot Pixel Shift sequence into a single full color image, where every pixel has its own measured red,
+// green and blue value, without demosaic. The input is either a single multi-frame file (Pentax Pixel Shift Resolution)
+// or the four files of a Sony Pixel Shift Multi Shooting sequence, in any order.
+// The output is white balanced with the camera multipliers and converted to sRGB.
+// 16 shot sequences with half pixel shifts are not supported.
+func MergePixelShift(paths ...string) (*image.NRGBA64, error) {
+	frames, calibration, err := loadPixelShiftFrames(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, frame := range frames[1:] {
+		if frame.VisibleWidth != frames[0].VisibleWidth || frame.VisibleHeight != frames[0].VisibleHeight {
+			return nil, fmt.Errorf("pixel shift frames have different sizes")
+		}
+	}
+	if frames[0].CFA.Width != 2 || frames[0].CFA.Height != 2 {
+		return nil, fmt.Errorf("pixel shift merge requires a Bayer sensor")
+	}
+
+	offsets := pixelShiftOffsets(frames)
+	return mergePixelShift(frames, offsets, calibration), nil
+}
+
+func loadPixelShiftFrames(paths []string) ([]*BayerImage, Calibration, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, Calibration{}, err
+	}
+	defer processor.Close()
+
+	load := func(path string, shot int) (*BayerImage, Metadata, error) {
+		processor.SetShot(shot)
+		if err := processor.Open(path); err != nil {
+			return nil, Metadata{}, err
+		}
+		metadata, err := processor.Metadata()
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		if err := processor.Unpack(); err != nil {
+			return nil, Metadata{}, err
+		}
+		bayer, err := processor.Bayer()
+		return bayer, metadata, err
+	}
+
+	switch len(paths) {
+	case 1:
+		metadata, err := ExtractMetadata(paths[0])
+		if err != nil {
+			return nil, Calibration{}, err
+		}
+		if metadata.RawCount < 4 {
+			return nil, Calibration{}, fmt.Errorf("file [%v] has [%v] frames, pixel shift requires 4", paths[0], metadata.RawCount)
+		}
+		frames := make([]*BayerImage, 4)
+		for shot := range frames {
+			if frames[shot], _, err = load(paths[0], shot); err != nil {
+				return nil, Calibration{}, err
+			}
+		}
+		return frames, metadata.Calibration, nil
+	case 4:
+		type indexed struct {
+			frame    *BayerImage
+			metadata Metadata
+		}
+		loaded := make([]indexed, 0, 4)
+		for _, path := range paths {
+			frame, metadata, err := load(path, 0)
+			if err != nil {
+				return nil, Calibration{}, err
+			}
+			if metadata.PixelShift.Shots > 4 {
+				return nil, Calibration{}, fmt.Errorf("file [%v] is part of a [%v] shot sequence, only 4 shots are supported", path, metadata.PixelShift.Shots)
+			}
+			if len(loaded) > 0 && metadata.PixelShift.GroupID != loaded[0].metadata.PixelShift.GroupID {
+				return nil, Calibration{}, fmt.Errorf("file [%v] is not part of the same pixel shift group", path)
+			}
+			loaded = append(loaded, indexed{frame: frame, metadata: metadata})
+		}
+		sort.SliceStable(loaded, func(i, j int) bool {
+			return loaded[i].metadata.PixelShift.Index < loaded[j].metadata.PixelShift.Index
+		})
+		frames := make([]*BayerImage, 0, 4)
+		for _, l := range loaded {
+			frames = append(frames, l.frame)
+		}
+		return frames, loaded[0].metadata.Calibration, nil
+	default:
+		return nil, Calibration{}, fmt.Errorf("pixel shift requires 1 multi-frame file or 4 files, got [%v]", len(paths))
+	}
+}
+
+// Finds the sensor offset of every frame relative to the first one. The offsets are not recorded consistently by the
+// vendors, so every combination covering the four CFA phases is tried on a central patch: the right one has matching
+// values from the two green frames and no zipper pattern in the red and blue planes.
+func pixelShiftOffsets(frames []*BayerImage) []pixelShiftOffset {
+	candidates := map[[2]int][]pixelShiftOffset{
+		{0, 1}: {{0, 1}, {0, -1}},
+		{1, 0}: {{1, 0}, {-1, 0}},
+		{1, 1}: {{1, 1}, {1, -1}, {-1, 1}, {-1, -1}},
+	}
+	phases := [][2]int{{0, 1}, {1, 0}, {1, 1}}
+
+	best := []pixelShiftOffset{{0, 0}, {0, 1}, {1, 1}, {1, 0}}
+	bestScore := math.Inf(1)
+	for _, perm := range [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}} {
+		for _, a := range candidates[phases[perm[0]]] {
+			for _, b := range candidates[phases[perm[1]]] {
+				for _, c := range candidates[phases[perm[2]]] {
+					offsets := []pixelShiftOffset{{0, 0}, a, b, c}
+					if score := pixelShiftScore(frames, offsets); score < bestScore {
+						best, bestScore = offsets, score
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+func pixelShiftScore(frames []*BayerImage, offsets []pixelShiftOffset) float64 {
+	w, h := frames[0].VisibleWidth, frames[0].VisibleHeight
+	size := min(pixelShiftPatch, w-2, h-2)
+	top, left := (h-size)/2, (w-size)/2
+
+	score := 0.0
+	var prevR, prevB float64
+	for row := top; row < top+size; row++ {
+		for col := left; col < left+size; col++ {
+			var r, b float64
+			greens := make([]float64, 0, 2)
+			for k, frame := range frames {
+				y, x := clamp(row+offsets[k].dy, 0, h-1), clamp(col+offsets[k].dx, 0, w-1)
+				v := float64(frame.At(y, x))
+				switch frame.CFA.ColorAt(y, x) {
+				case 'R':
+					r = v
+				case 'B':
+					b = v
+				default:
+					greens = append(greens, v)
+				}
+			}
+			if len(greens) != 2 {
+				return math.Inf(1)
+			}
+			score += math.Abs(greens[0] - greens[1])
+			if col > left {
+				score += math.Abs(r-prevR) + math.Abs(b-prevB)
+			}
+			prevR, prevB = r, b
+		}
+	}
+	return score
+}
+
+func mergePixelShift(frames []*BayerImage, offsets []pixelShiftOffset, calibration Calibration) *image.NRGBA64 {
+	w, h := frames[0].VisibleWidth, frames[0].VisibleHeight
+
+	mul := calibration.CamMul
+	if mul[0] <= 0 || mul[1] <= 0 || mul[2] <= 0 {
+		mul = calibration.PreMul
+	}
+	wb := [3]float64{1, 1, 1}
+	if mul[0] > 0 && mul[1] > 0 && mul[2] > 0 {
+		wb = [3]float64{mul[0] / mul[1], 1, mul[2] / mul[1]}
+	}
+
+	matrix := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if len(calibration.RGBCam) >= 3 && calibration.RGBCam[0][0] != 0 {
+		for i := 0; i < 3; i++ {
+			copy(matrix[i][:], calibration.RGBCam[i][:3])
+		}
+	}
+
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for row := 0; row < h; row++ {
+		for col := 0; col < w; col++ {
+			var camera [3]float64
+			greens := 0
+			for k, frame := range frames {
+				y, x := clamp(row+offsets[k].dy, 0, h-1), clamp(col+offsets[k].dx, 0, w-1)
+				black := float64(frame.BlackAt(y, x))
+				scale := float64(frame.White) - black
+				if scale <= 0 {
+					scale = 65535
+				}
+				v := math.Max(float64(frame.At(y, x))-black, 0) / scale
+				switch frame.CFA.ColorAt(y, x) {
+				case 'R':
+					camera[0] = v
+				case 'B':
+					camera[2] = v
+				default:
+					camera[1] += v
+					greens++
+				}
+			}
+			if greens > 0 {
+				camera[1] /= float64(greens)
+			}
+
+			i := img.PixOffset(col, row)
+			for c := 0; c < 3; c++ {
+				v := 0.0
+				for j := 0; j < 3; j++ {
+					v += matrix[c][j] * camera[j] * wb[j]
+				}
+				putUint16(img.Pix[i+2*c:], uint16(srgbGamma(v)*65535+0.5))
+			}
+			putUint16(img.Pix[i+6:], 0xffff)
+		}
+	}
+	return img
+}
+
+func clamp(v int, lo int, hi int) int {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,74 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"math"
+	"testing"
+)
+
+// Colors of a smooth scene, distinct enough in every direction for finding the frame offsets
+func pixelShiftScene(y int, x int) [3]float64 {
+	return [3]float64{
+		500 + 7*float64(x) + 3*float64(y),
+		800 + float64(x*x)/3 + float64(y*y)/4,
+		2000 + 5*float64(y) - 4*float64(x),
+	}
+}
+
+// Returns the frames of the scene taken with the sensor shifted by offsets
+func pixelShiftFrames(size int, offsets []pixelShiftOffset) []*BayerImage {
+	cfa := CFA{Pattern: "RGGB", Width: 2, Height: 2}
+	frames := make([]*BayerImage, len(offsets))
+	for k, offset := range offsets {
+		frame := &BayerImage{
+			Pix:           make([]uint16, size*size),
+			Stride:        size,
+			Width:         size,
+			Height:        size,
+			VisibleWidth:  size,
+			VisibleHeight: size,
+			White:         4095,
+			CFA:           cfa,
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				scene := pixelShiftScene(clamp(y-offset.dy, 0, size-1), clamp(x-offset.dx, 0, size-1))
+				c := map[byte]int{'R': 0, 'G': 1, 'B': 2}[cfa.ColorAt(y, x)]
+				frame.Pix[y*size+x] = uint16(scene[c])
+			}
+		}
+		frames[k] = frame
+	}
+	return frames
+}
+
+func TestMergePixelShift(t *testing.T) {
+	const size = 64
+	frames := pixelShiftFrames(size, []pixelShiftOffset{{0, 0}, {0, 1}, {1, 1}, {1, 0}})
+	offsets := pixelShiftOffsets(frames)
+	img := mergePixelShift(frames, offsets, Calibration{})
+
+	for y := 1; y < size-1; y++ {
+		for x := 1; x < size-1; x++ {
+			scene := pixelShiftScene(y, x)
+			got := img.NRGBA64At(x, y)
+			for c, v := range []uint16{got.R, got.G, got.B} {
+				want := srgbGamma(math.Floor(scene[c])/4095) * 65535
+				if math.Abs(float64(v)-want) > 1 {
+					t.Fatalf("channel %v at %v, %v = %v, want %v with offsets %v", c, x, y, v, want, offsets)
+				}
+			}
+		}
+	}
+}
+
+func TestMergePixelShiftInputs(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	if _, err := MergePixelShift(path, path); err == nil {
+		t.Errorf("MergePixelShift of 2 files succeeded")
+	}
+	if _, err := MergePixelShift(path); err == nil {
+		t.Errorf("MergePixelShift of a single frame file succeeded")
+	}
+}
@@ -0,0 +1,106 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ProcessorPool recycles Processors across requests, saving the cost of initializing and releasing a libraw handle
+// for every image. At most the size of the pool processors are handed out at the same time, Get blocks until one is returned.
+// A ProcessorPool is safe for concurrent use.
+type ProcessorPool struct {
+	slots  chan struct{}
+	idle   chan *Processor
+	mu     sync.Mutex
+	closed bool
+}
+
+// Creates a pool handing out at most size processors at the same time.
+func NewProcessorPool(size int) (*ProcessorPool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid pool size [%v]", size)
+	}
+	return &ProcessorPool{
+		slots: make(chan struct{}, size),
+		idle:  make(chan *Processor, size),
+	}, nil
+}
+
+// Returns an idle processor of the pool, or a new one if there is none. Blocks while the maximum number of processors
+// is in use, until one is returned or ctx is done. The processor has to be returned with Put.
+func (p *ProcessorPool) Get(ctx context.Context) (*Processor, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		<-p.slots
+		return nil, fmt.Errorf("processor pool is closed")
+	}
+
+	select {
+	case processor := <-p.idle:
+		return processor, nil
+	default:
+	}
+	processor, err := NewProcessor()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return processor, nil
+}
+
+// Returns a processor to the pool. The opened image and the settings of the processor are released,
+// so the next user gets it in the same state as a new one.
+func (p *ProcessorPool) Put(processor *Processor) {
+	defer func() { <-p.slots }()
+
+	processor.Recycle()
+	processor.SetContext(nil)
+	processor.SetProgress(nil)
+	processor.SetShot(0)
+	processor.SetDNGDefaultCrop(false)
+	processor.SetMemoryLimit(DefaultMemoryLimit())
+	processor.SetLogger(defaultLogger.Load())
+	processor.SetMetrics(packageMetrics())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		processor.Close()
+		return
+	}
+	select {
+	case p.idle <- processor:
+	default:
+		processor.Close()
+	}
+}
+
+// Closes the idle processors of the pool. Processors in use are closed when they are returned,
+// Get fails after the pool is closed.
+func (p *ProcessorPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for {
+		select {
+		case processor := <-p.idle:
+			processor.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProcessorPool(t *testing.T) {
+	if _, err := NewProcessorPool(0); err == nil {
+		t.Errorf("NewProcessorPool(0) succeeded")
+	}
+
+	pool, err := NewProcessorPool(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err = processor.Open(testDNG(t, "RGGB")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the only processor is in use
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get of a full pool error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the returned processor is recycled
+	pool.Put(processor)
+	recycled, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if recycled != processor {
+		t.Errorf("Get returned a new processor, want the idle one")
+	}
+	if err := recycled.Unpack(); err == nil {
+		t.Errorf("recycled processor still has the image opened")
+	}
+
+	pool.Close()
+	if _, err := pool.Get(context.Background()); err == nil {
+		t.Errorf("Get of a closed pool succeeded")
+	}
+	pool.Put(recycled)
+	if recycled.handle != nil {
+		t.Errorf("processor returned to a closed pool is not closed")
+	}
+}
@@ -0,0 +1,28 @@
+package golibraw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Writes interleaved samples in native byte order as a binary PGM (single channel) or PPM (three channels) image
+func writePNM(w io.Writer, width int, height int, colors int, bits int, data []byte) error {
+	magic := "P6"
+	if colors == 1 {
+		magic = "P5"
+	}
+	if _, err := fmt.Fprintf(w, "%s\n%d %d\n%d\n", magic, width, height, (1<<bits)-1); err != nil {
+		return err
+	}
+	if bits == 16 {
+		// PNM requires big endian samples
+		swapped := make([]byte, len(data))
+		for i := 0; i+1 < len(data); i += 2 {
+			binary.BigEndian.PutUint16(swapped[i:], binary.NativeEndian.Uint16(data[i:]))
+		}
+		data = swapped
+	}
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,31 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWritePNM(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writePNM(&buf, 2, 1, 3, 8, []byte{1, 2, 3, 4, 5, 6}); err != nil {
+		t.Fatalf("writePNM failed: %v", err)
+	}
+	if want := "P6\n2 1\n255\n\x01\x02\x03\x04\x05\x06"; buf.String() != want {
+		t.Errorf("8-bit PPM = %q, want %q", buf.String(), want)
+	}
+
+	data := make([]byte, 4)
+	binary.NativeEndian.PutUint16(data, 0x1234)
+	binary.NativeEndian.PutUint16(data[2:], 0xabcd)
+	buf.Reset()
+	if err := writePNM(&buf, 2, 1, 1, 16, data); err != nil {
+		t.Fatalf("writePNM failed: %v", err)
+	}
+	if want := "P5\n2 1\n65535\n\x12\x34\xab\xcd"; buf.String() != want {
+		t.Errorf("16-bit PGM = %q, want %q", buf.String(), want)
+	}
+	if binary.NativeEndian.Uint16(data) != 0x1234 {
+		t.Errorf("writePNM changed the samples of the caller")
+	}
+}
@@ -0,0 +1,474 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <stdlib.h>
+// #include <libraw/libraw.h>
+//
+// // The parameters of reading the raw data were moved to rawparams in libraw 0.21
+// static void set_shot_select(libraw_data_t *lr, unsigned shot) {
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// 	lr->rawparams.shot_select = shot;
+// #else
+// 	lr->params.shot_select = shot;
+// #endif
+// }
+//
+// // The memory limit is a compile time constant before libraw 0.20
+// static void set_max_raw_memory_mb(libraw_data_t *lr, unsigned mb) {
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// 	lr->rawparams.max_raw_memory_mb = mb;
+// #elif LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 20)
+// 	lr->params.max_raw_memory_mb = mb;
+// #endif
+// }
+//
+// // The DNG default crop is a raw option since libraw 0.21, a processing option in 0.20 and not supported before
+// static void set_dng_default_crop(libraw_data_t *lr, int enabled) {
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// 	lr->rawparams.options &= ~LIBRAW_RAWOPTIONS_USE_DNG_DEFAULT_CROP;
+// 	if (enabled)
+// 		lr->rawparams.options |= LIBRAW_RAWOPTIONS_USE_DNG_DEFAULT_CROP;
+// #elif LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 20)
+// 	lr->params.raw_processing_options &= ~LIBRAW_PROCESSING_USE_DNG_DEFAULT_CROP;
+// 	if (enabled)
+// 		lr->params.raw_processing_options |= LIBRAW_PROCESSING_USE_DNG_DEFAULT_CROP;
+// #endif
+// }
+import "C"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"runtime/cgo"
+	"time"
+	"unsafe"
+)
+
+// Processor wraps a single libraw handle, which can be reused for processing many RAW images one after the other,
+// saving the cost of initializing libraw for every image.
+// A Processor owns all the C memory allocated for it (libraw handle, input buffers, C strings), which is released
+// by Recycle and Close. It is not safe for concurrent use, Close has to be called when it is no longer needed.
+type Processor struct {
+	handle   *C.libraw_data_t
+	defaults C.libraw_output_params_t
+	buffer   unsafe.Pointer
+	cstrings []*C.char
+	path     string
+	dataSize int64
+	ctx      context.Context
+	progress ProgressFunc
+	self     cgo.Handle
+	// memory limit in megabytes
+	memoryLimit int
+	// frame decoded from files containing multiple frames, see SetShot
+	shot int
+	// crop DNG files to their DefaultCrop area, see SetDNGDefaultCrop
+	dngDefaultCrop bool
+	// first data error of the opened image and the panic of a callback, reported instead of crashing
+	dataErr *DataError
+	fault   error
+	// logger and metrics callback reporting stage timings, and the warnings and data error of the opened image already logged
+	logger        *slog.Logger
+	metrics       MetricsFunc
+	logged        Warnings
+	loggedDataErr bool
+	// size the processed image is scaled down to by Image
+	resize resizeSpec
+	// Image returns grayscale, see WithMonochrome
+	monochrome bool
+}
+
+// Creates a new Processor with an initialized libraw handle.
+func NewProcessor() (*Processor, error) {
+	handle := lrInit()
+	if handle == nil {
+		return nil, fmt.Errorf("failed to initialize libraw")
+	}
+	p := &Processor{handle: handle, defaults: handle.params, logger: defaultLogger.Load(), metrics: packageMetrics()}
+	p.registerProgress()
+	p.SetMemoryLimit(DefaultMemoryLimit())
+	return p, nil
+}
+
+// Sets the context of the processor. Once the context is cancelled or its deadline passes,
+// the running libraw operation is aborted and the context error is returned.
+func (p *Processor) SetContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+// Opens a RAW image file from file system. Any previously opened image is released.
+func (p *Processor) Open(path string) error {
+	if err := p.reset(); err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("input file [%v] does not exist", path)
+	}
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	p.dataSize = stat.Size()
+	start := time.Now()
+	if err := p.check(C.libraw_open_file(p.handle, cPath)); err != nil {
+		p.endStage("open", start, err, "path", path)
+		if f, openErr := os.Open(path); openErr == nil {
+			err = formatError(err, f)
+			f.Close()
+		}
+		return fmt.Errorf("failed to open file [%v] with [%w]", path, err)
+	}
+	p.path = path
+	p.endStage("open", start, nil)
+	return nil
+}
+
+// Opens a RAW image from memory. Any previously opened image is released.
+func (p *Processor) OpenBytes(data []byte) error {
+	if err := p.reset(); err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return fmt.Errorf("input data is empty")
+	}
+
+	// libraw keeps reading the buffer until the image is recycled, so it has to live in C memory
+	p.buffer = C.CBytes(data)
+	p.dataSize = int64(len(data))
+	start := time.Now()
+	err := p.check(C.libraw_open_buffer(p.handle, p.buffer, C.size_t(len(data))))
+	p.endStage("open", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to open input data with [%w]", formatError(err, bytes.NewReader(data)))
+	}
+	return nil
+}
+
+// Unpacks the RAW data of the opened image.
+func (p *Processor) Unpack() error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+	start := time.Now()
+	err := p.check(C.libraw_unpack(p.handle))
+	p.endStage("unpack", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to unpack image with [%w]", err)
+	}
+	return nil
+}
+
+// Processes the unpacked RAW data (demosaic, white balance, color conversion, etc.).
+// The options apply to the opened image only, the processor is reset to the libraw defaults on the next Open.
+func (p *Processor) Process(opts ...Option) error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+
+	o := newOptions(opts)
+	if o.err != nil {
+		return o.err
+	}
+	o.apply(p)
+	// the demosaic selected for X-Trans sensors only applies to this call, the options of the next ones are kept
+	userQual := p.handle.params.user_qual
+	defer func() { p.handle.params.user_qual = userQual }()
+	p.selectXTransDemosaic(o.xtransPasses)
+	if o.baselineExposure {
+		// computed from the exposure of the options on every call, not compounded over the calls
+		expCorrec, expShift := p.handle.params.exp_correc, p.handle.params.exp_shift
+		defer func() { p.handle.params.exp_correc, p.handle.params.exp_shift = expCorrec, expShift }()
+		p.applyBaselineExposure()
+	}
+	if o.resize.maxDim > 0 {
+		p.resize = o.resize
+	}
+	if o.monochrome {
+		p.monochrome = true
+	}
+	start := time.Now()
+	if err := p.checkMemory(); err != nil {
+		p.endStage("process", start, err)
+		return err
+	}
+
+	err := p.check(C.libraw_dcraw_process(p.handle))
+	p.endStage("process", start, err, "half_size", p.handle.params.half_size != 0)
+	if err != nil {
+		return fmt.Errorf("failed to process image with [%w]", err)
+	}
+	return nil
+}
+
+// Brightens DNG images by their BaselineExposure, on top of the exposure shift of WithExposure
+func (p *Processor) applyBaselineExposure() {
+	ev := float64(p.handle.color.dng_levels.baseline_exposure)
+	if p.handle.idata.dng_version == 0 || ev == 0 {
+		return
+	}
+	params := &p.handle.params
+	shift := math.Exp2(ev)
+	if params.exp_correc != 0 {
+		shift *= float64(params.exp_shift)
+	}
+	params.exp_correc = 1
+	params.exp_shift = C.float(min(max(shift, minExposureShift), maxExposureShift))
+}
+
+// Writes the processed image to the file system in PPM format, or TIFF format when processed for TIFF output.
+// Process has to be called first.
+func (p *Processor) Export(exportPath string) error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+
+	cPath := C.CString(exportPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	start := time.Now()
+	err := p.check(C.libraw_dcraw_ppm_tiff_writer(p.handle, cPath))
+	p.endStage("export", start, err, "output", exportPath)
+	if err != nil {
+		return fmt.Errorf("failed to export file to [%v] with [%w]", exportPath, err)
+	}
+	return nil
+}
+
+// Returns the metadata of the opened image.
+func (p *Processor) Metadata() (Metadata, error) {
+	if p.handle == nil {
+		return Metadata{}, fmt.Errorf("processor is closed")
+	}
+	metadata := metadataOf(p.handle, p.dataSize)
+	if r, closer := p.source(); r != nil {
+		defer closer()
+		metadata.addExif(readExif(r))
+	}
+	return metadata, nil
+}
+
+// Returns the processed image as standard image.Image, scaled down if processed WithResize. Images of monochrome
+// sensors and images processed WithMonochrome are returned as *image.Gray or *image.Gray16. Process has to be called first.
+func (p *Processor) Image() (image.Image, error) {
+	if p.handle == nil {
+		return nil, fmt.Errorf("processor is closed")
+	}
+	img, err := imageOf(p.handle, p.monochrome)
+	if err != nil || p.resize.maxDim <= 0 {
+		return img, err
+	}
+	return resample(img, p.resize.maxDim, p.resize.filter), nil
+}
+
+// Releases the opened image and resets the processing options, the libraw handle is kept for reuse.
+// The settings of the next Open (SetShot, SetMemoryLimit, SetDNGDefaultCrop) are kept.
+func (p *Processor) Recycle() {
+	if p.handle == nil {
+		return
+	}
+	C.libraw_recycle(p.handle)
+	p.handle.params = p.defaults
+	// libraw before 0.21 keeps the open settings in the processing parameters reset above
+	p.SetShot(p.shot)
+	p.SetMemoryLimit(p.memoryLimit)
+	p.SetDNGDefaultCrop(p.dngDefaultCrop)
+	p.freeBuffer()
+	p.dataErr = nil
+	p.fault = nil
+	p.logged = 0
+	p.loggedDataErr = false
+	p.resize = resizeSpec{}
+	p.monochrome = false
+}
+
+// Releases the libraw handle and all the memory allocated for the processor.
+func (p *Processor) Close() {
+	if p.handle == nil {
+		return
+	}
+	lrClose(p.handle)
+	p.handle = nil
+	p.freeBuffer()
+	p.unregisterProgress()
+}
+
+// Selects the frame to decode from RAW files containing multiple frames (see Metadata.RawCount) for the next Open calls.
+func (p *Processor) SetShot(index int) {
+	if p.handle == nil {
+		return
+	}
+	p.shot = index
+	C.set_shot_select(p.handle, C.uint(index))
+}
+
+// Crops DNG files to their DefaultCrop area for the next Open calls, removing the border pixels smartphone DNGs keep
+// for demosaic and lens correction. Requires libraw 0.20 or newer, ignored by older versions.
+func (p *Processor) SetDNGDefaultCrop(enabled bool) {
+	if p.handle == nil {
+		return
+	}
+	p.dngDefaultCrop = enabled
+	enable := C.int(0)
+	if enabled {
+		enable = 1
+	}
+	C.set_dng_default_crop(p.handle, enable)
+}
+
+// Limits the memory used for decoding and processing images to mb megabytes. libraw refuses to decode larger
+// sensor data, and Process refuses images whose processing buffers would exceed the limit, both with ErrMemoryLimit.
+// Takes effect when the next file is opened.
+func (p *Processor) SetMemoryLimit(mb int) {
+	if p.handle == nil || mb <= 0 {
+		return
+	}
+	p.memoryLimit = mb
+	C.set_max_raw_memory_mb(p.handle, C.uint(mb))
+}
+
+// Applies the options taking effect when a file is opened
+func (p *Processor) configure(o *options) {
+	p.SetProgress(o.progress)
+	p.SetShot(o.shot)
+	if o.logger != nil {
+		p.SetLogger(o.logger)
+	}
+	if o.metrics != nil {
+		p.SetMetrics(o.metrics)
+	}
+	if o.ctx != nil {
+		p.SetContext(o.ctx)
+	}
+	if o.memoryLimit > 0 {
+		p.SetMemoryLimit(o.memoryLimit)
+	}
+	if o.dngDefaultCrop {
+		p.SetDNGDefaultCrop(true)
+	}
+}
+
+// Estimates the memory needed by dcraw_process: the 4 channel 16-bit image, about the same again for interpolation
+// and the output buffer.
+func (p *Processor) checkMemory() error {
+	if p.memoryLimit <= 0 {
+		return nil
+	}
+	pixels := int64(p.handle.sizes.width) * int64(p.handle.sizes.height)
+	if p.handle.params.half_size != 0 {
+		pixels /= 4
+	}
+	needed := pixels * 8 * 2
+	if needed > int64(p.memoryLimit)<<20 {
+		return fmt.Errorf("processing needs about [%v] MB, more than the limit of [%v] MB: [%w]", needed>>20, p.memoryLimit, ErrMemoryLimit)
+	}
+	return nil
+}
+
+// Returns the context error if the operation was cancelled, otherwise the libraw error of the result.
+func (p *Processor) check(result C.int) error {
+	err := goResult(result)
+	if err != nil && p.fault != nil {
+		return p.fault
+	}
+	if err != nil && p.ctx != nil && p.ctx.Err() != nil {
+		return p.ctx.Err()
+	}
+	if err != nil && p.dataErr != nil {
+		return errors.Join(err, p.dataErr)
+	}
+	return err
+}
+
+// Explains the error of a file libraw failed to open because the linked version does not support its format yet
+func formatError(err error, r io.ReaderAt) error {
+	if !errors.Is(err, ErrUnsupportedFile) {
+		return err
+	}
+	header := make([]byte, 16)
+	n, _ := r.ReadAt(header, 0)
+	if DetectFormat(header[:n]) == "CR3" {
+		if versionErr := requireLibraw("CR3 decoding", 0, 20); versionErr != nil {
+			return versionErr
+		}
+	}
+	return err
+}
+
+// Returns the first error libraw encountered reading the image data of the opened image, nil if there was none.
+// libraw substitutes the unreadable data, so the image can still be processed, but it is likely damaged.
+func (p *Processor) DataError() error {
+	if p.dataErr == nil {
+		return nil
+	}
+	return p.dataErr
+}
+
+func (p *Processor) reset() error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+	p.Recycle()
+	return nil
+}
+
+func (p *Processor) freeBuffer() {
+	if p.buffer != nil {
+		C.free(p.buffer)
+		p.buffer = nil
+	}
+	for _, s := range p.cstrings {
+		C.free(unsafe.Pointer(s))
+	}
+	p.cstrings = nil
+	p.path = ""
+	p.dataSize = 0
+}
+
+// Returns a C copy of s, released with the opened image
+func (p *Processor) cString(s string) *C.char {
+	cs := C.CString(s)
+	p.cstrings = append(p.cstrings, cs)
+	return cs
+}
+
+// Returns the ICC profile embedded in the opened image, nil if there is none.
+func (p *Processor) ICCProfile() []byte {
+	if p.handle == nil || p.handle.color.profile == nil || p.handle.color.profile_length == 0 {
+		return nil
+	}
+	return C.GoBytes(p.handle.color.profile, C.int(p.handle.color.profile_length))
+}
+
+// Returns the XMP packet embedded in the opened image, nil if there is none.
+func (p *Processor) XMP() []byte {
+	if p.handle == nil || p.handle.idata.xmpdata == nil || p.handle.idata.xmplen == 0 {
+		return nil
+	}
+	return C.GoBytes(unsafe.Pointer(p.handle.idata.xmpdata), C.int(p.handle.idata.xmplen))
+}
+
+// Returns random access to the opened file or buffer for reading the data not exposed by libraw, nil if nothing is opened.
+// The returned function has to be called to release the source.
+func (p *Processor) source() (io.ReaderAt, func()) {
+	if p.buffer != nil {
+		return bytes.NewReader(unsafe.Slice((*byte)(p.buffer), p.dataSize)), func() {}
+	}
+	if p.path != "" {
+		f, err := os.Open(p.path)
+		if err == nil {
+			return f, func() { f.Close() }
+		}
+	}
+	return nil, nil
+}
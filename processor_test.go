@@ -0,0 +1,258 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+// One processor decodes many files, the processing options of a file do not apply to the next one
+func TestProcessorReuse(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+
+	for i, test := range []struct {
+		pattern string
+		opts    []Option
+		want    image.Rectangle
+	}{
+		{"RGGB", []Option{WithHalfSize()}, image.Rect(0, 0, testWidth/2, testHeight/2)},
+		{"GBRG", nil, image.Rect(0, 0, testWidth, testHeight)},
+		{"", nil, image.Rect(0, 0, testWidth, testHeight)},
+	} {
+		if err = processor.Open(testDNG(t, test.pattern)); err != nil {
+			t.Fatal(err)
+		}
+		if err = processor.Unpack(); err != nil {
+			t.Fatal(err)
+		}
+		if err = processor.Process(test.opts...); err != nil {
+			t.Fatal(err)
+		}
+		img, err := processor.Image()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := img.Bounds(); got != test.want {
+			t.Errorf("bounds of file %v = %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestProcessorClosed(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.Close()
+	// closing twice is allowed
+	processor.Close()
+
+	if err = processor.Open(testDNG(t, "RGGB")); err == nil {
+		t.Error("Open of a closed processor succeeded")
+	}
+	if err = processor.Unpack(); err == nil {
+		t.Error("Unpack of a closed processor succeeded")
+	}
+	if err = processor.Process(); err == nil {
+		t.Error("Process of a closed processor succeeded")
+	}
+	if _, err = processor.Image(); err == nil {
+		t.Error("Image of a closed processor succeeded")
+	}
+}
+
+// Calls out of order are reported by libraw instead of crashing
+func TestProcessorOutOfOrder(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	if err = processor.Unpack(); err == nil {
+		t.Error("Unpack without an opened file succeeded")
+	}
+	if err = processor.Open(testDNG(t, "RGGB")); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Process(); err == nil {
+		t.Error("Process without Unpack succeeded")
+	}
+}
+
+// The C strings of the path options are owned by the processor until the next image
+func TestProcessorPathOptions(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+
+	newOptions([]Option{WithOutputProfile("out.icc"), WithEmbeddedCameraProfile()}).apply(processor)
+	if processor.handle.params.output_profile == nil || processor.handle.params.camera_profile == nil {
+		t.Errorf("profile options did not set their parameters")
+	}
+	if len(processor.cstrings) != 2 {
+		t.Errorf("processor owns %v C strings, want 2", len(processor.cstrings))
+	}
+	processor.Recycle()
+	if processor.handle.params.output_profile != nil || processor.handle.params.camera_profile != nil || len(processor.cstrings) != 0 {
+		t.Errorf("Recycle kept the profile options")
+	}
+}
+
+// Files of formats newer than the linked libraw fail with ErrUnsupportedByLibraw
+func TestFormatError(t *testing.T) {
+	cr3 := bytes.NewReader([]byte("\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01"))
+	err := formatError(ErrUnsupportedFile, cr3)
+	if requireLibraw("CR3 decoding", 0, 20) != nil {
+		if !errors.Is(err, ErrUnsupportedByLibraw) {
+			t.Errorf("error of a CR3 file = %v, want %v", err, ErrUnsupportedByLibraw)
+		}
+	} else if err != ErrUnsupportedFile {
+		t.Errorf("error of an unsupported CR3 file = %v, want %v", err, ErrUnsupportedFile)
+	}
+
+	if err := formatError(ErrUnsupportedFile, bytes.NewReader([]byte("garbage"))); err != ErrUnsupportedFile {
+		t.Errorf("error of garbage = %v, want %v", err, ErrUnsupportedFile)
+	}
+	if err := formatError(ErrIO, cr3); err != ErrIO {
+		t.Errorf("I/O error of a CR3 file = %v, want %v", err, ErrIO)
+	}
+}
+
+// The demosaic selected for X-Trans sensors must not leak into the next Process calls of the processor
+func TestXTransDemosaicPerCall(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	if err = processor.Open(testDNG(t, testXTrans)); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Unpack(); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Process(WithDemosaic(DemosaicVNG), WithXTransPasses(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := Demosaic(processor.handle.params.user_qual); got != DemosaicVNG {
+		t.Errorf("demosaic after Process = %v, want %v", got, DemosaicVNG)
+	}
+}
+
+// The BaselineExposure of a DNG is applied on top of the exposure of the options of every call, without compounding
+func TestBaselineExposurePerCall(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	if err = processor.Open(testDNG(t, "RGGB")); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Unpack(); err != nil {
+		t.Fatal(err)
+	}
+	processor.handle.color.dng_levels.baseline_exposure = 1
+	before := processor.handle.params
+	for i := 0; i < 2; i++ {
+		if err = processor.Process(WithBaselineExposure()); err != nil {
+			t.Fatal(err)
+		}
+		params := processor.handle.params
+		if params.exp_correc != before.exp_correc || params.exp_shift != before.exp_shift {
+			t.Errorf("exposure after Process %v = %v/%v, want %v/%v", i, params.exp_correc, params.exp_shift,
+				before.exp_correc, before.exp_shift)
+		}
+	}
+}
+
+// The frame selected before Open must survive the reset of the processing parameters by Open
+func TestShotSurvivesOpen(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	path := testDNG(t, "RGGB")
+	processor.SetShot(1)
+	for i := 0; i < 2; i++ {
+		if err = processor.Open(path); err == nil {
+			err = processor.Unpack()
+		}
+		if !errors.Is(err, ErrNonexistentImage) {
+			t.Errorf("decoding frame 1 of a single frame DNG, attempt %v: %v, want %v", i, err, ErrNonexistentImage)
+		}
+	}
+}
+
+// The libraw side memory limit must survive the reset of the processing parameters by Open
+func TestMemoryLimitSurvivesOpen(t *testing.T) {
+	data := largeDNG(t)
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	processor.SetMemoryLimit(1)
+	for i := 0; i < 2; i++ {
+		if err = processor.OpenBytes(data); err == nil {
+			err = processor.Unpack()
+		}
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Errorf("unpacking 2 MB with a limit of 1 MB, attempt %v: %v, want %v", i, err, ErrMemoryLimit)
+		}
+	}
+}
+
+// The DNG default crop must apply to every file opened by a reused processor
+func TestDNGDefaultCropReused(t *testing.T) {
+	const (
+		tagDefaultCropOrigin = 0xc61f
+		tagDefaultCropSize   = 0xc620
+	)
+	fields, strip := dngFields(testImage("RGGB"), testMetadata())
+	fields.long(tagDefaultCropOrigin, 8, 8)
+	fields.long(tagDefaultCropSize, testWidth-16, testHeight-16)
+	var buf bytes.Buffer
+	if err := fields.write(&buf, tagStripOffsets, strip); err != nil {
+		t.Fatal(err)
+	}
+
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	for _, crop := range []bool{false, true, true} {
+		processor.SetDNGDefaultCrop(crop)
+		if err = processor.OpenBytes(buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if err = processor.Unpack(); err != nil {
+			t.Fatal(err)
+		}
+		if err = processor.Process(); err != nil {
+			t.Fatal(err)
+		}
+		img, err := processor.Image()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := image.Rect(0, 0, testWidth, testHeight)
+		if crop {
+			want = image.Rect(0, 0, testWidth-16, testHeight-16)
+		}
+		if got := img.Bounds(); got != want {
+			t.Errorf("bounds with the default crop %v = %v, want %v", crop, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <stdint.h>
+// #include <libraw/libraw.h>
+//
+// extern int goProgress(void *data, enum LibRaw_progress stage, int iteration, int expected);
+// extern void goDataError(void *data, char *file, INT64 offset);
+//
+// // The offset of data errors is 64-bit since libraw 0.21
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// static void data_error(void *data, const char *file, const INT64 offset) {
+// #else
+// static void data_error(void *data, const char *file, const int offset) {
+// #endif
+// 	goDataError(data, (char *)file, offset);
+// }
+//
+// static void set_progress_handler(libraw_data_t *lr, uintptr_t handle) {
+// 	libraw_set_progress_handler(lr, goProgress, (void *)handle);
+// 	libraw_set_dataerror_handler(lr, data_error, (void *)handle);
+// }
+import "C"
+
+import (
+	"runtime/cgo"
+)
+
+// ProgressFunc receives the name of the current libraw processing stage, with the iteration and
+// expected number of iterations within the stage.
+type ProgressFunc func(stage string, iteration int, total int)
+
+// Registers fn to be called as libraw advances through the processing stages of the opened image.
+func (p *Processor) SetProgress(fn ProgressFunc) {
+	p.progress = fn
+}
+
+// Reports the processing progress to fn.
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+func (p *Processor) registerProgress() {
+	p.self = cgo.NewHandle(p)
+	C.set_progress_handler(p.handle, C.uintptr_t(p.self))
+}
+
+func (p *Processor) unregisterProgress() {
+	if p.self != 0 {
+		p.self.Delete()
+		p.self = 0
+	}
+}
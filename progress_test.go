@@ -0,0 +1,61 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	type report struct {
+		stage     string
+		iteration int
+		total     int
+	}
+	var reports []report
+	_, err := ImportRawWithOptions(testDNG(t, "RGGB"), WithProgress(func(stage string, iteration int, total int) {
+		reports = append(reports, report{stage, iteration, total})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	stages := map[string]bool{}
+	for _, r := range reports {
+		if r.stage == "" || r.iteration < 0 || r.iteration > r.total {
+			t.Errorf("invalid progress report %+v", r)
+		}
+		stages[r.stage] = true
+	}
+	if len(stages) < 2 {
+		t.Errorf("progress of stages %v, want the stages of decoding and processing", stages)
+	}
+}
+
+// The progress function of a processor is kept for the next files
+func TestSetProgress(t *testing.T) {
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	calls := 0
+	processor.SetProgress(func(string, int, int) { calls++ })
+	path := testDNG(t, "RGGB")
+	for i := 0; i < 2; i++ {
+		before := calls
+		if err = processor.Open(path); err == nil {
+			if err = processor.Unpack(); err == nil {
+				err = processor.Process()
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls == before {
+			t.Errorf("no progress reported for file %v", i)
+		}
+	}
+}
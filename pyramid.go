@@ -0,0 +1,69 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"fmt"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reads a RAW image file from file system once and exports JPEG renditions fitting in each of sizes x sizes
+// (e.g. 3840, 1920, 640 and 160 pixels) to dir, named <name>_<size>.jpg. The image is rendered for the largest size
+// only, every smaller size is scaled down from the next larger one, sharing the decode across all sizes.
+// Returns the paths of the outputs in the order of sizes. The image is processed with the provided options.
+func GeneratePyramid(path string, sizes []int, dir string, opts ...Option) ([]string, error) {
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no pyramid sizes")
+	}
+	seen := map[int]bool{}
+	for _, size := range sizes {
+		if size <= 0 || seen[size] {
+			return nil, fmt.Errorf("invalid or duplicate pyramid size [%v]", size)
+		}
+		seen[size] = true
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory [%v] with [%w]", dir, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	outputs := make([]string, len(sizes))
+	for i, size := range sizes {
+		outputs[i] = filepath.Join(dir, base+"_"+strconv.Itoa(size)+".jpg")
+	}
+	output := newOptions(opts).output
+	for _, exportPath := range outputs {
+		if err := output.prepare(exportPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// largest first, each level is scaled down from the previous one
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+
+	img, err := ImportRawPreview(path, sizes[order[0]], opts...)
+	if err != nil {
+		return nil, err
+	}
+	level := img
+	for _, i := range order {
+		level = downscale(level, sizes[i])
+		err = encodeFile(outputs[i], output, func(w io.Writer) error {
+			return jpeg.Encode(w, level, &jpeg.Options{Quality: jpeg.DefaultQuality})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,51 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePyramid(t *testing.T) {
+	path := testDNG(t, "RGGB")
+	dir := filepath.Join(t.TempDir(), "pyramid")
+
+	// the sizes are not ordered, the outputs follow them
+	sizes := []int{testWidth / 4, testWidth * 2, testWidth / 2}
+	outputs, err := GeneratePyramid(path, sizes, dir)
+	if err != nil {
+		t.Fatalf("GeneratePyramid failed: %v", err)
+	}
+	if len(outputs) != len(sizes) {
+		t.Fatalf("outputs = %v, want one per size", outputs)
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for i, size := range sizes {
+		if want := filepath.Join(dir, base+"_"+strconv.Itoa(size)+".jpg"); outputs[i] != want {
+			t.Errorf("output [%v] = %v, want %v", i, outputs[i], want)
+		}
+		config, format := decodeConfigFile(t, outputs[i])
+		w, h := fitSize(testWidth, testHeight, size)
+		if format != "jpeg" || config.Width != w || config.Height != h {
+			t.Errorf("output for %v = %v %vx%v, want jpeg %vx%v", size, format, config.Width, config.Height, w, h)
+		}
+	}
+
+	for _, sizes := range [][]int{nil, {0}, {160, -1}, {160, 160}} {
+		if _, err := GeneratePyramid(path, sizes, t.TempDir()); err == nil {
+			t.Errorf("GeneratePyramid succeeded with sizes %v", sizes)
+		}
+	}
+
+	failed := t.TempDir()
+	if _, err := GeneratePyramid(filepath.Join(failed, "missing.dng"), []int{160}, failed); err == nil {
+		t.Errorf("GeneratePyramid succeeded on a missing file")
+	}
+	if entries, _ := os.ReadDir(failed); len(entries) != 0 {
+		t.Errorf("failed pyramid left %v entries", len(entries))
+	}
+}
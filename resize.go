@@ -0,0 +1,277 @@
+package golibraw
+
+import (
+	"image"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// Returns the size of a w x h image scaled down to fit in maxDim x maxDim, keeping the aspect ratio
+func fitSize(w int, h int, maxDim int) (int, int) {
+	if maxDim <= 0 || (w <= maxDim && h <= maxDim) {
+		return w, h
+	}
+	if w >= h {
+		return maxDim, max(1, h*maxDim/w)
+	}
+	return max(1, w*maxDim/h), maxDim
+}
+
+// Scales img down to fit in maxDim x maxDim by averaging the source pixels covered by each output pixel.
+// Images already fitting are returned unchanged.
+func downscale(img image.Image, maxDim int) image.Image {
+	bounds := img.Bounds()
+	w, h := fitSize(bounds.Dx(), bounds.Dy(), maxDim)
+	if w == bounds.Dx() && h == bounds.Dy() {
+		return img
+	}
+
+	src, ok := img.(image.RGBA64Image)
+	if !ok {
+		converted := image.NewRGBA64(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				converted.Set(x, y, img.At(x, y))
+			}
+		}
+		src = converted
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for dy := 0; dy < h; dy++ {
+		y0 := bounds.Min.Y + dy*bounds.Dy()/h
+		y1 := max(y0+1, bounds.Min.Y+(dy+1)*bounds.Dy()/h)
+		for dx := 0; dx < w; dx++ {
+			x0 := bounds.Min.X + dx*bounds.Dx()/w
+			x1 := max(x0+1, bounds.Min.X+(dx+1)*bounds.Dx()/w)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					c := src.RGBA64At(x, y)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			i := dst.PixOffset(dx, dy)
+			putUint16(dst.Pix[i:], uint16(r/n))
+			putUint16(dst.Pix[i+2:], uint16(g/n))
+			putUint16(dst.Pix[i+4:], uint16(b/n))
+			putUint16(dst.Pix[i+6:], uint16(a/n))
+		}
+	}
+	return dst
+}
+
+func putUint16(b []byte, v uint16) {
+	b[0] = uint8(v >> 8)
+	b[1] = uint8(v)
+}
+
+// ResizeFilter is the resampling kernel used for scaling images down, see WithResize.
+type ResizeFilter int
+
+const (
+	// Averages the source pixels covered by each output pixel: fast, but softer and prone to aliasing
+	ResizeBox ResizeFilter = iota
+	// Catmull-Rom cubic kernel: sharp with little ringing, a good default for previews
+	ResizeCatmullRom
+	// Lanczos kernel with 3 lobes: the sharpest, with slight ringing at high contrast edges
+	ResizeLanczos
+)
+
+// Returns the radius of the kernel and the kernel function, nil for the box filter
+func (f ResizeFilter) kernel() (float64, func(x float64) float64) {
+	switch f {
+	case ResizeCatmullRom:
+		return 2, func(x float64) float64 {
+			x = math.Abs(x)
+			switch {
+			case x < 1:
+				return 1.5*x*x*x - 2.5*x*x + 1
+			case x < 2:
+				return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+			default:
+				return 0
+			}
+		}
+	case ResizeLanczos:
+		return 3, func(x float64) float64 {
+			x = math.Abs(x)
+			switch {
+			case x < 1e-8:
+				return 1
+			case x < 3:
+				return 3 * math.Sin(math.Pi*x) * math.Sin(math.Pi*x/3) / (math.Pi * math.Pi * x * x)
+			default:
+				return 0
+			}
+		}
+	default:
+		return 0, nil
+	}
+}
+
+// Source pixels and normalized weights contributing to an output pixel
+type resampleTaps struct {
+	first   int
+	weights []float32
+}
+
+// Computes the taps of every output pixel for scaling src pixels down to dst pixels
+func resampleWeights(src int, dst int, radius float64, kernel func(x float64) float64) []resampleTaps {
+	scale := float64(src) / float64(dst)
+	support := radius * max(scale, 1)
+	taps := make([]resampleTaps, dst)
+	for i := range taps {
+		center := (float64(i)+0.5)*scale - 0.5
+		first := max(0, int(math.Ceil(center-support)))
+		last := min(src-1, int(math.Floor(center+support)))
+		weights := make([]float32, 0, last-first+1)
+		sum := 0.0
+		for j := first; j <= last; j++ {
+			w := kernel((float64(j) - center) / max(scale, 1))
+			weights = append(weights, float32(w))
+			sum += w
+		}
+		if sum != 0 {
+			for k := range weights {
+				weights[k] /= float32(sum)
+			}
+		}
+		taps[i] = resampleTaps{first: first, weights: weights}
+	}
+	return taps
+}
+
+// Scales img down to fit in maxDim x maxDim with the filter, keeping the aspect ratio. The result has the type of img
+// for the RGBA, NRGBA64, Gray and Gray16 images produced by the package. Images already fitting are returned unchanged.
+func resample(img image.Image, maxDim int, filter ResizeFilter) image.Image {
+	radius, kernel := filter.kernel()
+	if kernel == nil {
+		return downscale(img, maxDim)
+	}
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	w, h := fitSize(srcW, srcH, maxDim)
+	if w == srcW && h == srcH {
+		return img
+	}
+
+	// horizontal pass into 4 float channels per pixel, then the vertical pass into the output
+	columns := resampleWeights(srcW, w, radius, kernel)
+	rows := resampleWeights(srcH, h, radius, kernel)
+	tmp := make([]float32, srcH*w*4)
+	parallelRows(srcH, func(y int) {
+		src := make([]float32, srcW*4)
+		readRow(img, bounds.Min.Y+y, src)
+		out := tmp[y*w*4 : (y+1)*w*4]
+		for x, t := range columns {
+			var r, g, b, a float32
+			for k, weight := range t.weights {
+				i := (t.first + k) * 4
+				r += src[i] * weight
+				g += src[i+1] * weight
+				b += src[i+2] * weight
+				a += src[i+3] * weight
+			}
+			out[x*4], out[x*4+1], out[x*4+2], out[x*4+3] = r, g, b, a
+		}
+	})
+
+	var dst image.Image
+	var setRow func(y int, samples []float32)
+	switch img.(type) {
+	case *image.RGBA:
+		out := image.NewRGBA(image.Rect(0, 0, w, h))
+		dst, setRow = out, func(y int, samples []float32) {
+			pix := out.Pix[y*out.Stride:]
+			for i, v := range samples {
+				pix[i] = uint8(min(max(v/257+0.5, 0), 255))
+			}
+		}
+	case *image.NRGBA64:
+		out := image.NewNRGBA64(image.Rect(0, 0, w, h))
+		dst, setRow = out, func(y int, samples []float32) { putSamples(out.Pix[y*out.Stride:], samples) }
+	case *image.Gray:
+		out := image.NewGray(image.Rect(0, 0, w, h))
+		dst, setRow = out, func(y int, samples []float32) {
+			pix := out.Pix[y*out.Stride:]
+			for x := 0; x < w; x++ {
+				pix[x] = uint8(min(max(samples[x*4]/257+0.5, 0), 255))
+			}
+		}
+	case *image.Gray16:
+		out := image.NewGray16(image.Rect(0, 0, w, h))
+		dst, setRow = out, func(y int, samples []float32) {
+			pix := out.Pix[y*out.Stride:]
+			for x := 0; x < w; x++ {
+				putUint16(pix[2*x:], uint16(min(max(samples[x*4]+0.5, 0), 65535)))
+			}
+		}
+	default:
+		out := image.NewRGBA64(image.Rect(0, 0, w, h))
+		dst, setRow = out, func(y int, samples []float32) { putSamples(out.Pix[y*out.Stride:], samples) }
+	}
+	parallelRows(h, func(y int) {
+		out := make([]float32, w*4)
+		t := rows[y]
+		for k, weight := range t.weights {
+			src := tmp[(t.first+k)*w*4 : (t.first+k+1)*w*4]
+			for i, v := range src {
+				out[i] += v * weight
+			}
+		}
+		setRow(y, out)
+	})
+	return dst
+}
+
+// Calls fn for every row in [0, n) from a goroutine per CPU
+func parallelRows(n int, fn func(y int)) {
+	workers := min(runtime.NumCPU(), n)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for y := w; y < n; y += workers {
+				fn(y)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// Reads the samples of row y of img as 16-bit values
+func readRow(img image.Image, y int, out []float32) {
+	bounds := img.Bounds()
+	switch src := img.(type) {
+	case *image.RGBA:
+		pix := src.Pix[src.PixOffset(bounds.Min.X, y):]
+		for i := range out {
+			out[i] = float32(pix[i]) * 257
+		}
+	case *image.NRGBA64:
+		pix := src.Pix[src.PixOffset(bounds.Min.X, y):]
+		for i := range out {
+			out[i] = float32(uint16(pix[2*i])<<8 | uint16(pix[2*i+1]))
+		}
+	default:
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, y).RGBA()
+			out[x*4], out[x*4+1], out[x*4+2], out[x*4+3] = float32(r), float32(g), float32(b), float32(a)
+		}
+	}
+}
+
+// Writes filtered samples as 16-bit values, clamped as the kernels overshoot at edges
+func putSamples(pix []byte, samples []float32) {
+	for i, v := range samples {
+		putUint16(pix[2*i:], uint16(min(max(v+0.5, 0), 65535)))
+	}
+}
@@ -0,0 +1,43 @@
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxDim int
+		wantW, wantH int
+	}{
+		{96, 64, 0, 96, 64},
+		{96, 64, 100, 96, 64},
+		{96, 64, 48, 48, 32},
+		{64, 96, 48, 32, 48},
+		{1000, 1, 10, 10, 1},
+	}
+	for _, tt := range tests {
+		if w, h := fitSize(tt.w, tt.h, tt.maxDim); w != tt.wantW || h != tt.wantH {
+			t.Errorf("fitSize(%v, %v, %v) = %vx%v, want %vx%v", tt.w, tt.h, tt.maxDim, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	copy(img.Pix, []uint8{0, 100, 200, 200, 100, 200, 0, 0})
+	if got := downscale(img, 4); got != image.Image(img) {
+		t.Errorf("downscale changed an image fitting in the size")
+	}
+
+	scaled := downscale(img, 2)
+	if got := scaled.Bounds(); got != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("bounds = %v, want 2x1", got)
+	}
+	for x, want := range []uint8{100, 100} {
+		if got := color.GrayModel.Convert(scaled.At(x, 0)).(color.Gray).Y; got != want {
+			t.Errorf("pixel %v = %v, want the average %v", x, got, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Environment variable marking the helper processes started by Sandbox
+const sandboxEnv = "GOLIBRAW_SANDBOX_HELPER"
+
+// ErrSandboxCrashed is returned when the helper process of a Sandbox terminates without a response,
+// e.g. because libraw crashed on a malformed file.
+var ErrSandboxCrashed = errors.New("sandbox helper crashed")
+
+// Sandbox decodes RAW files in a helper process, so a crash or memory corruption in libraw caused by an untrusted file
+// cannot take down the calling process. Every call starts a new helper, which runs the executable of the calling
+// process (or Command if set), so the program has to call ServeSandbox at the very start of main.
+type Sandbox struct {
+	// Executable and arguments of the helper, the current executable by default. The helper has to call ServeSandbox.
+	Command string
+	Args    []string
+	// Memory limit of the helper in megabytes, see SetDefaultMemoryLimit. 0 for the default.
+	MemoryLimit int
+}
+
+type sandboxRequest struct {
+	Op          string
+	Path        string
+	HalfSize    bool
+	MemoryLimit int
+}
+
+type sandboxResponse struct {
+	Metadata  Metadata
+	RGBA      *image.RGBA
+	NRGBA64   *image.NRGBA64
+	Gray      *image.Gray
+	Gray16    *image.Gray16
+	Thumbnail []byte
+	Info      ThumbnailInfo
+	Err       string
+	Code      int
+}
+
+// Serves a request of a Sandbox and exits if the process was started as a sandbox helper, returns otherwise.
+// Has to be called at the start of main by programs using Sandbox.
+func ServeSandbox() {
+	if os.Getenv(sandboxEnv) != "1" {
+		return
+	}
+
+	var req sandboxRequest
+	resp := sandboxResponse{}
+	if err := gob.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		resp.Err = fmt.Sprintf("failed to read sandbox request with [%v]", err)
+	} else {
+		resp = serveSandboxRequest(req)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(resp); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func serveSandboxRequest(req sandboxRequest) sandboxResponse {
+	resp := sandboxResponse{}
+	SetDefaultMemoryLimit(req.MemoryLimit)
+
+	var err error
+	switch req.Op {
+	case "metadata":
+		resp.Metadata, err = ExtractMetadata(req.Path)
+	case "image":
+		var img image.Image
+		opts := []Option{}
+		if req.HalfSize {
+			opts = append(opts, WithHalfSize())
+		}
+		if img, err = ImportRawWithOptions(req.Path, opts...); err == nil {
+			switch img := img.(type) {
+			case *image.RGBA:
+				resp.RGBA = img
+			case *image.NRGBA64:
+				resp.NRGBA64 = img
+			case *image.Gray:
+				resp.Gray = img
+			case *image.Gray16:
+				resp.Gray16 = img
+			default:
+				err = fmt.Errorf("unexpected image type [%T]", img)
+			}
+		}
+	case "thumbnail":
+		resp.Thumbnail, resp.Info, err = ExtractThumbnailBytes(req.Path)
+	default:
+		err = fmt.Errorf("unknown sandbox operation [%v]", req.Op)
+	}
+
+	if err != nil {
+		resp.Err = err.Error()
+		var lrErr *Error
+		if errors.As(err, &lrErr) {
+			resp.Code = lrErr.Code
+		}
+	}
+	return resp
+}
+
+func (s *Sandbox) call(ctx context.Context, req sandboxRequest) (sandboxResponse, error) {
+	command := s.Command
+	if command == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return sandboxResponse{}, fmt.Errorf("failed to find sandbox helper with [%w]", err)
+		}
+		command = executable
+	}
+	req.MemoryLimit = s.MemoryLimit
+
+	var stdin, stdout, stderr bytes.Buffer
+	if err := gob.NewEncoder(&stdin).Encode(req); err != nil {
+		return sandboxResponse{}, err
+	}
+	cmd := exec.CommandContext(ctx, command, s.Args...)
+	cmd.Env = append(os.Environ(), sandboxEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = &stdin, &stdout, &stderr
+
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return sandboxResponse{}, ctx.Err()
+	}
+	var resp sandboxResponse
+	if err := gob.NewDecoder(&stdout).Decode(&resp); err != nil {
+		return sandboxResponse{}, fmt.Errorf("%w with [%v]: %v", ErrSandboxCrashed, runErr, strings.TrimSpace(stderr.String()))
+	}
+	if resp.Err != "" {
+		if resp.Code != 0 {
+			return resp, fmt.Errorf("%v: [%w]", resp.Err, &Error{Code: resp.Code, Message: resp.Err})
+		}
+		return resp, errors.New(resp.Err)
+	}
+	return resp, nil
+}
+
+// Reads the metadata of a RAW image file in a helper process, see ExtractMetadata.
+func (s *Sandbox) ExtractMetadata(ctx context.Context, path string) (Metadata, error) {
+	resp, err := s.call(ctx, sandboxRequest{Op: "metadata", Path: path})
+	if err != nil {
+		return Metadata{}, err
+	}
+	return resp.Metadata, nil
+}
+
+// Converts a RAW image file to standard image.Image in a helper process with the default processing, at half size
+// if halfSize is set. See ImportRaw.
+func (s *Sandbox) ImportRaw(ctx context.Context, path string, halfSize bool) (image.Image, error) {
+	resp, err := s.call(ctx, sandboxRequest{Op: "image", Path: path, HalfSize: halfSize})
+	if err != nil {
+		return nil, err
+	}
+	if resp.NRGBA64 != nil {
+		return resp.NRGBA64, nil
+	}
+	if resp.RGBA != nil {
+		return resp.RGBA, nil
+	}
+	if resp.Gray16 != nil {
+		return resp.Gray16, nil
+	}
+	if resp.Gray != nil {
+		return resp.Gray, nil
+	}
+	return nil, fmt.Errorf("sandbox helper returned no image")
+}
+
+// Reads the embedded thumbnail of a RAW image file in a helper process, see ExtractThumbnailBytes.
+func (s *Sandbox) ExtractThumbnailBytes(ctx context.Context, path string) ([]byte, ThumbnailInfo, error) {
+	resp, err := s.call(ctx, sandboxRequest{Op: "thumbnail", Path: path})
+	if err != nil {
+		return nil, ThumbnailInfo{}, err
+	}
+	return resp.Thumbnail, resp.Info, nil
+}
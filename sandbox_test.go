@@ -0,0 +1,71 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Serves the requests of the sandbox tests, the test binary is started as the sandbox helper running only this test
+func TestSandboxHelper(t *testing.T) {
+	ServeSandbox()
+}
+
+func testSandbox() *Sandbox {
+	return &Sandbox{Command: os.Args[0], Args: []string{"-test.run=^TestSandboxHelper$"}}
+}
+
+func TestSandbox(t *testing.T) {
+	sandbox := testSandbox()
+	path, preview := previewDNG(t)
+	ctx := context.Background()
+
+	md, err := sandbox.ExtractMetadata(ctx, path)
+	if err != nil {
+		t.Fatalf("ExtractMetadata failed: %v", err)
+	}
+	if md.Camera.Make != testMetadata().Camera.Make || md.Width != testWidth {
+		t.Errorf("metadata = %v %v, width %v, want the test camera, width %v", md.Camera.Make, md.Camera.Model, md.Width, testWidth)
+	}
+
+	img, err := sandbox.ImportRaw(ctx, path, true)
+	if err != nil {
+		t.Fatalf("ImportRaw failed: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, testWidth/2, testHeight/2) {
+		t.Errorf("bounds = %v, want the half size %vx%v", got, testWidth/2, testHeight/2)
+	}
+
+	data, info, err := sandbox.ExtractThumbnailBytes(ctx, path)
+	if err != nil {
+		t.Fatalf("ExtractThumbnailBytes failed: %v", err)
+	}
+	if info.Format != ThumbnailJPEG || len(data) != len(preview) {
+		t.Errorf("thumbnail = %v bytes of format %v, want the %v bytes JPEG preview", len(data), info.Format, len(preview))
+	}
+}
+
+func TestSandboxErrors(t *testing.T) {
+	ctx := context.Background()
+	garbage := filepath.Join("testdata", "corrupt", "garbage.raw")
+	if _, err := testSandbox().ImportRaw(ctx, garbage, false); !errors.Is(err, ErrUnsupportedFile) {
+		t.Errorf("ImportRaw of garbage error = %v, want %v", err, ErrUnsupportedFile)
+	}
+
+	// a helper exiting without response
+	crashing := &Sandbox{Command: os.Args[0], Args: []string{"-test.run=^$"}}
+	if _, err := crashing.ExtractMetadata(ctx, garbage); !errors.Is(err, ErrSandboxCrashed) {
+		t.Errorf("ExtractMetadata of a crashing helper error = %v, want %v", err, ErrSandboxCrashed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := testSandbox().ExtractMetadata(cancelled, garbage); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractMetadata with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
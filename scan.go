@@ -0,0 +1,54 @@
+package golibraw
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// RawFileInfo describes a RAW file found by ScanDir. Err is set if the file looks like a RAW file, but libraw failed to read it.
+type RawFileInfo struct {
+	Path     string
+	Format   string
+	Metadata Metadata
+	Err      error
+}
+
+// Walks the directory tree at root and collects the RAW files with their metadata.
+// Files are identified by their content, not their extension.
+func ScanDir(root string) ([]RawFileInfo, error) {
+	files := make([]RawFileInfo, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		format, err := detectFile(path)
+		if err != nil || format == "" {
+			return nil
+		}
+
+		metadata, err := ExtractMetadata(path)
+		files = append(files, RawFileInfo{Path: path, Format: format, Metadata: metadata, Err: err})
+		return nil
+	})
+	if err != nil {
+		return files, fmt.Errorf("failed to scan directory [%v] with [%w]", root, err)
+	}
+	return files, nil
+}
+
+func detectFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, format, err := IsRaw(f)
+	return format, err
+}
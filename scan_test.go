@@ -0,0 +1,45 @@
+package golibraw
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Finds the RAW files by their content in nested directories, skipping the other files
+func TestScanDir(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "corrupt", "truncated.dng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	raw := filepath.Join(root, "nested", "image.jpg")
+	files := map[string][]byte{
+		raw:                                data,
+		filepath.Join(root, "notes.dng"):   []byte("not a RAW file"),
+		filepath.Join(root, "nested", "x"): {},
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := ScanDir(root)
+	if err != nil {
+		t.Fatalf("ScanDir failed: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("found %+v, want only %v", found, raw)
+	}
+	if found[0].Path != raw || found[0].Format != "DNG" {
+		t.Errorf("found %v as %q, want %v as DNG", found[0].Path, found[0].Format, raw)
+	}
+
+	if _, err := ScanDir(filepath.Join(root, "missing")); err == nil {
+		t.Errorf("ScanDir of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,67 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+// Names and crop factors (relative to the 36x24mm diagonal) of the sensor formats reported by libraw
+var sensorFormats = map[C.int]struct {
+	name string
+	crop float64
+}{
+	C.LIBRAW_FORMAT_APSC:         {"APS-C", 1.5},
+	C.LIBRAW_FORMAT_FF:           {"Full Frame", 1},
+	C.LIBRAW_FORMAT_MF:           {"Medium Format", 0.79},
+	C.LIBRAW_FORMAT_APSH:         {"APS-H", 1.29},
+	C.LIBRAW_FORMAT_1INCH:        {"1\"", 2.7},
+	C.LIBRAW_FORMAT_1div2p3INCH:  {"1/2.3\"", 5.6},
+	C.LIBRAW_FORMAT_1div1p7INCH:  {"1/1.7\"", 4.6},
+	C.LIBRAW_FORMAT_FT:           {"Four Thirds", 2},
+	C.LIBRAW_FORMAT_CROP645:      {"645 Crop", 0.64},
+	C.LIBRAW_FORMAT_LeicaS:       {"Leica S", 0.8},
+	C.LIBRAW_FORMAT_645:          {"645", 0.62},
+	C.LIBRAW_FORMAT_66:           {"6x6", 0.55},
+	C.LIBRAW_FORMAT_69:           {"6x9", 0.43},
+	C.LIBRAW_FORMAT_LF:           {"Large Format", 0.27},
+	C.LIBRAW_FORMAT_Leica_DMR:    {"Leica DMR", 1.37},
+	C.LIBRAW_FORMAT_67:           {"6x7", 0.48},
+	C.LIBRAW_FORMAT_SigmaAPSC:    {"APS-C", 1.5},
+	C.LIBRAW_FORMAT_SigmaMerrill: {"APS-C", 1.5},
+	C.LIBRAW_FORMAT_SigmaAPSH:    {"APS-H", 1.35},
+	C.LIBRAW_FORMAT_3648:         {"36x48", 0.72},
+	C.LIBRAW_FORMAT_68:           {"6x8", 0.46},
+}
+
+// Returns the sensor format name and the crop factor of the camera. The crop factor is derived from the recorded
+// 35mm equivalent focal length if available, otherwise from the sensor format. Zero is returned if both are unknown.
+func sensorFormatOf(librawProcessor *C.libraw_data_t, focal float64, focal35mm float64) (string, float64) {
+	format, ok := sensorFormats[C.int(librawProcessor.lens.makernotes.CameraFormat)]
+	crop := format.crop
+	if ok && format.crop == 1.5 && librawProcessor.idata.maker_index == C.LIBRAW_CAMERAMAKER_Canon {
+		crop = 1.6
+	}
+	if focal > 0 && focal35mm > 0 {
+		crop = focal35mm / focal
+	}
+	return format.name, crop
+}
+
+// Returns the kind of sensor data of the opened image. Canon sRAW and Nikon small NEF files are decoded to full color
+// pixels, like linear DNG files, and have no filter array.
+func rawTypeOf(librawProcessor *C.libraw_data_t) RawType {
+	idata := &librawProcessor.idata
+	switch {
+	case idata.is_foveon != 0:
+		return RawTypeFoveon
+	case idata.colors == 1:
+		return RawTypeMonochrome
+	case idata.filters == 9:
+		return RawTypeXTrans
+	case idata.filters != 0:
+		return RawTypeBayer
+	case idata.colors >= 3:
+		return RawTypeLinear
+	}
+	return RawTypeUnknown
+}
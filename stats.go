@@ -0,0 +1,108 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+)
+
+// Stats holds exposure statistics of a processed image, with 8-bit sample values.
+type Stats struct {
+	// Histograms of the red, green, blue and luminance (Rec. 709) values
+	Histogram [4][256]int `json:"histogram"`
+	// Mean and median of the red, green, blue and luminance values
+	Mean   [4]float64 `json:"mean"`
+	Median [4]int     `json:"median"`
+	// Percentage of the pixels with saturated (255) or black (0) red, green and blue values
+	ClippedChannels [3]float64 `json:"clipped_channels"`
+	CrushedChannels [3]float64 `json:"crushed_channels"`
+	// Percentage of the pixels with any channel saturated, and with all channels black
+	Clipped float64 `json:"clipped"`
+	Crushed float64 `json:"crushed"`
+	Pixels  int     `json:"pixels"`
+}
+
+// Reads a RAW image file from file system and computes its exposure statistics from a half size render with the
+// default processing, e.g. for flagging badly exposed frames.
+func Analyze(path string) (Stats, error) {
+	img, err := ImportRawWithOptions(path, WithHalfSize())
+	if err != nil {
+		return Stats{}, err
+	}
+	return AnalyzeImage(img), nil
+}
+
+// Computes the exposure statistics of an image, e.g. of a processed RAW image or an embedded thumbnail.
+func AnalyzeImage(img image.Image) Stats {
+	stats := Stats{}
+	clipped, crushed := 0, 0
+	var clippedChannels, crushedChannels [3]int
+
+	add := func(rgb [3]uint8) {
+		luma := (2126*int(rgb[0]) + 7152*int(rgb[1]) + 722*int(rgb[2]) + 5000) / 10000
+		stats.Histogram[3][luma]++
+		anyClipped, allCrushed := false, true
+		for c, v := range rgb {
+			stats.Histogram[c][v]++
+			if v == 255 {
+				clippedChannels[c]++
+				anyClipped = true
+			}
+			if v == 0 {
+				crushedChannels[c]++
+			} else {
+				allCrushed = false
+			}
+		}
+		if anyClipped {
+			clipped++
+		}
+		if allCrushed {
+			crushed++
+		}
+	}
+
+	b := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				i := rgba.PixOffset(x, y)
+				add([3]uint8{rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2]})
+			}
+		}
+	} else {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, _ := img.At(x, y).RGBA()
+				add([3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8)})
+			}
+		}
+	}
+
+	stats.Pixels = b.Dx() * b.Dy()
+	if stats.Pixels == 0 {
+		return stats
+	}
+	percent := func(n int) float64 {
+		return 100 * float64(n) / float64(stats.Pixels)
+	}
+	stats.Clipped = percent(clipped)
+	stats.Crushed = percent(crushed)
+	for c := 0; c < 3; c++ {
+		stats.ClippedChannels[c] = percent(clippedChannels[c])
+		stats.CrushedChannels[c] = percent(crushedChannels[c])
+	}
+	for c, histogram := range stats.Histogram {
+		sum, count := 0, 0
+		stats.Median[c] = -1
+		for v, n := range histogram {
+			sum += v * n
+			count += n
+			if stats.Median[c] < 0 && 2*count >= stats.Pixels {
+				stats.Median[c] = v
+			}
+		}
+		stats.Mean[c] = float64(sum) / float64(stats.Pixels)
+	}
+	return stats
+}
@@ -0,0 +1,65 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	img.Set(1, 0, color.RGBA{R: 255, G: 100, B: 0, A: 255})
+	img.Set(0, 1, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	img.Set(1, 1, color.RGBA{R: 50, G: 100, B: 150, A: 255})
+
+	stats := AnalyzeImage(img)
+	if stats.Pixels != 4 {
+		t.Fatalf("pixels = %v, want 4", stats.Pixels)
+	}
+	if stats.Clipped != 50 || stats.Crushed != 25 {
+		t.Errorf("clipped, crushed = %v%%, %v%%, want 50%%, 25%%", stats.Clipped, stats.Crushed)
+	}
+	if stats.ClippedChannels != [3]float64{50, 25, 25} || stats.CrushedChannels != [3]float64{25, 25, 50} {
+		t.Errorf("clipped, crushed channels = %v, %v, want [50 25 25], [25 25 50]", stats.ClippedChannels, stats.CrushedChannels)
+	}
+	if stats.Histogram[0][255] != 2 || stats.Histogram[1][100] != 2 || stats.Histogram[3][255] != 1 || stats.Histogram[3][0] != 1 {
+		t.Errorf("histograms do not count the pixel values")
+	}
+	if stats.Mean[0] != 140 || stats.Mean[2] != 101.25 {
+		t.Errorf("red, blue mean = %v, %v, want 140, 101.25", stats.Mean[0], stats.Mean[2])
+	}
+	if stats.Median[1] != 100 {
+		t.Errorf("green median = %v, want 100", stats.Median[1])
+	}
+
+	// images of other types give the same statistics
+	nrgba := image.NewNRGBA(img.Bounds())
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			nrgba.Set(x, y, img.At(x, y))
+		}
+	}
+	if got := AnalyzeImage(nrgba); got != stats {
+		t.Errorf("statistics of the NRGBA image differ from the RGBA image")
+	}
+	if got := AnalyzeImage(image.NewRGBA(image.Rectangle{})); got.Pixels != 0 || got.Mean != [4]float64{} {
+		t.Errorf("statistics of an empty image = %+v, want none", got)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	stats, err := Analyze(testDNG(t, "RGGB"))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if want := testWidth / 2 * testHeight / 2; stats.Pixels != want {
+		t.Errorf("pixels = %v, want %v of the half size render", stats.Pixels, want)
+	}
+	if _, err := Analyze(filepath.Join(t.TempDir(), "missing.dng")); err == nil {
+		t.Errorf("Analyze of a missing file succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+package golibraw
+
+import (
+	"math"
+)
+
+// Estimates the correlated color temperature (Kelvin) and tint of the light the camera white balance was set for,
+// from the camera white balance multipliers and the XYZ to camera matrix.
+// Tint is the distance from the Planckian locus (Duv) scaled by -3000, positive towards magenta like in common RAW converters.
+func colorTemperature(camMul [4]float64, camXYZ [][]float64) (float64, float64, bool) {
+	if len(camXYZ) < 3 || camMul[0] <= 0 || camMul[1] <= 0 || camMul[2] <= 0 {
+		return 0, 0, false
+	}
+
+	// camera response to the white the multipliers neutralize
+	neutral := [3]float64{camMul[1] / camMul[0], 1, camMul[1] / camMul[2]}
+
+	m := [3][3]float64{}
+	for i := 0; i < 3; i++ {
+		if len(camXYZ[i]) < 3 {
+			return 0, 0, false
+		}
+		copy(m[i][:], camXYZ[i][:3])
+	}
+	inv, ok := invert3(m)
+	if !ok {
+		return 0, 0, false
+	}
+
+	var xyz [3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			xyz[i] += inv[i][j] * neutral[j]
+		}
+	}
+	sum := xyz[0] + xyz[1] + xyz[2]
+	if sum <= 0 {
+		return 0, 0, false
+	}
+	x, y := xyz[0]/sum, xyz[1]/sum
+
+	// McCamy's approximation of the correlated color temperature
+	n := (x - 0.3320) / (0.1858 - y)
+	cct := 449*n*n*n + 3525*n*n + 6823.3*n + 5520.33
+	if cct <= 0 || math.IsNaN(cct) || math.IsInf(cct, 0) {
+		return 0, 0, false
+	}
+
+	// distance from the Planckian locus in the CIE 1960 UCS, with Krystek's approximation of the locus
+	d := -2*x + 12*y + 3
+	u, v := 4*x/d, 6*y/d
+	t := cct
+	up := (0.860117757 + 1.54118254e-4*t + 1.28641212e-7*t*t) / (1 + 8.42420235e-4*t + 7.08145163e-7*t*t)
+	vp := (0.317398726 + 4.22806245e-5*t + 4.20481691e-8*t*t) / (1 - 2.89741816e-5*t + 1.61456053e-7*t*t)
+	duv := math.Hypot(u-up, v-vp)
+	if v < vp {
+		duv = -duv
+	}
+	return cct, -duv * 3000, true
+}
+
+func invert3(m [3][3]float64) ([3][3]float64, bool) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if math.Abs(det) < 1e-12 {
+		return [3][3]float64{}, false
+	}
+	var inv [3][3]float64
+	inv[0][0] = (m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det
+	inv[0][1] = (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det
+	inv[0][2] = (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det
+	inv[1][0] = (m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det
+	inv[1][1] = (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det
+	inv[1][2] = (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det
+	inv[2][0] = (m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det
+	inv[2][1] = (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det
+	inv[2][2] = (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det
+	return inv, true
+}
@@ -0,0 +1,41 @@
+package golibraw
+
+import "testing"
+
+// XYZ to linear sRGB, the camera of the tests sees sRGB
+var testSRGB = [][]float64{{3.2406, -1.5372, -0.4986}, {-0.9689, 1.8758, 0.0415}, {0.0557, -0.2040, 1.0570}}
+
+func TestColorTemperature(t *testing.T) {
+	// equal multipliers neutralize the D65 white of sRGB
+	cct, tint, ok := colorTemperature([4]float64{1, 1, 1, 1}, testSRGB)
+	if !ok || cct < 6400 || cct > 6600 {
+		t.Errorf("temperature of D65 = %v, %v, want about 6500 K", cct, ok)
+	}
+	if tint < -20 || tint > 20 {
+		t.Errorf("tint of D65 = %v, want about neutral", tint)
+	}
+
+	// more blue gain is set for warmer light
+	warm, _, ok := colorTemperature([4]float64{1, 1, 2, 1}, testSRGB)
+	if !ok || warm >= cct {
+		t.Errorf("temperature with blue gain = %v, %v, want below %v", warm, ok, cct)
+	}
+	cold, _, ok := colorTemperature([4]float64{2, 1, 1, 1}, testSRGB)
+	if !ok || cold <= cct {
+		t.Errorf("temperature with red gain = %v, %v, want above %v", cold, ok, cct)
+	}
+
+	for name, test := range map[string]struct {
+		camMul [4]float64
+		camXYZ [][]float64
+	}{
+		"no multipliers": {[4]float64{}, testSRGB},
+		"no matrix":      {[4]float64{1, 1, 1, 1}, nil},
+		"short rows":     {[4]float64{1, 1, 1, 1}, [][]float64{{1}, {1}, {1}}},
+		"singular":       {[4]float64{1, 1, 1, 1}, [][]float64{{1, 0, 0}, {1, 0, 0}, {0, 0, 1}}},
+	} {
+		if _, _, ok := colorTemperature(test.camMul, test.camXYZ); ok {
+			t.Errorf("colorTemperature with %v succeeded", name)
+		}
+	}
+}
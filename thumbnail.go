@@ -0,0 +1,310 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <stdlib.h>
+// #include <libraw/libraw.h>
+//
+// // The list of thumbnails was added in libraw 0.21, older versions have none
+// typedef struct {
+// 	int format, bits;
+// 	unsigned width, height, length;
+// } thumb_item;
+//
+// static int thumb_count(libraw_data_t *lr) {
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// 	return lr->thumbs_list.thumbcount < LIBRAW_THUMBNAIL_MAXCOUNT ? lr->thumbs_list.thumbcount : LIBRAW_THUMBNAIL_MAXCOUNT;
+// #else
+// 	return 0;
+// #endif
+// }
+//
+// // format is 1 for JPEG, 2 for bitmap and 0 for unknown thumbnails, as ThumbnailFormat
+// static thumb_item get_thumb_item(libraw_data_t *lr, int i) {
+// 	thumb_item result = {0};
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// 	libraw_thumbnail_item_t *item = &lr->thumbs_list.thumblist[i];
+// 	result.width = item->twidth;
+// 	result.height = item->theight;
+// 	result.length = item->tlength;
+// 	switch (item->tformat) {
+// 	case LIBRAW_INTERNAL_THUMBNAIL_JPEG:
+// 		result.format = 1;
+// 		break;
+// 	case LIBRAW_INTERNAL_THUMBNAIL_PPM:
+// 	case LIBRAW_INTERNAL_THUMBNAIL_KODAK_RGB:
+// 	case LIBRAW_INTERNAL_THUMBNAIL_KODAK_YCBCR:
+// 		result.format = 2;
+// 		result.bits = 8;
+// 		break;
+// 	case LIBRAW_INTERNAL_THUMBNAIL_PPM16:
+// 		result.format = 2;
+// 		result.bits = 16;
+// 		break;
+// 	default:
+// 		break;
+// 	}
+// #endif
+// 	return result;
+// }
+//
+// static int unpack_thumb_ex(libraw_data_t *lr, int i) {
+// #if LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// 	return libraw_unpack_thumb_ex(lr, i);
+// #else
+// 	return LIBRAW_NOT_IMPLEMENTED;
+// #endif
+// }
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"time"
+	"unsafe"
+)
+
+// Unpacks the embedded thumbnail of the opened image.
+func (p *Processor) UnpackThumbnail() error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+	start := time.Now()
+	err := p.check(C.libraw_unpack_thumb(p.handle))
+	p.endStage("unpack_thumb", start, err)
+	if err != nil {
+		return fmt.Errorf("unpacking thumbnail from RAW failed with [%w]", err)
+	}
+	return nil
+}
+
+// Writes the unpacked thumbnail to the file system, JPEG thumbnails as is, bitmap thumbnails in PPM format.
+// UnpackThumbnail has to be called first.
+func (p *Processor) ExportThumbnail(exportPath string) error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+
+	cPath := C.CString(exportPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	if err := p.check(C.libraw_dcraw_thumb_writer(p.handle, cPath)); err != nil {
+		return fmt.Errorf("writing thumbnail failed with [%w]", err)
+	}
+	return nil
+}
+
+// Returns the unpacked thumbnail. UnpackThumbnail has to be called first.
+func (p *Processor) Thumbnail() ([]byte, ThumbnailInfo, error) {
+	if p.handle == nil {
+		return nil, ThumbnailInfo{}, fmt.Errorf("processor is closed")
+	}
+
+	var result C.int
+	thumb := C.libraw_dcraw_make_mem_thumb(p.handle, &result)
+	defer C.libraw_dcraw_clear_mem(thumb)
+
+	if err := goResult(result); err != nil {
+		return nil, ThumbnailInfo{}, fmt.Errorf("reading thumbnail failed with [%w]", err)
+	}
+	if thumb == nil {
+		return nil, ThumbnailInfo{}, fmt.Errorf("reading thumbnail failed")
+	}
+
+	info := ThumbnailInfo{
+		Width:  int(thumb.width),
+		Height: int(thumb.height),
+		Colors: int(thumb.colors),
+		Bits:   int(thumb.bits),
+		Size:   int(thumb.data_size),
+	}
+	switch thumb._type {
+	case C.LIBRAW_IMAGE_JPEG:
+		info.Format = ThumbnailJPEG
+	case C.LIBRAW_IMAGE_BITMAP:
+		info.Format = ThumbnailBitmap
+	}
+	return C.GoBytes(unsafe.Pointer(&thumb.data), C.int(thumb.data_size)), info, nil
+}
+
+// Reads a RAW image file from file system and returns the embedded thumbnail image - if it exists - with its format and size.
+// This method is significantly faster than importing the RAW image file.
+func ExtractThumbnailBytes(path string) ([]byte, ThumbnailInfo, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, ThumbnailInfo{}, err
+	}
+	defer processor.Close()
+
+	if err = processor.Open(path); err != nil {
+		return nil, ThumbnailInfo{}, err
+	}
+
+	if err = processor.UnpackThumbnail(); err != nil {
+		return nil, ThumbnailInfo{}, err
+	}
+
+	return processor.Thumbnail()
+}
+
+// Reads a RAW image file from file system and writes the embedded thumbnail image - if it exists - to w.
+// JPEG thumbnails are written as is, bitmap thumbnails in PPM format.
+func ExtractThumbnailTo(inputPath string, w io.Writer) error {
+	data, info, err := ExtractThumbnailBytes(inputPath)
+	if err != nil {
+		return err
+	}
+	return writeThumbnail(w, data, info)
+}
+
+// Writes a thumbnail returned by ExtractThumbnailBytes or Processor.Thumbnail to exportPath like ExtractThumbnail does,
+// JPEG thumbnails as is, bitmap thumbnails in PPM format, without reading the RAW image file again.
+func WriteThumbnail(exportPath string, data []byte, info ThumbnailInfo, output OutputOptions) error {
+	return encodeFile(exportPath, output, func(w io.Writer) error {
+		return writeThumbnail(w, data, info)
+	})
+}
+
+// Reads a RAW image file from file system and decodes the embedded thumbnail image - if it exists - to standard image.Image.
+// This method is significantly faster than importing the RAW image file.
+func ImportThumbnail(path string) (image.Image, error) {
+	data, info, err := ExtractThumbnailBytes(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeThumbnail(data, info)
+}
+
+func decodeThumbnail(data []byte, info ThumbnailInfo) (image.Image, error) {
+	switch info.Format {
+	case ThumbnailJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding JPEG thumbnail failed with [%w]", err)
+		}
+		return img, nil
+	case ThumbnailBitmap:
+		bitmap := rawImg{
+			Height:   info.Height,
+			Width:    info.Width,
+			Bits:     uint(info.Bits),
+			Colors:   info.Colors,
+			DataSize: len(data),
+			Data:     data,
+		}
+		if bitmap.Bits != 8 && bitmap.Bits != 16 || bitmap.Colors != 1 && bitmap.Colors != 3 {
+			return nil, fmt.Errorf("unsupported bitmap thumbnail with [%v] colors of [%v] bits", info.Colors, info.Bits)
+		}
+		if info.Width <= 0 || info.Height <= 0 || len(data) < info.Width*info.Height*info.Colors*info.Bits/8 {
+			return nil, fmt.Errorf("bitmap thumbnail of [%v] bytes is too short for [%vx%v] pixels", len(data), info.Width, info.Height)
+		}
+		switch {
+		case bitmap.Colors == 1 && bitmap.Bits == 16:
+			return bitmap.gray16(), nil
+		case bitmap.Colors == 1:
+			return bitmap.gray(), nil
+		case bitmap.Bits == 16:
+			return bitmap.nrgba64(), nil
+		}
+		return bitmap.rgba(), nil
+	default:
+		return nil, fmt.Errorf("unsupported thumbnail format [%v]", info.Format)
+	}
+}
+
+// Returns the list of thumbnails embedded in the opened image, in the order used by UnpackThumbnailIndex.
+// Needs libraw 0.21 or newer, fails with ErrUnsupportedByLibraw otherwise.
+func (p *Processor) Thumbnails() ([]ThumbnailInfo, error) {
+	if p.handle == nil {
+		return nil, fmt.Errorf("processor is closed")
+	}
+	if err := requireLibraw("thumbnail list", 0, 21); err != nil {
+		return nil, err
+	}
+
+	count := int(C.thumb_count(p.handle))
+	thumbnails := make([]ThumbnailInfo, 0, count)
+	for i := 0; i < count; i++ {
+		item := C.get_thumb_item(p.handle, C.int(i))
+		info := ThumbnailInfo{
+			Format: ThumbnailFormat(item.format),
+			Width:  int(item.width),
+			Height: int(item.height),
+			Size:   int(item.length),
+		}
+		if info.Format == ThumbnailBitmap {
+			info.Colors, info.Bits = 3, int(item.bits)
+		}
+		thumbnails = append(thumbnails, info)
+	}
+	return thumbnails, nil
+}
+
+// Unpacks the embedded thumbnail at index idx of the list returned by Thumbnails.
+// Needs libraw 0.21 or newer, fails with ErrUnsupportedByLibraw otherwise.
+func (p *Processor) UnpackThumbnailIndex(idx int) error {
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+	if err := requireLibraw("thumbnail list", 0, 21); err != nil {
+		return err
+	}
+	if err := p.check(C.unpack_thumb_ex(p.handle, C.int(idx))); err != nil {
+		return fmt.Errorf("unpacking thumbnail [%v] from RAW failed with [%w]", idx, err)
+	}
+	return nil
+}
+
+// Reads a RAW image file from file system and lists all the embedded thumbnails, e.g. to select the smallest one
+// satisfying a target resolution.
+func ListThumbnails(path string) ([]ThumbnailInfo, error) {
+	processor, err := NewProcessor()
+	if err != nil {
+		return nil, err
+	}
+	defer processor.Close()
+
+	if err = processor.Open(path); err != nil {
+		return nil, err
+	}
+	return processor.Thumbnails()
+}
+
+// Reads a RAW image file from file system and writes the embedded thumbnail at index idx of ListThumbnails to w.
+// JPEG thumbnails are written as is, bitmap thumbnails in PPM format.
+func ExtractThumbnailIndex(path string, idx int, w io.Writer) error {
+	processor, err := NewProcessor()
+	if err != nil {
+		return err
+	}
+	defer processor.Close()
+
+	if err = processor.Open(path); err != nil {
+		return err
+	}
+
+	if err = processor.UnpackThumbnailIndex(idx); err != nil {
+		return err
+	}
+
+	data, info, err := processor.Thumbnail()
+	if err != nil {
+		return err
+	}
+	return writeThumbnail(w, data, info)
+}
+
+func writeThumbnail(w io.Writer, data []byte, info ThumbnailInfo) error {
+	switch info.Format {
+	case ThumbnailJPEG:
+		_, err := w.Write(data)
+		return err
+	case ThumbnailBitmap:
+		return writePNM(w, info.Width, info.Height, info.Colors, info.Bits, data)
+	default:
+		return fmt.Errorf("unsupported thumbnail format [%v]", info.Format)
+	}
+}
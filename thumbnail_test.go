@@ -0,0 +1,263 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	previewWidth  = 32
+	previewHeight = 24
+)
+
+// Returns the path of a test DNG with a JPEG preview of previewWidth x previewHeight pixels chained after the raw
+// IFD, and the JPEG data of the preview
+func previewDNG(t testing.TB) (string, []byte) {
+	t.Helper()
+	preview := image.NewRGBA(image.Rect(0, 0, previewWidth, previewHeight))
+	for y := 0; y < previewHeight; y++ {
+		for x := 0; x < previewWidth; x++ {
+			preview.Set(x, y, color.RGBA{R: uint8(x * 8), G: uint8(y * 8), B: 0x80, A: 0xff})
+		}
+	}
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, preview, nil); err != nil {
+		t.Fatalf("failed to encode preview: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeDNG(&buf, testImage("RGGB"), testMetadata()); err != nil {
+		t.Fatalf("failed to write test DNG: %v", err)
+	}
+	if buf.Len()%2 == 1 {
+		buf.WriteByte(0)
+	}
+	data := buf.Bytes()
+
+	// link the preview IFD as the next IFD of the raw IFD, the preview data follows the preview IFD
+	ifdOffset := len(data)
+	next := 8 + 2 + 12*int(binary.LittleEndian.Uint16(data[8:]))
+	binary.LittleEndian.PutUint32(data[next:], uint32(ifdOffset))
+
+	type entry struct {
+		tag, typ uint16
+		value    uint32
+	}
+	entries := []entry{
+		{tagNewSubFileType, typeLong, 1},
+		{tagImageWidth, typeLong, previewWidth},
+		{tagImageLength, typeLong, previewHeight},
+		{tagBitsPerSample, typeShort, 8},
+		{tagCompression, typeShort, 7},
+		{tagPhotometric, typeShort, 6},
+		{tagStripOffsets, typeLong, uint32(ifdOffset + 2 + 12*10 + 4)},
+		{tagSamplesPerPixel, typeShort, 3},
+		{tagRowsPerStrip, typeLong, previewHeight},
+		{tagStripByteCounts, typeLong, uint32(jpegData.Len())},
+	}
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(entries)))
+	for _, e := range entries {
+		data = binary.LittleEndian.AppendUint16(data, e.tag)
+		data = binary.LittleEndian.AppendUint16(data, e.typ)
+		data = binary.LittleEndian.AppendUint32(data, 1)
+		if e.typ == typeShort {
+			data = binary.LittleEndian.AppendUint16(data, uint16(e.value))
+			data = binary.LittleEndian.AppendUint16(data, 0)
+		} else {
+			data = binary.LittleEndian.AppendUint32(data, e.value)
+		}
+	}
+	data = binary.LittleEndian.AppendUint32(data, 0)
+	data = append(data, jpegData.Bytes()...)
+
+	path := filepath.Join(t.TempDir(), "preview.dng")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write test DNG: %v", err)
+	}
+	return path, jpegData.Bytes()
+}
+
+func TestExtractThumbnailBytes(t *testing.T) {
+	path, preview := previewDNG(t)
+	data, info, err := ExtractThumbnailBytes(path)
+	if err != nil {
+		t.Fatalf("ExtractThumbnailBytes failed: %v", err)
+	}
+	if info.Format != ThumbnailJPEG {
+		t.Errorf("format = %v, want %v", info.Format, ThumbnailJPEG)
+	}
+	if !bytes.Equal(data, preview) {
+		t.Errorf("thumbnail of %v bytes differs from the embedded preview of %v bytes", len(data), len(preview))
+	}
+	if info.Size != len(data) {
+		t.Errorf("size = %v, want %v", info.Size, len(data))
+	}
+
+	if _, _, err := ExtractThumbnailBytes(testDNG(t, "RGGB")); err == nil {
+		t.Errorf("ExtractThumbnailBytes succeeded on a file without thumbnail")
+	}
+}
+
+func TestImportThumbnail(t *testing.T) {
+	path, _ := previewDNG(t)
+	img, err := ImportThumbnail(path)
+	if err != nil {
+		t.Fatalf("ImportThumbnail failed: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, previewWidth, previewHeight) {
+		t.Errorf("bounds = %v, want %vx%v", got, previewWidth, previewHeight)
+	}
+}
+
+func TestDecodeBitmapThumbnail(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   ThumbnailInfo
+		data   []byte
+		want   color.Color
+		errors bool
+	}{
+		{"rgb", ThumbnailInfo{Format: ThumbnailBitmap, Width: 2, Height: 1, Colors: 3, Bits: 8}, []byte{1, 2, 3, 4, 5, 6}, color.RGBA{R: 1, G: 2, B: 3, A: 0xff}, false},
+		{"gray", ThumbnailInfo{Format: ThumbnailBitmap, Width: 2, Height: 1, Colors: 1, Bits: 8}, []byte{7, 8}, color.Gray{Y: 7}, false},
+		{"gray16", ThumbnailInfo{Format: ThumbnailBitmap, Width: 1, Height: 1, Colors: 1, Bits: 16}, []byte{0x12, 0x34}, nil, false},
+		{"short", ThumbnailInfo{Format: ThumbnailBitmap, Width: 2, Height: 2, Colors: 3, Bits: 8}, []byte{1, 2, 3}, nil, true},
+		{"colors", ThumbnailInfo{Format: ThumbnailBitmap, Width: 1, Height: 1, Colors: 4, Bits: 8}, []byte{1, 2, 3, 4}, nil, true},
+		{"format", ThumbnailInfo{Width: 1, Height: 1, Colors: 3, Bits: 8}, []byte{1, 2, 3}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeThumbnail(tt.data, tt.info)
+			if tt.errors {
+				if err == nil {
+					t.Errorf("decodeThumbnail succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeThumbnail failed: %v", err)
+			}
+			if got := img.Bounds(); got != image.Rect(0, 0, tt.info.Width, tt.info.Height) {
+				t.Errorf("bounds = %v, want %vx%v", got, tt.info.Width, tt.info.Height)
+			}
+			if tt.want != nil && img.At(0, 0) != tt.want {
+				t.Errorf("first pixel = %v, want %v", img.At(0, 0), tt.want)
+			}
+		})
+	}
+}
+
+// Lists the thumbnails with libraw 0.21, expecting ErrUnsupportedByLibraw with older versions
+func TestListThumbnails(t *testing.T) {
+	path, preview := previewDNG(t)
+	thumbnails, err := ListThumbnails(path)
+	if requireLibraw("thumbnail list", 0, 21) != nil {
+		if !errors.Is(err, ErrUnsupportedByLibraw) {
+			t.Errorf("ListThumbnails error = %v, want %v", err, ErrUnsupportedByLibraw)
+		}
+		if err := ExtractThumbnailIndex(path, 0, io.Discard); !errors.Is(err, ErrUnsupportedByLibraw) {
+			t.Errorf("ExtractThumbnailIndex error = %v, want %v", err, ErrUnsupportedByLibraw)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("ListThumbnails failed: %v", err)
+	}
+
+	idx := -1
+	for i, info := range thumbnails {
+		if info.Format == ThumbnailJPEG && info.Width == previewWidth && info.Height == previewHeight {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		t.Fatalf("thumbnails = %+v, want the %vx%v JPEG preview", thumbnails, previewWidth, previewHeight)
+	}
+
+	var buf bytes.Buffer
+	if err := ExtractThumbnailIndex(path, idx, &buf); err != nil {
+		t.Fatalf("ExtractThumbnailIndex failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), preview) {
+		t.Errorf("thumbnail [%v] of %v bytes differs from the embedded preview of %v bytes", idx, buf.Len(), len(preview))
+	}
+	if err := ExtractThumbnailIndex(path, len(thumbnails), io.Discard); err == nil {
+		t.Errorf("ExtractThumbnailIndex succeeded with an index out of the list")
+	}
+}
+
+func TestExtractThumbnailTo(t *testing.T) {
+	path, preview := previewDNG(t)
+	var buf bytes.Buffer
+	if err := ExtractThumbnailTo(path, &buf); err != nil {
+		t.Fatalf("ExtractThumbnailTo failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), preview) {
+		t.Errorf("written thumbnail of %v bytes differs from the embedded preview of %v bytes", buf.Len(), len(preview))
+	}
+}
+
+func TestProcessorExportThumbnail(t *testing.T) {
+	path, preview := previewDNG(t)
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if err = processor.UnpackThumbnail(); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "thumb.jpg")
+	if err = processor.ExportThumbnail(output); err != nil {
+		t.Fatalf("ExportThumbnail failed: %v", err)
+	}
+	if written, _ := os.ReadFile(output); !bytes.Equal(written, preview) {
+		t.Errorf("exported thumbnail of %v bytes differs from the embedded preview of %v bytes", len(written), len(preview))
+	}
+
+	processor.Close()
+	if err = processor.UnpackThumbnail(); err == nil {
+		t.Error("UnpackThumbnail of a closed processor succeeded")
+	}
+	if err = processor.ExportThumbnail(output); err == nil {
+		t.Error("ExportThumbnail of a closed processor succeeded")
+	}
+	if _, _, err = processor.Thumbnail(); err == nil {
+		t.Error("Thumbnail of a closed processor succeeded")
+	}
+}
+
+func TestWriteThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	jpegPath := filepath.Join(dir, "thumb.jpg")
+	data := []byte{0xff, 0xd8, 0xff, 0xd9}
+	if err := WriteThumbnail(jpegPath, data, ThumbnailInfo{Format: ThumbnailJPEG}, OutputOptions{}); err != nil {
+		t.Fatalf("WriteThumbnail failed: %v", err)
+	}
+	if written, _ := os.ReadFile(jpegPath); !bytes.Equal(written, data) {
+		t.Errorf("JPEG thumbnail written as % x, want % x", written, data)
+	}
+	if err := WriteThumbnail(jpegPath, data, ThumbnailInfo{Format: ThumbnailJPEG}, OutputOptions{}); err == nil {
+		t.Errorf("WriteThumbnail replaced an existing file without Overwrite")
+	}
+
+	ppmPath := filepath.Join(dir, "thumb.ppm")
+	info := ThumbnailInfo{Format: ThumbnailBitmap, Width: 2, Height: 1, Colors: 3, Bits: 8}
+	if err := WriteThumbnail(ppmPath, []byte{1, 2, 3, 4, 5, 6}, info, OutputOptions{}); err != nil {
+		t.Fatalf("WriteThumbnail failed: %v", err)
+	}
+	if written, _ := os.ReadFile(ppmPath); !bytes.HasPrefix(written, []byte("P6")) || !bytes.HasSuffix(written, []byte{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("bitmap thumbnail written as %q, want a PPM image", written)
+	}
+}
@@ -0,0 +1,202 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// TIFF tags used by the package
+const (
+	tagNewSubFileType      = 0x00fe
+	tagCompression         = 0x0103
+	tagPhotometric         = 0x0106
+	tagMake                = 0x010f
+	tagSubIFDs             = 0x014a
+	tagCopyright           = 0x8298
+	tagExifIFD             = 0x8769
+	tagOffsetTime          = 0x9011
+	tagExposureBias        = 0x9204
+	tagSubjectDistance     = 0x9206
+	tagFlash               = 0x9209
+	tagMakerNote           = 0x927c
+	tagDNGVersion          = 0xc612
+	tagBaselineExposure    = 0xc62a
+	tagOpcodeList1         = 0xc740
+	tagOpcodeList2         = 0xc741
+	tagOpcodeList3         = 0xc74e
+	tagProfileGainTableMap = 0xcd2d
+
+	tagNikonShutterCount = 0x00a7
+)
+
+// Photometric interpretations of RAW sensor data
+const (
+	photometricCFA       = 32803
+	photometricLinearRaw = 34892
+)
+
+// TIFF field types
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeUndefined = 7
+	typeSLong     = 9
+	typeSRational = 10
+)
+
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// Minimal reader of TIFF structures, shared by the TIFF based RAW containers (CR2, NEF, ARW, DNG, ORF, RW2, ...)
+type tiffReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+	first int64
+}
+
+type tiffEntry struct {
+	tag    uint16
+	typ    uint16
+	count  int
+	offset int64
+}
+
+// Returns a reader for r if it starts with a TIFF (or TIFF-like RAW) header.
+func newTiffReader(r io.ReaderAt) (*tiffReader, bool) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, false
+	}
+
+	t := &tiffReader{r: r}
+	switch {
+	case bytes.HasPrefix(header, []byte("II")):
+		t.order = binary.LittleEndian
+	case bytes.HasPrefix(header, []byte("MM")):
+		t.order = binary.BigEndian
+	default:
+		return nil, false
+	}
+
+	// regular TIFF, Olympus ORF and Panasonic RW2 variants
+	switch t.order.Uint16(header[2:]) {
+	case 0x2a, 0x4f52, 0x5352, 0x55:
+	default:
+		return nil, false
+	}
+	t.first = int64(t.order.Uint32(header[4:]))
+	return t, true
+}
+
+func (t *tiffReader) read(offset int64, n int) []byte {
+	if offset < 0 || n <= 0 || n > 1<<24 {
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := t.r.ReadAt(b, offset); err != nil {
+		return nil
+	}
+	return b
+}
+
+// Returns the entries of the IFD at offset and the offset of the next IFD.
+func (t *tiffReader) ifd(offset int64) ([]tiffEntry, int64) {
+	header := t.read(offset, 2)
+	if header == nil {
+		return nil, 0
+	}
+	count := int(t.order.Uint16(header))
+	data := t.read(offset+2, count*12+4)
+	if data == nil {
+		return nil, 0
+	}
+
+	entries := make([]tiffEntry, 0, count)
+	for i := 0; i < count; i++ {
+		e := data[i*12:]
+		entry := tiffEntry{
+			tag:   t.order.Uint16(e),
+			typ:   t.order.Uint16(e[2:]),
+			count: int(t.order.Uint32(e[4:])),
+		}
+		if typeSizes[entry.typ]*entry.count <= 4 {
+			entry.offset = offset + 2 + int64(i*12) + 8
+		} else {
+			entry.offset = int64(t.order.Uint32(e[8:]))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, int64(t.order.Uint32(data[count*12:]))
+}
+
+func findEntry(entries []tiffEntry, tag uint16) (tiffEntry, bool) {
+	for _, e := range entries {
+		if e.tag == tag {
+			return e, true
+		}
+	}
+	return tiffEntry{}, false
+}
+
+func (t *tiffReader) bytes(e tiffEntry) []byte {
+	return t.read(e.offset, typeSizes[e.typ]*e.count)
+}
+
+func (t *tiffReader) ascii(e tiffEntry) string {
+	return strings.TrimRight(string(t.bytes(e)), "\x00 ")
+}
+
+// Returns the i-th value of an integer entry
+func (t *tiffReader) uint(e tiffEntry, i int) uint32 {
+	if i >= e.count {
+		return 0
+	}
+	switch e.typ {
+	case typeByte, typeUndefined:
+		b := t.read(e.offset+int64(i), 1)
+		if b != nil {
+			return uint32(b[0])
+		}
+	case typeShort:
+		b := t.read(e.offset+int64(2*i), 2)
+		if b != nil {
+			return uint32(t.order.Uint16(b))
+		}
+	case typeLong, typeSLong:
+		b := t.read(e.offset+int64(4*i), 4)
+		if b != nil {
+			return t.order.Uint32(b)
+		}
+	}
+	return 0
+}
+
+// Returns the i-th value of a rational entry
+func (t *tiffReader) rational(e tiffEntry, i int) float64 {
+	if i >= e.count {
+		return 0
+	}
+	b := t.read(e.offset+int64(8*i), 8)
+	if b == nil {
+		return 0
+	}
+	switch e.typ {
+	case typeRational:
+		num, den := t.order.Uint32(b), t.order.Uint32(b[4:])
+		if den == 0 {
+			return 0
+		}
+		return float64(num) / float64(den)
+	case typeSRational:
+		num, den := int32(t.order.Uint32(b)), int32(t.order.Uint32(b[4:]))
+		if den == 0 {
+			return 0
+		}
+		return float64(num) / float64(den)
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package golibraw
+
+import (
+	"time"
+)
+
+// Returns the capture time of the image. If the camera recorded the offset from UTC, the time is in that zone,
+// otherwise the recorded wall clock time is interpreted in the local time zone.
+// The zero time is returned if the capture time is unknown.
+func (m Metadata) Time() time.Time {
+	if offset, ok := parseOffset(m.TimeOffset); ok {
+		return m.TimeIn(time.FixedZone(m.TimeOffset, offset))
+	}
+	return m.TimeIn(time.Local)
+}
+
+// Returns the capture time of the image with the wall clock time recorded by the camera interpreted in loc,
+// e.g. the time zone the photo was taken in. The offset recorded by the camera is ignored.
+// The zero time is returned if the capture time is unknown.
+func (m Metadata) TimeIn(loc *time.Location) time.Time {
+	if m.Timestamp == 0 {
+		return time.Time{}
+	}
+	// libraw converts the recorded wall clock time with mktime, i.e. in the local time zone of the process
+	wall := time.Unix(m.Timestamp, 0).In(time.Local)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+}
+
+// Parses an EXIF time offset ("+02:00") to seconds east of UTC
+func parseOffset(offset string) (int, bool) {
+	t, err := time.Parse("-07:00", offset)
+	if err != nil {
+		return 0, false
+	}
+	_, seconds := t.Zone()
+	return seconds, true
+}
@@ -0,0 +1,44 @@
+package golibraw
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMetadataTime(t *testing.T) {
+	// libraw converts the recorded wall clock time in the local time zone
+	wall := time.Date(2024, 5, 1, 10, 20, 30, 0, time.Local)
+	m := Metadata{Timestamp: wall.Unix()}
+
+	if got, want := m.TimeIn(time.UTC), time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("TimeIn(UTC) = %v, want %v", got, want)
+	}
+	if got := m.Time(); !got.Equal(wall) {
+		t.Errorf("Time() without offset = %v, want %v", got, wall)
+	}
+
+	m.TimeOffset = "+02:00"
+	got := m.Time()
+	if _, offset := got.Zone(); offset != 2*60*60 || got.Hour() != 10 || got.Minute() != 20 {
+		t.Errorf("Time() with offset %v = %v, want 10:20:30 +02:00", m.TimeOffset, got)
+	}
+	if got, want := m.TimeIn(time.UTC), time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("TimeIn(UTC) ignoring the offset = %v, want %v", got, want)
+	}
+
+	m.TimeOffset = "   :  "
+	if got := m.Time(); !got.Equal(wall) {
+		t.Errorf("Time() with an invalid offset = %v, want %v", got, wall)
+	}
+	if got := (Metadata{}).Time(); !got.IsZero() {
+		t.Errorf("Time() of an unknown capture time = %v, want zero", got)
+	}
+}
+
+func TestReadExifTimeOffset(t *testing.T) {
+	data := exifTIFF(nil, testEntry{tagOffsetTime, typeASCII, []byte("-05:00\x00")})
+	if exif := readExif(bytes.NewReader(data)); exif.timeOffset != "-05:00" {
+		t.Errorf("time offset = %q, want -05:00", exif.timeOffset)
+	}
+}
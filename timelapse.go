@@ -0,0 +1,73 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"fmt"
+	"image"
+)
+
+// Share of pixels clipped by the automatic brightening of libraw
+const defaultAutoBrightThreshold = 0.01
+
+// Returns processing options rendering every frame of a timelapse with the settings of the reference frame, for
+// flicker-free frames. The white balance recorded for the reference is used for all frames and the automatic
+// brightening of libraw, which varies from frame to frame, is replaced by the fixed brightness it chooses for the
+// reference. The given options are applied to the reference and included in the result, e.g. for a sequence:
+//
+//	opts, err := TimelapseOptions(frames[0], WithBitDepth(16))
+//	results, err := Batch{Options: opts}.Convert(ctx, frames, "frames", FormatTIFF)
+func TimelapseOptions(reference string, opts ...Option) ([]Option, error) {
+	metadata, err := ExtractMetadata(reference)
+	if err != nil {
+		return nil, err
+	}
+	mul := metadata.Calibration.CamMul
+	if mul[0] <= 0 || mul[1] <= 0 || mul[2] <= 0 {
+		mul = metadata.Calibration.PreMul
+	}
+	if mul[0] <= 0 || mul[1] <= 0 || mul[2] <= 0 {
+		return nil, fmt.Errorf("white balance of reference frame [%v] is unknown", reference)
+	}
+	whiteBalance := WithWhiteBalanceMultipliers(mul[0], mul[1], mul[2], mul[3])
+
+	// the linear render without brightening gives the white point libraw picks for auto brightening
+	render := append(append([]Option{}, opts...), whiteBalance, WithHalfSize(), WithLinearOutput())
+	linear, err := ImportRawWithOptions(reference, render...)
+	if err != nil {
+		return nil, err
+	}
+	white := autoBrightWhite(linear, defaultAutoBrightThreshold)
+
+	locked := append([]Option{}, opts...)
+	return append(locked, whiteBalance, WithAutoBright(false), WithBrightness(0x2000<<3/white)), nil
+}
+
+// Returns the white point of automatic brightening: the highest of the channel values exceeded by threshold share of the
+// pixels, like libraw computes it on 13-bit histograms
+func autoBrightWhite(img image.Image, threshold float64) float64 {
+	var histogram [3][0x2000]int
+	bounds := img.Bounds()
+	row := make([]float32, bounds.Dx()*4)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		readRow(img, y, row)
+		for i := 0; i < len(row); i += 4 {
+			for c := range histogram {
+				histogram[c][int(row[i+c])>>3]++
+			}
+		}
+	}
+
+	clipped := int(float64(bounds.Dx()*bounds.Dy()) * threshold)
+	white := 32
+	for c := range histogram {
+		total, val := 0, 0x2000
+		for val--; val > 32; val-- {
+			if total += histogram[c][val]; total > clipped {
+				break
+			}
+		}
+		white = max(white, val)
+	}
+	return float64(white << 3)
+}
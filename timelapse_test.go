@@ -0,0 +1,90 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoBrightWhite(t *testing.T) {
+	// 16-bit images of 100x100 pixels at 0x4000 with the given number of pixels at full scale
+	frame := func(highlights int) image.Image {
+		img := image.NewNRGBA64(image.Rect(0, 0, 100, 100))
+		for i := 0; i < 100*100; i++ {
+			c := color.NRGBA64{R: 0x4000, G: 0x4000, B: 0x4000, A: 0xffff}
+			if i < highlights {
+				c = color.NRGBA64{R: 0xffff, G: 0xffff, B: 0xffff, A: 0xffff}
+			}
+			img.SetNRGBA64(i%100, i/100, c)
+		}
+		return img
+	}
+	for _, tt := range []struct {
+		highlights int
+		want       float64
+	}{
+		// up to 1% of the pixels are clipped
+		{0, 0x4000},
+		{50, 0x4000},
+		{200, 0x1fff << 3},
+	} {
+		if got := autoBrightWhite(frame(tt.highlights), defaultAutoBrightThreshold); got != tt.want {
+			t.Errorf("white with %v highlights = %v, want %v", tt.highlights, got, tt.want)
+		}
+	}
+	// black frames are not brightened without limit
+	if got := autoBrightWhite(image.NewNRGBA64(image.Rect(0, 0, 10, 10)), defaultAutoBrightThreshold); got != 32<<3 {
+		t.Errorf("white of a black frame = %v, want %v", got, 32<<3)
+	}
+}
+
+func TestTimelapseOptions(t *testing.T) {
+	img := testImage("RGGB")
+	reference := writeTestDNG(t, img, testMetadata())
+	for i := range img.Pix {
+		img.Pix[i] /= 2
+	}
+	dark := writeTestDNG(t, img, testMetadata())
+
+	opts, err := TimelapseOptions(reference, WithBitDepth(16))
+	if err != nil {
+		t.Fatalf("TimelapseOptions failed: %v", err)
+	}
+	processor, err := NewProcessor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer processor.Close()
+	newOptions(opts).apply(processor)
+	params := processor.handle.params
+	if params.output_bps != 16 || params.no_auto_bright != 1 || params.user_mul[0] != 2 || params.user_mul[2] != 1.5 {
+		t.Errorf("options do not keep the bit depth, lock the white balance and disable auto brightening")
+	}
+	if params.bright <= 0 {
+		t.Errorf("brightness = %v, want the positive brightness of the reference", params.bright)
+	}
+
+	// the locked brightness keeps the darker frame darker, frames are not brightened independently
+	mean := func(path string) float64 {
+		img, err := ImportRawWithOptions(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.NRGBA64)
+		sum := 0
+		for i := 0; i+1 < len(rgba.Pix); i += 2 {
+			sum += int(rgba.Pix[i])<<8 | int(rgba.Pix[i+1])
+		}
+		return float64(sum) / float64(len(rgba.Pix)/2)
+	}
+	if a, b := mean(dark), mean(reference); a >= 0.9*b {
+		t.Errorf("mean of the darker frame = %v, not below the reference = %v", a, b)
+	}
+
+	if _, err := TimelapseOptions(filepath.Join(t.TempDir(), "missing.dng")); err == nil {
+		t.Errorf("TimelapseOptions succeeded on a missing file")
+	}
+}
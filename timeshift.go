@@ -0,0 +1,136 @@
+package golibraw
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TimeShift is the corrected capture time of a file of a multi-camera shoot, see ShiftTimestamps.
+type TimeShift struct {
+	Path string
+	// Camera the offset was selected by, see CameraKey
+	Camera   string
+	Original time.Time
+	Shifted  time.Time
+	Metadata Metadata
+	Err      error
+}
+
+// Returns the key identifying the camera body of an image for ShiftTimestamps: its serial number,
+// or the maker and model if the camera does not record the serial number.
+func CameraKey(m Metadata) string {
+	if serial := strings.TrimSpace(m.Camera.Serial); serial != "" {
+		return serial
+	}
+	return strings.TrimSpace(m.Camera.Make + " " + m.Camera.Model)
+}
+
+// Returns the camera key of path and the offset of its clock from the clock of the camera of reference, from two images
+// of the same moment, e.g. of a clock or of a flash fired in front of all the cameras. The offset is added to the capture
+// times by ShiftTimestamps to synchronize the camera with the reference. The capture times of files without a recorded
+// UTC offset are interpreted in loc, the time zone the camera clocks are set to.
+func ClockOffset(reference string, path string, loc *time.Location) (string, time.Duration, error) {
+	ref, err := ExtractMetadata(reference)
+	if err != nil {
+		return "", 0, err
+	}
+	other, err := ExtractMetadata(path)
+	if err != nil {
+		return "", 0, err
+	}
+	if ref.Timestamp == 0 || other.Timestamp == 0 {
+		return "", 0, fmt.Errorf("capture time of [%v] or [%v] is unknown", reference, path)
+	}
+	return CameraKey(other), captureTime(ref, loc).Sub(captureTime(other, loc)), nil
+}
+
+// Returns the capture time in the UTC offset recorded by the camera, or in loc if the camera did not record it
+func captureTime(m Metadata, loc *time.Location) time.Time {
+	if offset, ok := parseOffset(m.TimeOffset); ok {
+		return m.TimeIn(time.FixedZone(m.TimeOffset, offset))
+	}
+	return m.TimeIn(loc)
+}
+
+// Reads the capture times of the RAW image files of paths and shifts them by the offset of their camera, keyed by
+// CameraKey, to synchronize the clocks of the cameras of a shoot. Files of cameras without an offset keep their time.
+// The capture times of files without a recorded UTC offset are interpreted in loc, the time zone the camera clocks are
+// set to, which is also the zone of the shifted times written by WriteTimeShiftSidecars and WriteTimeShiftCSV.
+// The results are in the order of paths. The RAW files are never modified.
+func ShiftTimestamps(paths []string, offsets map[string]time.Duration, loc *time.Location) []TimeShift {
+	shifts := make([]TimeShift, len(paths))
+	for i, path := range paths {
+		shift := TimeShift{Path: path}
+		shift.Metadata, shift.Err = ExtractMetadata(path)
+		if shift.Err == nil && shift.Metadata.Timestamp == 0 {
+			shift.Err = fmt.Errorf("capture time of [%v] is unknown", path)
+		}
+		if shift.Err == nil {
+			shift.Camera = CameraKey(shift.Metadata)
+			shift.Original = captureTime(shift.Metadata, loc)
+			shift.Shifted = shift.Original.Add(offsets[shift.Camera])
+		}
+		shifts[i] = shift
+	}
+	return shifts
+}
+
+// Writes an XMP sidecar with the shifted capture time next to every successfully shifted file, named like the RAW
+// file with the extension replaced by ".xmp" (the Lightroom and Capture One convention). Existing sidecars may hold
+// the edits of a RAW converter, they are only replaced with overwrite. Without it, nothing is written if any of the
+// sidecars exists.
+func WriteTimeShiftSidecars(shifts []TimeShift, overwrite bool) error {
+	if !overwrite {
+		for _, shift := range shifts {
+			sidecar := timeShiftSidecar(shift.Path)
+			if _, err := os.Stat(sidecar); shift.Err == nil && err == nil {
+				return fmt.Errorf("XMP sidecar [%v] already exists", sidecar)
+			}
+		}
+	}
+	for _, shift := range shifts {
+		if shift.Err != nil {
+			continue
+		}
+		shifted := shift.Shifted.Format(time.RFC3339)
+		err := WriteXMPSidecar(timeShiftSidecar(shift.Path), shift.Metadata, map[string]string{
+			"exif:DateTimeOriginal": shifted,
+			"photoshop:DateCreated": shifted,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func timeShiftSidecar(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".xmp"
+}
+
+// Writes the shifts as CSV with a header row: the path, the camera key, the original and the shifted capture time in
+// RFC 3339 format and the error of the files which could not be shifted.
+func WriteTimeShiftCSV(w io.Writer, shifts []TimeShift) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"path", "camera", "original", "shifted", "error"})
+	for _, shift := range shifts {
+		record := []string{shift.Path, shift.Camera, "", "", ""}
+		if shift.Err != nil {
+			record[4] = shift.Err.Error()
+		} else {
+			record[2] = shift.Original.Format(time.RFC3339)
+			record[3] = shift.Shifted.Format(time.RFC3339)
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV with [%w]", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package golibraw
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTimeShiftSidecars(t *testing.T) {
+	dir := t.TempDir()
+	zone := time.FixedZone("CEST", 2*60*60)
+	shifted := time.Date(2024, 6, 1, 12, 30, 0, 0, zone)
+	shifts := []TimeShift{
+		{Path: filepath.Join(dir, "a.NEF"), Shifted: shifted},
+		{Path: filepath.Join(dir, "b.NEF"), Shifted: shifted},
+	}
+	edits := []byte("edits of a RAW converter")
+	if err := os.WriteFile(filepath.Join(dir, "b.xmp"), edits, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteTimeShiftSidecars(shifts, false); err == nil {
+		t.Fatalf("WriteTimeShiftSidecars replaced an existing sidecar without overwrite")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.xmp")); err == nil {
+		t.Errorf("WriteTimeShiftSidecars wrote sidecars before failing")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.xmp")); string(data) != string(edits) {
+		t.Errorf("existing sidecar was modified")
+	}
+
+	if err := WriteTimeShiftSidecars(shifts, true); err != nil {
+		t.Fatalf("WriteTimeShiftSidecars failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "b.xmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "2024-06-01T12:30:00+02:00") {
+		t.Errorf("sidecar does not hold the shifted time in its zone:\n%s", data)
+	}
+}
+
+func TestCaptureTime(t *testing.T) {
+	// libraw converts the wall clock time with the local time zone of the process
+	md := Metadata{Timestamp: time.Date(2024, 6, 1, 10, 0, 0, 0, time.Local).Unix()}
+	zone := time.FixedZone("", -5*60*60)
+	if got, want := captureTime(md, zone), time.Date(2024, 6, 1, 10, 0, 0, 0, zone); !got.Equal(want) {
+		t.Errorf("capture time without offset = %v, want %v", got, want)
+	}
+	md.TimeOffset = "+09:00"
+	if got, want := captureTime(md, zone), time.Date(2024, 6, 1, 10, 0, 0, 0, time.FixedZone("", 9*60*60)); !got.Equal(want) {
+		t.Errorf("capture time with offset = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+//
+// // The capabilities of RawSpeed 3, zlib and libjpeg were added in libraw 0.21, reported as missing before
+// #if !LIBRAW_COMPILE_CHECK_VERSION_NOTLESS(0, 21)
+// #define LIBRAW_CAPS_RAWSPEED3 0
+// #define LIBRAW_CAPS_RAWSPEED_BITS 0
+// #define LIBRAW_CAPS_ZLIB 0
+// #define LIBRAW_CAPS_JPEG 0
+// #endif
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// Features describes the optional features the linked libraw was built with. RawSpeed3, RawSpeedBits, ZLib and JPEG
+// are only reported by libraw 0.21 and newer, they are false with older versions.
+type Features struct {
+	RawSpeed     bool
+	RawSpeed3    bool
+	RawSpeedBits bool
+	DNGSDK       bool
+	GPRSDK       bool
+	UnicodePaths bool
+	X3FTools     bool
+	RPi6By9      bool
+	ZLib         bool
+	JPEG         bool
+	// Canon CR3 decoding, available since libraw 0.20
+	CR3 bool
+}
+
+// Returns the version of the linked libraw, e.g. "0.21.2-Release".
+func Version() string {
+	return C.GoString(C.libraw_version())
+}
+
+// Returns the version number of the linked libraw.
+func VersionNumber() (major int, minor int, patch int) {
+	v := int(C.libraw_versionNumber())
+	return v >> 16, (v >> 8) & 0xff, v & 0xff
+}
+
+// Returns the optional features of the linked libraw, detected at runtime.
+func Capabilities() Features {
+	caps := uint(C.libraw_capabilities())
+	major, minor, _ := VersionNumber()
+	return Features{
+		RawSpeed:     caps&C.LIBRAW_CAPS_RAWSPEED != 0,
+		RawSpeed3:    caps&C.LIBRAW_CAPS_RAWSPEED3 != 0,
+		RawSpeedBits: caps&C.LIBRAW_CAPS_RAWSPEED_BITS != 0,
+		DNGSDK:       caps&C.LIBRAW_CAPS_DNGSDK != 0,
+		GPRSDK:       caps&C.LIBRAW_CAPS_GPRSDK != 0,
+		UnicodePaths: caps&C.LIBRAW_CAPS_UNICODEPATHS != 0,
+		X3FTools:     caps&C.LIBRAW_CAPS_X3FTOOLS != 0,
+		RPi6By9:      caps&C.LIBRAW_CAPS_RPI6BY9 != 0,
+		ZLib:         caps&C.LIBRAW_CAPS_ZLIB != 0,
+		JPEG:         caps&C.LIBRAW_CAPS_JPEG != 0,
+		CR3:          major > 0 || minor >= 20,
+	}
+}
+
+// Returns ErrUnsupportedByLibraw for a feature added in libraw major.minor if the linked libraw is older
+func requireLibraw(feature string, major int, minor int) error {
+	linkedMajor, linkedMinor, _ := VersionNumber()
+	if linkedMajor > major || linkedMajor == major && linkedMinor >= minor {
+		return nil
+	}
+	return fmt.Errorf("%v needs libraw %v.%v or newer, linked [%v]: [%w]", feature, major, minor, Version(), ErrUnsupportedByLibraw)
+}
+
+// Returns the list of cameras supported by the linked libraw, e.g. "Canon EOS R5".
+func SupportedCameras() []string {
+	count := int(C.libraw_cameraCount())
+	list := unsafe.Slice(C.libraw_cameraList(), count)
+	cameras := make([]string, 0, count)
+	for _, camera := range list {
+		if camera == nil {
+			break
+		}
+		cameras = append(cameras, C.GoString(camera))
+	}
+	return cameras
+}
+
+// Reports whether the camera identified by its maker and model (e.g. Metadata.Camera) is supported by the linked libraw.
+func IsSupportedCamera(maker string, model string) bool {
+	name := strings.ToLower(strings.TrimSpace(maker + " " + model))
+	for _, camera := range SupportedCameras() {
+		if strings.ToLower(camera) == name {
+			return true
+		}
+	}
+	return false
+}
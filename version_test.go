@@ -0,0 +1,43 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	major, minor, patch := VersionNumber()
+	if want := fmt.Sprintf("%d.%d.%d", major, minor, patch); !strings.HasPrefix(Version(), want) {
+		t.Errorf("Version() = %q, want the version number %v", Version(), want)
+	}
+	if err := requireLibraw("test", major, minor); err != nil {
+		t.Errorf("requireLibraw of the linked version failed: %v", err)
+	}
+	if err := requireLibraw("test", major+1, 0); !errors.Is(err, ErrUnsupportedByLibraw) {
+		t.Errorf("requireLibraw of the next major version = %v, want %v", err, ErrUnsupportedByLibraw)
+	}
+	if caps := Capabilities(); caps.CR3 != (major > 0 || minor >= 20) {
+		t.Errorf("Capabilities().CR3 = %v with libraw %v", caps.CR3, Version())
+	}
+}
+
+func TestSupportedCameras(t *testing.T) {
+	cameras := SupportedCameras()
+	if len(cameras) == 0 {
+		t.Fatal("SupportedCameras() is empty")
+	}
+	maker, model, ok := strings.Cut(cameras[len(cameras)/2], " ")
+	if !ok {
+		t.Skipf("camera %q has no model", cameras[len(cameras)/2])
+	}
+	if !IsSupportedCamera(strings.ToUpper(maker), model) {
+		t.Errorf("IsSupportedCamera(%q, %q) = false, want true", strings.ToUpper(maker), model)
+	}
+	if camera := testMetadata().Camera; IsSupportedCamera(camera.Make, camera.Model) {
+		t.Errorf("IsSupportedCamera(%q, %q) = true, want false", camera.Make, camera.Model)
+	}
+}
@@ -0,0 +1,59 @@
+// go:build (darwin && cgo) || linux
+
+package golibraw
+
+// #include <libraw/libraw.h>
+import "C"
+
+const (
+	WarnBadCameraWB         Warnings = C.LIBRAW_WARN_BAD_CAMERA_WB
+	WarnNoMetadata          Warnings = C.LIBRAW_WARN_NO_METADATA
+	WarnNoJPEGLib           Warnings = C.LIBRAW_WARN_NO_JPEGLIB
+	WarnNoEmbeddedProfile   Warnings = C.LIBRAW_WARN_NO_EMBEDDED_PROFILE
+	WarnNoInputProfile      Warnings = C.LIBRAW_WARN_NO_INPUT_PROFILE
+	WarnBadOutputProfile    Warnings = C.LIBRAW_WARN_BAD_OUTPUT_PROFILE
+	WarnNoBadPixelMap       Warnings = C.LIBRAW_WARN_NO_BADPIXELMAP
+	WarnBadDarkFrameFile    Warnings = C.LIBRAW_WARN_BAD_DARKFRAME_FILE
+	WarnBadDarkFrameDim     Warnings = C.LIBRAW_WARN_BAD_DARKFRAME_DIM
+	WarnRawSpeedProblem     Warnings = C.LIBRAW_WARN_RAWSPEED_PROBLEM
+	WarnRawSpeedUnsupported Warnings = C.LIBRAW_WARN_RAWSPEED_UNSUPPORTED
+	WarnRawSpeedProcessed   Warnings = C.LIBRAW_WARN_RAWSPEED_PROCESSED
+	WarnFallbackToAHD       Warnings = C.LIBRAW_WARN_FALLBACK_TO_AHD
+	WarnParseFujiProcessed  Warnings = C.LIBRAW_WARN_PARSEFUJI_PROCESSED
+	WarnDNGSDKProcessed     Warnings = C.LIBRAW_WARN_DNGSDK_PROCESSED
+	WarnDNGImagesReordered  Warnings = C.LIBRAW_WARN_DNG_IMAGES_REORDERED
+	WarnDNGStage2Applied    Warnings = C.LIBRAW_WARN_DNG_STAGE2_APPLIED
+	WarnDNGStage3Applied    Warnings = C.LIBRAW_WARN_DNG_STAGE3_APPLIED
+)
+
+var warningNames = []struct {
+	flag Warnings
+	name string
+}{
+	{WarnBadCameraWB, "bad camera white balance"},
+	{WarnNoMetadata, "no metadata"},
+	{WarnNoJPEGLib, "no JPEG library"},
+	{WarnNoEmbeddedProfile, "no embedded profile"},
+	{WarnNoInputProfile, "no input profile"},
+	{WarnBadOutputProfile, "bad output profile"},
+	{WarnNoBadPixelMap, "no bad pixel map"},
+	{WarnBadDarkFrameFile, "bad dark frame file"},
+	{WarnBadDarkFrameDim, "bad dark frame dimensions"},
+	{WarnRawSpeedProblem, "RawSpeed problem"},
+	{WarnRawSpeedUnsupported, "RawSpeed unsupported"},
+	{WarnRawSpeedProcessed, "RawSpeed processed"},
+	{WarnFallbackToAHD, "fallback to AHD"},
+	{WarnParseFujiProcessed, "Fuji parser processed"},
+	{WarnDNGSDKProcessed, "DNG SDK processed"},
+	{WarnDNGImagesReordered, "DNG images reordered"},
+	{WarnDNGStage2Applied, "DNG stage 2 applied"},
+	{WarnDNGStage3Applied, "DNG stage 3 applied"},
+}
+
+// Returns the warnings collected while opening and processing the current image.
+func (p *Processor) Warnings() Warnings {
+	if p.handle == nil {
+		return 0
+	}
+	return Warnings(p.handle.process_warnings)
+}
@@ -0,0 +1,21 @@
+//go:build cgo
+
+package golibraw
+
+import "testing"
+
+func TestWarnings(t *testing.T) {
+	w := WarnNoMetadata | WarnFallbackToAHD
+	if !w.Has(WarnNoMetadata) || !w.Has(WarnFallbackToAHD) || w.Has(WarnBadCameraWB) {
+		t.Errorf("%#x.Has reports the wrong flags", uint(w))
+	}
+	if !w.Has(WarnNoMetadata | WarnFallbackToAHD) {
+		t.Errorf("%#x.Has of both flags = false, want true", uint(w))
+	}
+	if got, want := w.String(), "no metadata, fallback to AHD"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := Warnings(0).String(); got != "" {
+		t.Errorf("String() without warnings = %q, want empty", got)
+	}
+}
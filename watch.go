@@ -0,0 +1,336 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default interval between two scans of a watched directory
+const defaultWatchInterval = time.Second
+
+// Default output directory of a Watcher, created in the watched directory. Subdirectories are not watched, so the
+// outputs are never picked up as new files.
+const defaultWatchOutputDir = "processed"
+
+// Watcher monitors a directory for new RAW files, e.g. the hot folder of tethered shooting, and renders them,
+// extracts their thumbnails and writes their metadata as JSON when they arrive.
+// On Linux the directory is watched with inotify and a file is processed once it is closed after writing or moved into
+// the directory. Elsewhere, or with Poll, the directory is scanned periodically and a file is processed once its size
+// and modification time did not change between two scans, so files still being copied are not picked up half written.
+type Watcher struct {
+	// Time between two scans of the directory when polling, one second by default
+	Interval time.Duration
+	// Scans the directory periodically instead of relying on file system notifications, needed for network shares
+	Poll bool
+	// Directory of the outputs, the "processed" subdirectory of the watched directory by default. The outputs are named
+	// after the complete name of the input, e.g. IMG_0001.CR3.jpg, so inputs differing only in their extension do not
+	// overwrite each other's outputs.
+	OutputDir string
+	// Renders new files to Format with the conversion settings of Batch, e.g. Batch.Quality and Batch.MaxDim
+	Render bool
+	Format Format
+	// Extracts the embedded thumbnail of new files as <name>.thumb.jpg, or .thumb.ppm for bitmap thumbnails
+	Thumbnail bool
+	// Writes the metadata of new files as <name>.json
+	Metadata bool
+	// Processes the RAW files already in the directory when watching starts, instead of only the new ones
+	Existing bool
+	// Conversion settings: processing options, quality, size and output options. Batch.Workers new files are processed
+	// in parallel, one by default.
+	Batch Batch
+	// Called after each processed file, from the worker goroutines
+	OnFile func(event WatchEvent)
+}
+
+// WatchEvent is the outcome of processing a new file found by a Watcher.
+type WatchEvent struct {
+	Input string
+	// Files written for the input
+	Outputs  []string
+	Metadata Metadata
+	Err      error
+}
+
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	// set once the file is processed or found not to be a RAW file
+	done bool
+}
+
+// State of a watched directory: the files seen and the outputs written, which are never processed as inputs even
+// when they are written to the watched directory
+type watchState struct {
+	dir       string
+	outputDir string
+	files     map[string]*watchedFile
+	mu        sync.Mutex
+	outputs   map[string]bool
+}
+
+func (s *watchState) addOutput(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs[path] = true
+}
+
+func (s *watchState) isOutput(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outputs[path]
+}
+
+// Watches dir until ctx is cancelled, processing the new RAW files. Files are identified by their content, not their
+// extension. Subdirectories, hidden files (e.g. the temporary files of atomic outputs) and the outputs of the watcher
+// are ignored. Returns the context error on cancellation, or the error of watching the directory.
+func (w Watcher) Watch(ctx context.Context, dir string) error {
+	outputDir := w.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Join(dir, defaultWatchOutputDir)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory [%v] with [%w]", outputDir, err)
+	}
+	state := &watchState{dir: dir, outputDir: outputDir, files: map[string]*watchedFile{}, outputs: map[string]bool{}}
+
+	if !w.Poll {
+		// falls back to polling where notifications are not available
+		if notifier, err := newDirNotifier(dir); err == nil {
+			defer notifier.Close()
+			return w.watchNotified(ctx, state, notifier)
+		}
+	}
+
+	if !w.Existing {
+		if err := w.scan(state, func(string) {}); err != nil {
+			return err
+		}
+		for _, f := range state.files {
+			f.done = true
+		}
+	}
+	return w.poll(ctx, state)
+}
+
+// Scans the directory every Interval, processing the files unchanged since the previous scan
+func (w Watcher) poll(ctx context.Context, state *watchState) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var ready []string
+		if err := w.scan(state, func(path string) { ready = append(ready, path) }); err != nil {
+			return err
+		}
+		w.processAll(ctx, state, ready)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Processes the files reported complete by the notifier, and the files already in the directory with Existing
+func (w Watcher) watchNotified(ctx context.Context, state *watchState, notifier *dirNotifier) error {
+	entries, err := os.ReadDir(state.dir)
+	if err != nil {
+		return fmt.Errorf("failed to scan directory [%v] with [%w]", state.dir, err)
+	}
+	var ready []string
+	for _, entry := range entries {
+		path := filepath.Join(state.dir, entry.Name())
+		if w.complete(state, path) && w.Existing {
+			ready = append(ready, path)
+		}
+	}
+	w.processAll(ctx, state, ready)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case path, ok := <-notifier.events:
+			if !ok {
+				return fmt.Errorf("failed to watch directory [%v]", state.dir)
+			}
+			ready = ready[:0]
+			for {
+				if path == "" {
+					// notifications were lost, every changed file is checked
+					entries, _ := os.ReadDir(state.dir)
+					for _, entry := range entries {
+						if path := filepath.Join(state.dir, entry.Name()); w.complete(state, path) {
+							ready = append(ready, path)
+						}
+					}
+				} else if w.complete(state, path) {
+					ready = append(ready, path)
+				}
+				if len(notifier.events) == 0 {
+					break
+				}
+				path = <-notifier.events
+			}
+			w.processAll(ctx, state, ready)
+		}
+	}
+}
+
+// Records the current state of a file reported complete, reporting whether it is a RAW file which was not processed
+// in this state yet
+func (w Watcher) complete(state *watchState, path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".") || state.isOutput(path) {
+		return false
+	}
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if f, ok := state.files[path]; ok && f.size == info.Size() && f.modTime.Equal(info.ModTime()) {
+		return false
+	}
+	state.files[path] = &watchedFile{size: info.Size(), modTime: info.ModTime(), done: true}
+	format, err := detectFile(path)
+	return err == nil && format != ""
+}
+
+// Processes the files with Batch.Workers goroutines, until ctx is cancelled
+func (w Watcher) processAll(ctx context.Context, state *watchState, paths []string) {
+	workers := w.Batch.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, workers)
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			event := w.process(ctx, path, state)
+			if w.OnFile != nil {
+				w.OnFile(event)
+			}
+		}(path)
+	}
+	wg.Wait()
+}
+
+// Updates the state of the files of the directory, calling ready for the RAW files unchanged since the previous scan
+func (w Watcher) scan(state *watchState, ready func(path string)) error {
+	entries, err := os.ReadDir(state.dir)
+	if err != nil {
+		return fmt.Errorf("failed to scan directory [%v] with [%w]", state.dir, err)
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(state.dir, entry.Name())
+		if state.isOutput(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// removed since listing the directory
+			continue
+		}
+		seen[path] = true
+		f, ok := state.files[path]
+		if !ok || f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+			// new or still being written, re-processed if replaced with a different file
+			state.files[path] = &watchedFile{size: info.Size(), modTime: info.ModTime()}
+			continue
+		}
+		if f.done {
+			continue
+		}
+		f.done = true
+		if format, err := detectFile(path); err == nil && format != "" {
+			ready(path)
+		}
+	}
+	for path := range state.files {
+		if !seen[path] {
+			delete(state.files, path)
+		}
+	}
+	return nil
+}
+
+func (w Watcher) process(ctx context.Context, input string, state *watchState) WatchEvent {
+	event := WatchEvent{Input: input}
+	base := filepath.Join(state.outputDir, filepath.Base(input))
+
+	var err error
+	if event.Metadata, err = ExtractMetadata(input); err != nil {
+		event.Err = err
+		return event
+	}
+	if w.Metadata {
+		output := base + ".json"
+		state.addOutput(output)
+		err = encodeFile(output, w.Batch.Output, func(out io.Writer) error {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(event.Metadata)
+		})
+		if err != nil {
+			event.Err = err
+			return event
+		}
+		event.Outputs = append(event.Outputs, output)
+	}
+	if w.Thumbnail {
+		output, err := w.thumbnail(input, base, state)
+		if err != nil {
+			event.Err = err
+			return event
+		}
+		event.Outputs = append(event.Outputs, output)
+	}
+	if w.Render {
+		output := base + w.Format.Extension()
+		state.addOutput(output)
+		if err = w.Batch.convert(ctx, input, output, w.Format); err != nil {
+			event.Err = err
+			return event
+		}
+		event.Outputs = append(event.Outputs, output)
+	}
+	return event
+}
+
+func (w Watcher) thumbnail(input string, base string, state *watchState) (string, error) {
+	data, info, err := ExtractThumbnailBytes(input)
+	if err != nil {
+		return "", err
+	}
+	output := base + ".thumb.jpg"
+	if info.Format != ThumbnailJPEG {
+		output = base + ".thumb.ppm"
+	}
+	state.addOutput(output)
+	return output, encodeFile(output, w.Batch.Output, func(out io.Writer) error {
+		return writeThumbnail(out, data, info)
+	})
+}
@@ -0,0 +1,75 @@
+//go:build cgo && linux
+
+package golibraw
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Notifies the files of a directory closed after writing or moved into it, using inotify. An empty path reports lost
+// notifications. The events channel is closed when the directory can no longer be watched.
+type dirNotifier struct {
+	file   *os.File
+	events chan string
+	done   chan struct{}
+}
+
+func newDirNotifier(dir string) (*dirNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify with [%w]", err)
+	}
+	if _, err = syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch directory [%v] with [%w]", dir, err)
+	}
+	// the non-blocking descriptor is read through the runtime poller, so Close interrupts a pending read
+	n := &dirNotifier{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan string, 64),
+		done:   make(chan struct{}),
+	}
+	go n.read(dir)
+	return n, nil
+}
+
+func (n *dirNotifier) read(dir string) {
+	defer close(n.events)
+	buf := make([]byte, 64*1024)
+	for {
+		count, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= count; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			name := buf[offset+syscall.SizeofInotifyEvent : min(count, offset+syscall.SizeofInotifyEvent+int(event.Len))]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			path := ""
+			switch {
+			case event.Mask&syscall.IN_IGNORED != 0:
+				// the directory was removed or unmounted
+				return
+			case event.Mask&syscall.IN_Q_OVERFLOW == 0:
+				path = filepath.Join(dir, string(bytes.TrimRight(name, "\x00")))
+			}
+			select {
+			case n.events <- path:
+			case <-n.done:
+				return
+			}
+		}
+	}
+}
+
+// Stops watching the directory.
+func (n *dirNotifier) Close() error {
+	close(n.done)
+	return n.file.Close()
+}
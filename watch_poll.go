@@ -0,0 +1,18 @@
+//go:build cgo && !linux
+
+package golibraw
+
+import "fmt"
+
+// File system notifications are only used on Linux, other systems poll the watched directory
+type dirNotifier struct {
+	events chan string
+}
+
+func newDirNotifier(dir string) (*dirNotifier, error) {
+	return nil, fmt.Errorf("file system notifications are not supported, directory [%v] is polled", dir)
+}
+
+func (n *dirNotifier) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+//go:build cgo
+
+package golibraw
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Copies a RAW file into a watched directory, expecting it to be processed once with its outputs in the default
+// output directory
+func TestWatcher(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		dir := t.TempDir()
+		events := make(chan WatchEvent, 4)
+		watcher := Watcher{
+			Interval: 10 * time.Millisecond,
+			Poll:     poll,
+			Metadata: true,
+			Render:   true,
+			Format:   FormatTIFF,
+			OnFile:   func(event WatchEvent) { events <- event },
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- watcher.Watch(ctx, dir) }()
+
+		// give the watcher time to start before the file arrives
+		time.Sleep(50 * time.Millisecond)
+		data, err := os.ReadFile(testDNG(t, "RGGB"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, "a.dng"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case event := <-events:
+			if event.Err != nil {
+				t.Fatalf("poll %v: processing failed: %v", poll, event.Err)
+			}
+			want := []string{filepath.Join(dir, "processed", "a.dng.json"), filepath.Join(dir, "processed", "a.dng.tiff")}
+			if len(event.Outputs) != len(want) || event.Outputs[0] != want[0] || event.Outputs[1] != want[1] {
+				t.Errorf("poll %v: outputs = %v, want %v", poll, event.Outputs, want)
+			}
+		case <-time.After(30 * time.Second):
+			t.Fatalf("poll %v: the new file was not processed", poll)
+		}
+		select {
+		case event := <-events:
+			t.Errorf("poll %v: unexpected event for [%v]", poll, event.Input)
+		case <-time.After(200 * time.Millisecond):
+		}
+		cancel()
+		if err = <-done; err != context.Canceled {
+			t.Errorf("poll %v: Watch returned %v, want context.Canceled", poll, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package golibraw
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Namespaces of the XMP prefixes usable in WriteXMPSidecar
+var xmpNamespaces = map[string]string{
+	"aux":       "http://ns.adobe.com/exif/1.0/aux/",
+	"crs":       "http://ns.adobe.com/camera-raw-settings/1.0/",
+	"darktable": "http://darktable.sf.net/",
+	"dc":        "http://purl.org/dc/elements/1.1/",
+	"exif":      "http://ns.adobe.com/exif/1.0/",
+	"lr":        "http://ns.adobe.com/lightroom/1.0/",
+	"photoshop": "http://ns.adobe.com/photoshop/1.0/",
+	"tiff":      "http://ns.adobe.com/tiff/1.0/",
+	"xmp":       "http://ns.adobe.com/xap/1.0/",
+}
+
+// XMP properties holding a list, written as rdf:Bag, rdf:Seq or rdf:Alt
+var xmpArrays = map[string]string{
+	"dc:subject":             "Bag",
+	"lr:hierarchicalSubject": "Bag",
+	"dc:creator":             "Seq",
+	"exif:ISOSpeedRatings":   "Seq",
+	"dc:rights":              "Alt",
+	"dc:description":         "Alt",
+	"dc:title":               "Alt",
+}
+
+// Writes an XMP sidecar file to path with the capture metadata of md and the extra properties, overwriting an existing file.
+// Extra properties are keyed with their qualified name, e.g. "xmp:Rating", "xmp:Label" or "dc:subject", and override
+// the ones taken from md. List properties (like the "dc:subject" keywords or several "dc:creator" authors) are given as
+// comma separated values, the artist of md is written as a single creator.
+// Use the RAW file name with the extension replaced by ".xmp" for Lightroom, or appended with ".xmp" for darktable.
+func WriteXMPSidecar(path string, md Metadata, extras map[string]string) error {
+	properties := xmpProperties(md)
+	// the artist of md is a single creator, even if the name has a comma like "Doe, John"
+	single := map[string]bool{"dc:creator": true}
+	for name, value := range extras {
+		prefix, _, ok := strings.Cut(name, ":")
+		if !ok || xmpNamespaces[prefix] == "" {
+			return fmt.Errorf("unsupported XMP property [%v]", name)
+		}
+		properties[name] = value
+		delete(single, name)
+	}
+
+	if err := os.WriteFile(path, xmpPacket(properties, single), 0o644); err != nil {
+		return fmt.Errorf("failed to write XMP sidecar [%v] with [%w]", path, err)
+	}
+	return nil
+}
+
+func xmpProperties(md Metadata) map[string]string {
+	properties := make(map[string]string)
+	set := func(name string, value string) {
+		if value != "" {
+			properties[name] = value
+		}
+	}
+	rational := func(v float64) string {
+		if v <= 0 {
+			return ""
+		}
+		if n := math.Round(1 / v); v < 1 && math.Abs(1/n-v) < v/100 {
+			return fmt.Sprintf("1/%v", n)
+		}
+		return fmt.Sprintf("%v/1000", math.Round(v*1000))
+	}
+
+	set("tiff:Make", md.Camera.Make)
+	set("tiff:Model", md.Camera.Model)
+	if md.Orientation > 0 {
+		set("tiff:Orientation", strconv.Itoa(md.Orientation))
+	}
+	if captured := md.Time(); !captured.IsZero() {
+		set("exif:DateTimeOriginal", captured.Format(time.RFC3339))
+	}
+	if md.ISO > 0 {
+		set("exif:ISOSpeedRatings", strconv.Itoa(md.ISO))
+	}
+	set("exif:ExposureTime", rational(md.Shutter))
+	set("exif:FNumber", rational(md.Aperture))
+	set("exif:FocalLength", rational(md.FocalLength))
+	if md.ExposureCompensation != 0 {
+		set("exif:ExposureBiasValue", fmt.Sprintf("%v/100", math.Round(md.ExposureCompensation*100)))
+	}
+	set("aux:SerialNumber", md.Camera.Serial)
+	set("aux:Lens", md.Lens.Model)
+	set("dc:creator", md.Artist)
+	set("dc:rights", md.Copyright)
+	set("dc:description", md.Description)
+	return properties
+}
+
+// Builds the XMP packet of properties, list properties are split at commas unless they are listed in single
+func xmpPacket(properties map[string]string, single map[string]bool) []byte {
+	names := make([]string, 0, len(properties))
+	prefixes := make(map[string]bool)
+	for name := range properties {
+		names = append(names, name)
+		prefix, _, _ := strings.Cut(name, ":")
+		prefixes[prefix] = true
+	}
+	sort.Strings(names)
+
+	escape := func(s string) string {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\" x:xmptk=\"golibraw\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"")
+	for _, prefix := range sortedKeys(prefixes) {
+		fmt.Fprintf(&b, "\n    xmlns:%v=\"%v\"", prefix, xmpNamespaces[prefix])
+	}
+	for _, name := range names {
+		if xmpArrays[name] == "" {
+			fmt.Fprintf(&b, "\n    %v=\"%v\"", name, escape(properties[name]))
+		}
+	}
+	b.WriteString(">\n")
+
+	for _, name := range names {
+		kind := xmpArrays[name]
+		if kind == "" {
+			continue
+		}
+		items := []string{properties[name]}
+		if (kind == "Bag" || kind == "Seq") && !single[name] {
+			items = strings.Split(properties[name], ",")
+		}
+		fmt.Fprintf(&b, "   <%v>\n    <rdf:%v>\n", name, kind)
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			if kind == "Alt" {
+				fmt.Fprintf(&b, "     <rdf:li xml:lang=\"x-default\">%v</rdf:li>\n", escape(item))
+			} else {
+				fmt.Fprintf(&b, "     <rdf:li>%v</rdf:li>\n", escape(item))
+			}
+		}
+		fmt.Fprintf(&b, "    </rdf:%v>\n   </%v>\n", kind, name)
+	}
+
+	b.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>\n")
+	return b.Bytes()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+package golibraw
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteXMPSidecar(t *testing.T) {
+	md := Metadata{
+		Camera:      Camera{Make: "Sony", Model: "ILCE-7RM4", Serial: "1234"},
+		Orientation: 6,
+		ISO:         400,
+		Shutter:     1.0 / 125,
+		Aperture:    2.8,
+		Artist:      "Doe, John",
+		Copyright:   "(c) Doe & Co",
+	}
+	path := filepath.Join(t.TempDir(), "image.xmp")
+	extras := map[string]string{"xmp:Rating": "5", "dc:subject": "landscape, mountains", "tiff:Model": "Custom"}
+	if err := WriteXMPSidecar(path, md, extras); err != nil {
+		t.Fatalf("WriteXMPSidecar failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet := string(data)
+
+	for _, want := range []string{
+		`xmlns:tiff="http://ns.adobe.com/tiff/1.0/"`,
+		`tiff:Make="Sony"`,
+		`tiff:Model="Custom"`,
+		`tiff:Orientation="6"`,
+		`exif:ExposureTime="1/125"`,
+		`exif:FNumber="2800/1000"`,
+		`aux:SerialNumber="1234"`,
+		`xmp:Rating="5"`,
+		"<rdf:Seq>\n     <rdf:li>400</rdf:li>",
+		"<rdf:Bag>\n     <rdf:li>landscape</rdf:li>\n     <rdf:li>mountains</rdf:li>",
+		"<rdf:Seq>\n     <rdf:li>Doe, John</rdf:li>",
+		`<rdf:li xml:lang="x-default">(c) Doe &amp; Co</rdf:li>`,
+	} {
+		if !strings.Contains(packet, want) {
+			t.Errorf("XMP packet has no %q:\n%v", want, packet)
+		}
+	}
+	if strings.Contains(packet, "xmlns:lr=") {
+		t.Errorf("XMP packet declares the unused lr namespace:\n%v", packet)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(packet))
+	for {
+		if _, err := decoder.Token(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("XMP packet is not well-formed XML: %v", err)
+		}
+	}
+
+	if err := WriteXMPSidecar(path, md, map[string]string{"foo:Bar": "1"}); err == nil {
+		t.Errorf("WriteXMPSidecar succeeded with an unknown namespace")
+	}
+	if err := WriteXMPSidecar(path, md, map[string]string{"Rating": "1"}); err == nil {
+		t.Errorf("WriteXMPSidecar succeeded with an unqualified property")
+	}
+}